@@ -10,6 +10,7 @@ import (
 	"github.com/Antiaastu/distributed-event-ticketing/auth-service/internal/middleware"
 	"github.com/Antiaastu/distributed-event-ticketing/auth-service/internal/repository"
 	"github.com/Antiaastu/distributed-event-ticketing/auth-service/internal/service"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
@@ -24,7 +25,7 @@ import (
 func main() {
 	database.ConnectDB()
 	database.SeedAdmin()
-	messaging.ConnectRabbitMQ()
+	messaging.ConnectRabbitMQ(database.NewDedupStore())
 
 	userRepo := repository.NewUserRepository()
 	authService := service.NewAuthService(userRepo)
@@ -74,6 +75,9 @@ func main() {
 			adminRoutes.DELETE("/users", authHandler.DeleteUser)
 			adminRoutes.GET("/audit-logs", authHandler.GetAuditLogs)
 		}
+
+		// Dead-lettered messages
+		shared.NewDeadLetterHandler(messaging.Client).RegisterRoutes(adminRoutes)
 	}
 	log.Println("Auth Service running on port 3001")
 	r.Run(":3001")
@@ -1,89 +1,64 @@
 package messaging
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
 
-	"github.com/streadway/amqp"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 )
 
-var Channel *amqp.Channel
+const (
+	EmailVerificationQueue = "email_verification"
+	PasswordResetQueue     = "password_reset"
+	AuditLogsQueue         = "audit_logs"
 
-func ConnectRabbitMQ() {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
+	// Producer is the source attribute of the events this service publishes.
+	Producer = "auth-service"
+)
 
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ: ", err)
-	}
+// Client is the service's long-lived RabbitMQ connection.
+var Client *shared.Client
 
-	Channel, err = conn.Channel()
-	if err != nil {
-		log.Fatal("Failed to open a channel: ", err)
-	}
+func ConnectRabbitMQ(dedupStore dedup.Store) {
+	Client = shared.NewClient(shared.URLFromEnv())
+	// Redelivered messages are acked without being processed twice
+	Client.Deduplicate(dedup.NewFilter(dedupStore))
 
 	// Declare queues
-	queues := []string{"email_verification", "password_reset", "audit_logs"}
-	for _, q := range queues {
-		_, err = Channel.QueueDeclare(
-			q,     // name
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			log.Fatal("Failed to declare queue: ", err)
-		}
+	Client.DeclareTopology(shared.DeclareQueues(EmailVerificationQueue, PasswordResetQueue, AuditLogsQueue))
+
+	if err := Client.Connect(); err != nil {
+		log.Printf("RabbitMQ not available yet, retrying in the background: %v", err)
 	}
 }
 
 func PublishEmailVerification(email, code string) {
-	message := map[string]string{
-		"email": email,
-		"code":  code,
+	body, err := events.Marshal(events.TypeEmailVerificationRequested, Producer, "", events.EmailVerificationRequested{
+		Email: email,
+		Code:  code,
+	})
+	if err != nil {
+		log.Println("Failed to encode message: ", err)
+		return
 	}
-	body, _ := json.Marshal(message)
 
-	err := Channel.Publish(
-		"",                   // exchange
-		"email_verification", // routing key
-		false,                // mandatory
-		false,                // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-	if err != nil {
+	if err := Client.Publish("", EmailVerificationQueue, body); err != nil {
 		log.Println("Failed to publish message: ", err)
 	}
 }
 
 func PublishPasswordReset(email, code string) {
-	message := map[string]string{
-		"email": email,
-		"code":  code,
+	body, err := events.Marshal(events.TypePasswordResetRequested, Producer, "", events.PasswordResetRequested{
+		Email: email,
+		Code:  code,
+	})
+	if err != nil {
+		log.Println("Failed to encode message: ", err)
+		return
 	}
-	body, _ := json.Marshal(message)
 
-	err := Channel.Publish(
-		"",               // exchange
-		"password_reset", // routing key
-		false,            // mandatory
-		false,            // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-	if err != nil {
+	if err := Client.Publish("", PasswordResetQueue, body); err != nil {
 		log.Println("Failed to publish message: ", err)
 	}
 }
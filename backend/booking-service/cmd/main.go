@@ -11,6 +11,9 @@ import (
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/service"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/worker"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/idempotency"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
@@ -26,14 +29,18 @@ func main() {
 	database.ConnectDB()
 
 	bookingRepo := repository.NewBookingRepository()
-	bookingService := service.NewBookingService(bookingRepo)
+	bookingService := service.NewBookingService(bookingRepo, repository.NewSagaRepository())
 	bookingHandler := handlers.NewBookingHandler(bookingService)
+	idempotencyRepo := idempotency.NewRepository(database.DB)
+
+	// Connect to RabbitMQ and start consumer
+	messaging.ConnectRabbitMQ(bookingService, database.NewDedupStore())
 
 	// Start Cleanup Worker
 	worker.StartCleanupWorker(bookingService)
 
-	// Connect to RabbitMQ and start consumer
-	messaging.ConnectRabbitMQ(bookingService)
+	// Start Outbox Relay
+	outbox.StartRelay(outbox.NewStore(database.DB), messaging.Client)
 
 	r := gin.Default()
 
@@ -50,17 +57,27 @@ func main() {
 	// Public/Internal routes (no auth required for service-to-service or public access)
 	r.GET("/api/bookings/:id", bookingHandler.GetBooking)
 
+	// Internal only
+	r.GET("/api/bookings/inventory", middleware.InternalAuthMiddleware(), bookingHandler.GetTicketInventory)
+
 	api := r.Group("/api")
 	api.Use(middleware.AuthMiddleware())
 	{
-		api.POST("/bookings", bookingHandler.CreateBooking)
+		api.POST("/bookings", idempotency.Middleware(idempotencyRepo), bookingHandler.CreateBooking)
+		api.POST("/bookings/:id/cancel", bookingHandler.CancelBooking)
+		api.GET("/bookings/:id/saga", bookingHandler.GetBookingSaga)
+		api.GET("/bookings/:id/history", bookingHandler.GetBookingHistory)
 		api.GET("/bookings/event/:eventId/seats", bookingHandler.GetEventBookedSeats)
 		api.GET("/bookings/organizer/sales", bookingHandler.GetOrganizerSales)
 		api.GET("/bookings/organizer/sales/:eventId", bookingHandler.GetSales)
+		api.GET("/bookings/organizer/sales/:eventId/tiers", bookingHandler.GetTierSales)
 		api.GET("/bookings/user", bookingHandler.GetUserBookings)
 		api.GET("/bookings/all", bookingHandler.GetAllBookings)
 	}
 
+	// Dead-lettered messages (Admin only)
+	shared.NewDeadLetterHandler(messaging.Client).RegisterRoutes(api.Group("/bookings/admin"))
+
 	log.Println("Booking Service running on port 3002")
 	r.Run(":3002")
 }
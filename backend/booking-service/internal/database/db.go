@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/idempotency"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -29,5 +31,10 @@ func ConnectDB() {
 	}
 
 	log.Println("Connected to Database")
-	DB.AutoMigrate(&models.Booking{})
+	DB.AutoMigrate(&models.Booking{}, &models.BookingItem{}, &idempotency.Record{}, &outbox.Message{}, &models.Saga{}, &models.SagaStep{}, &models.BookingStatusHistory{})
+
+	// booking_confirmed used to be a plain queue; route unsent confirmations through the fanout exchange
+	DB.Model(&outbox.Message{}).
+		Where("status = ? AND exchange = ? AND routing_key = ?", outbox.StatusPending, "", "booking_confirmed").
+		Updates(map[string]interface{}{"exchange": "booking_confirmed", "routing_key": ""})
 }
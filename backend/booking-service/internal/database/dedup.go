@@ -0,0 +1,26 @@
+package database
+
+import (
+	"log"
+
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup/gormstore"
+)
+
+// NewDedupStore returns the store consumers record processed message IDs in,
+// chosen by DEDUP_STORE: postgres (default) or memory.
+func NewDedupStore() dedup.Store {
+	switch kind := dedup.StoreKindFromEnv("postgres"); kind {
+	case "memory":
+		return dedup.NewMemoryStore()
+	case "postgres":
+	default:
+		log.Printf("Unknown DEDUP_STORE %q, using postgres", kind)
+	}
+
+	store, err := gormstore.NewStore(DB)
+	if err != nil {
+		log.Fatal("Failed to set up message deduplication: ", err)
+	}
+	return store
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -18,21 +20,30 @@ func NewBookingHandler(service service.BookingService) *BookingHandler {
 }
 
 type CreateBookingRequest struct {
-	EventID   uint        `json:"event_id" binding:"required"`
-	SeatCount int         `json:"seat_count" binding:"required,min=1"`
-	Amount    float64     `json:"amount" binding:"required,gt=0"`
-	Seats     interface{} `json:"seats"`
+	EventID   uint `json:"event_id" binding:"required"`
+	SeatCount int  `json:"seat_count" binding:"required,min=1"`
+	// TicketClass is used for bookings without explicit seats.
+	TicketClass string `json:"ticket_class"`
+	// Amount is optional. The total is always priced server-side; when the
+	// client sends an amount it must match that price.
+	Amount float64     `json:"amount" binding:"omitempty,gt=0"`
+	Seats  interface{} `json:"seats"`
+	// AdmissionToken is required for events with a waiting room: the queue
+	// token of the user once the waiting room admitted them.
+	AdmissionToken string `json:"admission_token"`
 }
 
 // @Summary Create a booking
-// @Description Book tickets for an event
+// @Description Book tickets for an event, within the tickets per user the event and its tiers allow across the user's pending and confirmed bookings
 // @Tags bookings
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param input body CreateBookingRequest true "Booking Input"
+// @Param Idempotency-Key header string false "Replays the first response for retries of the same request"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /bookings [post]
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -50,15 +61,156 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	seatsBytes, _ := json.Marshal(req.Seats)
 	seatsStr := string(seatsBytes)
 
-	booking, err := h.service.CreateBooking(uint(userID.(float64)), req.EventID, req.SeatCount, req.Amount, seatsStr)
+	booking, err := h.service.CreateBooking(uint(userID.(float64)), req.EventID, req.SeatCount, req.TicketClass, req.Amount, seatsStr, req.AdmissionToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err == models.ErrEventNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrSeatCountMismatch || err == models.ErrAmountMismatch || err == models.ErrUnknownSeat ||
+			err == models.ErrUnknownTier || errors.Is(err, models.ErrSeatsRejected) || err == models.ErrEventNotOnSale ||
+			err == models.ErrSalesNotStarted || err == models.ErrSalesEnded || err == models.ErrTierNotOnSale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, models.ErrPurchaseLimit) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Booking successful", "booking": booking})
 }
 
+type CancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// @Summary Cancel a booking
+// @Description Cancel a pending or confirmed booking. Confirmed bookings are refunded according to the event's cancellation policy.
+// @Tags bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Param input body CancelBookingRequest false "Cancellation Input"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /bookings/{id}/cancel [post]
+func (h *BookingHandler) CancelBooking(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	role, _ := c.Get("role")
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	// The body is optional
+	var req CancelBookingRequest
+	_ = c.ShouldBindJSON(&req)
+
+	booking, err := h.service.CancelBooking(uint(id), uint(userID.(float64)), role == "admin", req.Reason)
+	if err != nil {
+		if err == models.ErrBookingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else if err == models.ErrNotCancellable || err == models.ErrDeadlinePassed || errors.Is(err, models.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled", "booking": booking})
+}
+
+// @Summary Get a booking's saga
+// @Description Where a purchase is in the lock → create → pay → confirm → issue ticket flow, with every step and compensation so far.
+// @Tags bookings
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /bookings/{id}/saga [get]
+func (h *BookingHandler) GetBookingSaga(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	role, _ := c.Get("role")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	saga, err := h.service.GetBookingSaga(uint(id), uint(userID.(float64)), role == "admin")
+	if err != nil {
+		if err == models.ErrBookingNotFound || err == models.ErrSagaNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saga"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saga": saga})
+}
+
+// @Summary Get a booking's status history
+// @Description Every status change of a booking with its reason and actor, oldest first.
+// @Tags bookings
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /bookings/{id}/history [get]
+func (h *BookingHandler) GetBookingHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	role, _ := c.Get("role")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	history, err := h.service.GetBookingHistory(uint(id), uint(userID.(float64)), role == "admin")
+	if err != nil {
+		if err == models.ErrBookingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrForbidden {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking history"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
 func (h *BookingHandler) GetSales(c *gin.Context) {
 	// Check for Organizer role
 	role, exists := c.Get("role")
@@ -83,6 +235,79 @@ func (h *BookingHandler) GetSales(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"sales": bookings})
 }
 
+// @Summary Get sales per ticket tier
+// @Description Get capacity, availability, tickets sold and held, and revenue for each ticket tier of one of the caller's events (Organizer only)
+// @Tags bookings
+// @Produce json
+// @Security BearerAuth
+// @Param eventId path int true "Event ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /bookings/organizer/sales/{eventId}/tiers [get]
+func (h *BookingHandler) GetTierSales(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "organizer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer role"})
+		return
+	}
+
+	eventIDStr := c.Param("eventId")
+	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	tiers, err := h.service.GetTierSales(uint(eventID), uint(userID.(float64)))
+	if err != nil {
+		if err == models.ErrEventNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrEventNotOwned {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sales"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event_id": eventID, "tiers": tiers})
+}
+
+// @Summary Get ticket inventory
+// @Description Get the tickets held by pending bookings and sold to confirmed bookings per event and ticket class. Used by the Event Service to rebuild and reconcile its seat counters; requires the internal X-Internal-Token.
+// @Tags bookings
+// @Produce json
+// @Param event_id query int false "Limit to one event"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /bookings/inventory [get]
+func (h *BookingHandler) GetTicketInventory(c *gin.Context) {
+	var eventID uint64
+	if eventIDStr := c.Query("event_id"); eventIDStr != "" {
+		var err error
+		eventID, err = strconv.ParseUint(eventIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+	}
+
+	inventory, err := h.service.GetTicketInventory(uint(eventID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ticket inventory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"inventory": inventory})
+}
+
 func (h *BookingHandler) GetOrganizerSales(c *gin.Context) {
 	// Check for Organizer role
 	role, exists := c.Get("role")
@@ -150,8 +375,8 @@ func (h *BookingHandler) GetEventBookedSeats(c *gin.Context) {
 
 	var bookedSeats []interface{}
 	for _, b := range bookings {
-		// Skip failed or cancelled bookings
-		if b.Status == "failed" || b.Status == "cancelled" {
+		// Only pending and confirmed bookings hold seats
+		if b.Status != models.BookingStatusPending && b.Status != models.BookingStatusConfirmed {
 			continue
 		}
 
@@ -159,7 +384,7 @@ func (h *BookingHandler) GetEventBookedSeats(c *gin.Context) {
 		if err := json.Unmarshal([]byte(b.Seats), &seats); err == nil {
 			for _, seat := range seats {
 				// Override status based on booking status
-				if b.Status == "confirmed" {
+				if b.Status == models.BookingStatusConfirmed {
 					seat["status"] = "sold"
 				} else {
 					seat["status"] = "locked"
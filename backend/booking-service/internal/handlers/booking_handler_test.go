@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// tierSalesService answers GetTierSales for events owned by organizer 1.
+type tierSalesService struct {
+	service.BookingService
+}
+
+func (s *tierSalesService) GetTierSales(eventID, organizerID uint) ([]models.TierSales, error) {
+	if eventID != 5 {
+		return nil, models.ErrEventNotFound
+	}
+	if organizerID != 1 {
+		return nil, models.ErrEventNotOwned
+	}
+	return []models.TierSales{{Code: "vip"}}, nil
+}
+
+func TestGetTierSales(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewBookingHandler(&tierSalesService{})
+
+	tests := []struct {
+		name       string
+		role       string
+		userID     float64
+		eventID    string
+		wantStatus int
+	}{
+		{"own event", "organizer", 1, "5", http.StatusOK},
+		{"another organizer's event", "organizer", 2, "5", http.StatusForbidden},
+		{"customer", "user", 1, "5", http.StatusForbidden},
+		{"unknown event", "organizer", 1, "6", http.StatusNotFound},
+		{"invalid event ID", "organizer", 1, "x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/sales/:eventId/tiers", func(c *gin.Context) {
+				c.Set("role", tt.role)
+				c.Set("user_id", tt.userID)
+			}, h.GetTierSales)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sales/"+tt.eventID+"/tiers", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,121 +1,56 @@
 package messaging
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
+	"time"
 
-	"github.com/streadway/amqp"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 )
 
-type BookingConfirmedEvent struct {
-	BookingID uint    `json:"booking_id"`
-	UserID    uint    `json:"user_id"`
-	EventID   uint    `json:"event_id"`
-	Amount    float64 `json:"amount"`
-	SeatCount int     `json:"seat_count"`
-	Seats     string  `json:"seats"`
-}
-
-func PublishBookingConfirmed(event BookingConfirmedEvent) error {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		return fmt.Errorf("failed to connect to RabbitMQ: %v", err)
-	}
-	defer conn.Close()
-
-	ch, err := conn.Channel()
-	if err != nil {
-		return fmt.Errorf("failed to open a channel: %v", err)
-	}
-	defer ch.Close()
+const (
+	BookingCancelledQueue = "booking_cancelled"
+	RefundRequestedQueue  = "refund_requested"
+	AuditLogsQueue        = "audit_logs"
+	// EventRefundRequestedQueue asks the payment service to refund every
+	// booking of a cancelled event as one job.
+	EventRefundRequestedQueue = "event_refund_requested"
 
-	q, err := ch.QueueDeclare(
-		"booking_confirmed", // name
-		true,                // durable
-		false,               // delete when unused
-		false,               // exclusive
-		false,               // no-wait
-		nil,                 // arguments
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare a queue: %v", err)
-	}
+	// Producer is the source attribute of the events this service publishes.
+	Producer = "booking-service"
 
-	body, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event: %v", err)
-	}
-
-	err = ch.Publish(
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,  // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-	if err != nil {
-		return fmt.Errorf("failed to publish a message: %v", err)
-	}
+	// BookingConfirmedExchange is a fanout exchange so every subscribing
+	// service gets each confirmation on its own queue.
+	BookingConfirmedExchange = "booking_confirmed"
+)
 
-	log.Printf("Published booking_confirmed event for booking %d", event.BookingID)
-	return nil
+// BookingConfirmedSubscriberQueues are the queues bound to BookingConfirmedExchange.
+var BookingConfirmedSubscriberQueues = []string{
+	"event-service.booking_confirmed",
+	"notification-service.booking_confirmed",
 }
 
-type AuditLogMessage struct {
-	UserID    uint   `json:"user_id"`
-	Action    string `json:"action"`
-	Details   string `json:"details"`
-	IPAddress string `json:"ip_address"`
-	CreatedAt string `json:"created_at"`
+// Publish sends a message to RabbitMQ and reports whether the broker accepted it.
+func Publish(exchange, routingKey string, body []byte) error {
+	if Client == nil {
+		return shared.ErrNotConnected
+	}
+	return Client.Publish(exchange, routingKey, body)
 }
 
 func PublishAuditLog(userID uint, action, details string) {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-
-	conn, err := amqp.Dial(connStr)
+	body, err := events.Marshal(events.TypeAuditLogRecorded, Producer, "", events.AuditLogRecorded{
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now(),
+	})
 	if err != nil {
-		log.Printf("Failed to connect to RabbitMQ for audit: %v", err)
+		log.Printf("Failed to encode audit log: %v", err)
 		return
 	}
-	defer conn.Close()
 
-	ch, err := conn.Channel()
-	if err != nil {
-		log.Printf("Failed to open channel for audit: %v", err)
-		return
+	if err := Publish("", AuditLogsQueue, body); err != nil {
+		log.Printf("Failed to publish audit log: %v", err)
 	}
-	defer ch.Close()
-
-	msg := AuditLogMessage{
-		UserID:  userID,
-		Action:  action,
-		Details: details,
-	}
-	body, _ := json.Marshal(msg)
-
-	ch.Publish(
-		"",           // exchange
-		"audit_logs", // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
 }
@@ -1,90 +1,109 @@
 package messaging
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 	"github.com/streadway/amqp"
 )
 
-type BookingConfirmer interface {
+// PaymentEventHandler reacts to events published by the payment,
+// notification and event services.
+type PaymentEventHandler interface {
 	ConfirmBooking(bookingID uint) error
+	RecordRefund(bookingID uint, totalRefunded float64) error
+	TicketIssued(bookingID uint) error
+	CancelEventBookings(eventID, organizerID uint, reason string) error
 }
 
-func ConnectRabbitMQ(bookingService BookingConfirmer) {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ: ", err)
-	}
+const (
+	PaymentRefundedExchange = "payment_refunded"
+	PaymentRefundedQueue    = "booking-service.payment_refunded"
+	PaymentSuccessQueue     = "payment_success"
+	TicketIssuedQueue       = "ticket_issued"
+	EventCancelledExchange  = "event_cancelled"
+	EventCancelledQueue     = "booking-service.event_cancelled"
+)
 
-	ch, err := conn.Channel()
-	if err != nil {
-		log.Fatal("Failed to open a channel: ", err)
-	}
+// Client is the service's long-lived RabbitMQ connection.
+var Client *shared.Client
 
-	q, err := ch.QueueDeclare(
-		"payment_success", // name
-		true,              // durable
-		false,             // delete when unused
-		false,             // exclusive
-		false,             // no-wait
-		nil,               // arguments
-	)
-	if err != nil {
-		log.Fatal("Failed to declare a queue: ", err)
-	}
+func ConnectRabbitMQ(bookingService PaymentEventHandler, dedupStore dedup.Store) {
+	Client = shared.NewClient(shared.URLFromEnv())
+	// Redelivered messages are acked without being processed twice
+	Client.Deduplicate(dedup.NewFilter(dedupStore))
 
-	// Declare audit_logs queue
-	_, err = ch.QueueDeclare(
-		"audit_logs", // name
-		true,         // durable
-		false,        // delete when unused
-		false,        // exclusive
-		false,        // no-wait
-		nil,          // arguments
-	)
-	if err != nil {
-		log.Fatal("Failed to declare audit_logs queue: ", err)
-	}
+	Client.DeclareTopology(shared.DeclareQueues(
+		PaymentSuccessQueue,
+		AuditLogsQueue,
+		BookingCancelledQueue,
+		RefundRequestedQueue,
+		EventRefundRequestedQueue,
+		TicketIssuedQueue,
+	))
+	// Subscriber queues are declared here too so confirmations are kept until the subscribers start
+	Client.DeclareTopology(shared.DeclareFanout(BookingConfirmedExchange, BookingConfirmedSubscriberQueues...))
+	// Bind our own queue to the payment_refunded fanout exchange
+	Client.DeclareTopology(shared.DeclareFanout(PaymentRefundedExchange, PaymentRefundedQueue))
+	// Bind our own queue to the event_cancelled fanout exchange
+	Client.DeclareTopology(shared.DeclareFanout(EventCancelledExchange, EventCancelledQueue))
 
-	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		true,   // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
-	)
-	if err != nil {
-		log.Fatal("Failed to register a consumer: ", err)
-	}
+	Client.Consume(PaymentSuccessQueue, func(d amqp.Delivery) error {
+		var message events.PaymentSucceeded
+		if _, err := events.Unmarshal(d.Body, events.TypePaymentSucceeded, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding message: %v", err))
+		}
+
+		log.Printf("Received payment success for booking %d", message.BookingID)
+		if err := bookingService.ConfirmBooking(message.BookingID); err != nil {
+			return fmt.Errorf("error confirming booking: %v", err)
+		}
+		return nil
+	})
+
+	Client.Consume(PaymentRefundedQueue, func(d amqp.Delivery) error {
+		var message events.PaymentRefunded
+		if _, err := events.Unmarshal(d.Body, events.TypePaymentRefunded, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding message: %v", err))
+		}
 
-	go func() {
-		for d := range msgs {
-			var message struct {
-				BookingID uint    `json:"booking_id"`
-				UserID    uint    `json:"user_id"`
-				Amount    float64 `json:"amount"`
-			}
-			if err := json.Unmarshal(d.Body, &message); err != nil {
-				log.Printf("Error decoding message: %s", err)
-				continue
-			}
-
-			log.Printf("Received payment success for booking %d", message.BookingID)
-			if err := bookingService.ConfirmBooking(message.BookingID); err != nil {
-				log.Printf("Error confirming booking: %s", err)
-			}
+		log.Printf("Received payment refund for booking %d", message.BookingID)
+		if err := bookingService.RecordRefund(message.BookingID, message.TotalRefunded); err != nil {
+			return fmt.Errorf("error recording refund: %v", err)
 		}
-	}()
+		return nil
+	})
+
+	Client.Consume(TicketIssuedQueue, func(d amqp.Delivery) error {
+		var message events.TicketIssued
+		if _, err := events.Unmarshal(d.Body, events.TypeTicketIssued, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding message: %v", err))
+		}
+
+		log.Printf("Received ticket issued for booking %d", message.BookingID)
+		if err := bookingService.TicketIssued(message.BookingID); err != nil {
+			return fmt.Errorf("error completing booking saga: %v", err)
+		}
+		return nil
+	})
+
+	Client.Consume(EventCancelledQueue, func(d amqp.Delivery) error {
+		var message events.EventCancelled
+		if _, err := events.Unmarshal(d.Body, events.TypeEventCancelled, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding message: %v", err))
+		}
+
+		log.Printf("Received cancellation of event %d", message.EventID)
+		if err := bookingService.CancelEventBookings(message.EventID, message.OrganizerID, message.Reason); err != nil {
+			return fmt.Errorf("error cancelling bookings of event %d: %v", message.EventID, err)
+		}
+		return nil
+	})
+
+	if err := Client.Connect(); err != nil {
+		log.Printf("RabbitMQ not available yet, retrying in the background: %v", err)
+	}
 }
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"os"
@@ -45,3 +46,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// InternalTokenHeader carries the INTERNAL_SERVICE_TOKEN shared by the
+// backend services.
+const InternalTokenHeader = "X-Internal-Token"
+
+// InternalAuthMiddleware only accepts requests from internal services.
+func InternalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		token := c.GetHeader(InternalTokenHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Internal service token required"})
+			c.Abort()
+			return
+		}
+		c.Set("internal", true)
+		c.Next()
+	}
+}
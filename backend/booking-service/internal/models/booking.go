@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -11,6 +13,8 @@ const (
 	BookingStatusConfirmed BookingStatus = "confirmed"
 	BookingStatusFailed    BookingStatus = "failed"
 	BookingStatusCancelled BookingStatus = "cancelled"
+	BookingStatusRefunded  BookingStatus = "refunded"
+	BookingStatusExpired   BookingStatus = "expired"
 )
 
 type Booking struct {
@@ -20,5 +24,53 @@ type Booking struct {
 	SeatCount   int           `gorm:"not null" json:"seat_count"`
 	Seats       string        `json:"seats"` // JSON string of selected seats
 	Status      BookingStatus `gorm:"default:'pending'" json:"status"`
+	ServiceFee  float64       `gorm:"default:0" json:"service_fee"`
 	TotalAmount float64       `gorm:"not null" json:"total_amount"`
+	Currency    string        `gorm:"default:'ETB'" json:"currency"`
+	Items       []BookingItem `gorm:"foreignKey:BookingID" json:"items"`
+	ConfirmedAt *time.Time    `json:"confirmed_at"`
+	// HoldToken is the token the booking's seat locks are held under in the
+	// Event Service; unlocking or confirming them requires it.
+	HoldToken string `json:"-"`
+
+	// Cancellation
+	CancelledAt        *time.Time `json:"cancelled_at"`
+	CancellationReason string     `json:"cancellation_reason"`
+	RefundAmount       float64    `gorm:"default:0" json:"refund_amount"`
+	RefundedAmount     float64    `gorm:"default:0" json:"refunded_amount"`
+}
+
+// BookingItem is the server-side price breakdown of a single seat (or
+// unassigned ticket) in a booking.
+type BookingItem struct {
+	gorm.Model
+	BookingID   uint    `gorm:"not null;index" json:"booking_id"`
+	SeatID      string  `json:"seat_id"`
+	TicketClass string  `gorm:"not null" json:"ticket_class"`
+	Price       float64 `gorm:"not null" json:"price"`
+}
+
+var (
+	ErrEventNotFound     = &Error{Message: "Event not found"}
+	ErrSeatCountMismatch = &Error{Message: "Seat count does not match the selected seats"}
+	ErrAmountMismatch    = &Error{Message: "Amount does not match the ticket prices for this event"}
+	ErrUnknownSeat       = &Error{Message: "One or more seats do not exist at this event's venue"}
+	ErrUnknownTier       = &Error{Message: "Ticket tier does not exist for this event"}
+	ErrEventNotOnSale    = &Error{Message: "Tickets for this event are not on sale"}
+	ErrSalesNotStarted   = &Error{Message: "Ticket sales for this event have not started yet"}
+	ErrSalesEnded        = &Error{Message: "Ticket sales for this event have ended"}
+	ErrTierNotOnSale     = &Error{Message: "Tickets of this tier are not on sale"}
+	ErrBookingNotFound   = &Error{Message: "Booking not found"}
+	ErrForbidden         = &Error{Message: "You are not allowed to access this booking"}
+	ErrEventNotOwned     = &Error{Message: "You are not the organizer of this event"}
+	ErrNotCancellable    = &Error{Message: "Only pending or confirmed bookings can be cancelled"}
+	ErrDeadlinePassed    = &Error{Message: "The cancellation deadline for this event has passed"}
+)
+
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// bookingTransitions lists the statuses a booking may move to from each
+// status. Failed, expired and refunded bookings are final apart from the
+// refund of a late payment.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusFailed, BookingStatusCancelled, BookingStatusExpired},
+	BookingStatusConfirmed: {BookingStatusCancelled, BookingStatusRefunded},
+	BookingStatusFailed:    {BookingStatusRefunded},
+	BookingStatusCancelled: {BookingStatusRefunded},
+	BookingStatusExpired:   {BookingStatusRefunded},
+}
+
+// CanTransition reports whether a booking may move from one status to another.
+func CanTransition(from, to BookingStatus) bool {
+	for _, allowed := range bookingTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// BookingStatusHistory records a single status change of a booking.
+type BookingStatusHistory struct {
+	ID         uint          `gorm:"primaryKey" json:"id"`
+	BookingID  uint          `gorm:"not null;index" json:"booking_id"`
+	FromStatus BookingStatus `json:"from_status"`
+	ToStatus   BookingStatus `gorm:"not null" json:"to_status"`
+	Reason     string        `json:"reason"`
+	// Actor is who made the change, e.g. "user:12", "admin:1" or "system:cleanup".
+	Actor     string    `gorm:"not null" json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (BookingStatusHistory) TableName() string {
+	return "booking_status_history"
+}
+
+// Actors of status changes made by the service itself.
+const (
+	ActorCleanup        = "system:cleanup"
+	ActorPaymentService = "payment-service"
+	ActorEventService   = "event-service"
+)
+
+// UserActor names a user (or an admin acting on a user's booking) as the
+// actor of a status change.
+func UserActor(userID uint, isAdmin bool) string {
+	if isAdmin {
+		return fmt.Sprintf("admin:%d", userID)
+	}
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// ErrInvalidTransition is matched by every TransitionError with errors.Is.
+var ErrInvalidTransition = &Error{Message: "Booking status change is not allowed"}
+
+// TransitionError rejects a status change the state machine does not allow.
+type TransitionError struct {
+	BookingID uint
+	From      BookingStatus
+	To        BookingStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("booking %d cannot move from %s to %s", e.BookingID, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
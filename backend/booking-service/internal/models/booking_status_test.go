@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []BookingStatus{
+		BookingStatusPending, BookingStatusConfirmed, BookingStatusFailed,
+		BookingStatusCancelled, BookingStatusExpired, BookingStatusRefunded,
+	}
+	allowed := map[[2]BookingStatus]bool{
+		{BookingStatusPending, BookingStatusConfirmed}:   true,
+		{BookingStatusPending, BookingStatusFailed}:      true,
+		{BookingStatusPending, BookingStatusCancelled}:   true,
+		{BookingStatusPending, BookingStatusExpired}:     true,
+		{BookingStatusConfirmed, BookingStatusCancelled}: true,
+		{BookingStatusConfirmed, BookingStatusRefunded}:  true,
+		// Late payments of bookings that are no longer payable are refunded
+		{BookingStatusFailed, BookingStatusRefunded}:    true,
+		{BookingStatusCancelled, BookingStatusRefunded}: true,
+		{BookingStatusExpired, BookingStatusRefunded}:   true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]BookingStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if CanTransition("", BookingStatusConfirmed) {
+		t.Error("a booking without a status may be confirmed")
+	}
+}
+
+func TestTransitionErrorIsInvalidTransition(t *testing.T) {
+	var err error = &TransitionError{BookingID: 4, From: BookingStatusExpired, To: BookingStatusConfirmed}
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Error("TransitionError does not match ErrInvalidTransition")
+	}
+	if err.Error() != "booking 4 cannot move from expired to confirmed" {
+		t.Errorf("message %q", err.Error())
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "fmt"
+
+// ErrPurchaseLimit is matched by every PurchaseLimitError with errors.Is.
+var ErrPurchaseLimit = &Error{Message: "Per-user ticket limit reached"}
+
+// PurchaseLimitError reports a booking that would take a user past the
+// tickets they may hold for an event, or for one of its tiers.
+type PurchaseLimitError struct {
+	Tier  string // the tier's name, empty for the event's own limit
+	Limit int
+	Held  int // tickets of the user's pending and confirmed bookings
+}
+
+func (e *PurchaseLimitError) Error() string {
+	scope := "this event"
+	if e.Tier != "" {
+		scope = fmt.Sprintf("the %s tickets of this event", e.Tier)
+	}
+	if e.Held == 0 {
+		return fmt.Sprintf("You can buy at most %d tickets for %s", e.Limit, scope)
+	}
+	return fmt.Sprintf("You can buy at most %d tickets for %s and already have %d in your bookings", e.Limit, scope, e.Held)
+}
+
+func (e *PurchaseLimitError) Is(target error) bool {
+	return target == ErrPurchaseLimit
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SagaStatus is the state of a booking saga as a whole.
+type SagaStatus string
+
+const (
+	// SagaStatusRunning means the saga is moving forward or waiting for a message.
+	SagaStatusRunning SagaStatus = "running"
+	// SagaStatusCompleted means the ticket was issued.
+	SagaStatusCompleted SagaStatus = "completed"
+	// SagaStatusCompensating means a step failed and compensation is under way.
+	SagaStatusCompensating SagaStatus = "compensating"
+	// SagaStatusCompensated means every completed step was undone.
+	SagaStatusCompensated SagaStatus = "compensated"
+	// SagaStatusFailed means a compensation step failed and needs attention.
+	SagaStatusFailed SagaStatus = "failed"
+)
+
+// SagaStepName names a forward or compensation step of a booking saga.
+type SagaStepName string
+
+const (
+	SagaStepLockSeats      SagaStepName = "lock_seats"
+	SagaStepCreateBooking  SagaStepName = "create_booking"
+	SagaStepPayment        SagaStepName = "payment"
+	SagaStepConfirmBooking SagaStepName = "confirm_booking"
+	SagaStepIssueTicket    SagaStepName = "issue_ticket"
+
+	// Compensation steps
+	SagaStepUnlockSeats   SagaStepName = "unlock_seats"
+	SagaStepRefundPayment SagaStepName = "refund_payment"
+)
+
+type SagaStepStatus string
+
+const (
+	SagaStepStatusStarted   SagaStepStatus = "started"
+	SagaStepStatusSucceeded SagaStepStatus = "succeeded"
+	SagaStepStatusFailed    SagaStepStatus = "failed"
+)
+
+// Saga tracks a purchase through lock → create → pay → confirm → issue
+// ticket. It is started before the booking exists, so BookingID is set once
+// the booking has been created.
+type Saga struct {
+	gorm.Model
+	BookingID     *uint        `gorm:"uniqueIndex" json:"booking_id"`
+	UserID        uint         `gorm:"not null" json:"user_id"`
+	EventID       uint         `gorm:"not null" json:"event_id"`
+	Status        SagaStatus   `gorm:"not null;index" json:"status"`
+	CurrentStep   SagaStepName `json:"current_step"`
+	FailureReason string       `json:"failure_reason"`
+	Steps         []SagaStep   `gorm:"foreignKey:SagaID" json:"steps"`
+}
+
+// SagaStep is one entry in a saga's history.
+type SagaStep struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	SagaID       uint           `gorm:"not null;index" json:"saga_id"`
+	Step         SagaStepName   `gorm:"not null" json:"step"`
+	Status       SagaStepStatus `gorm:"not null" json:"status"`
+	Compensation bool           `gorm:"default:false" json:"compensation"`
+	Detail       string         `json:"detail"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+var ErrSagaNotFound = &Error{Message: "No saga found for this booking"}
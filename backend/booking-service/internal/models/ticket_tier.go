@@ -0,0 +1,41 @@
+package models
+
+// ErrSeatsRejected is matched by every SeatsRejectedError with errors.Is.
+var ErrSeatsRejected = &Error{Message: "The Event Service rejected the requested tickets"}
+
+// SeatsRejectedError carries the Event Service's reason for refusing a seat
+// lock, such as a tier that is not on sale or an order over its limit.
+type SeatsRejectedError struct {
+	Reason string
+}
+
+func (e *SeatsRejectedError) Error() string {
+	return e.Reason
+}
+
+func (e *SeatsRejectedError) Is(target error) bool {
+	return target == ErrSeatsRejected
+}
+
+// TierSales is the sales report line of one ticket tier of an event.
+type TierSales struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Capacity  int     `json:"capacity"`
+	Available int     `json:"available"`
+	Sold      int     `json:"sold"`    // tickets in confirmed bookings
+	Pending   int     `json:"pending"` // tickets held by unpaid bookings
+	Revenue   float64 `json:"revenue"` // ticket revenue of confirmed bookings, before fees
+}
+
+// TicketInventory is the number of tickets of one ticket class of an event
+// held by pending bookings and sold to confirmed ones. The Event Service
+// rebuilds and reconciles its seat counters from it.
+type TicketInventory struct {
+	EventID      uint     `json:"event_id"`
+	TicketClass  string   `json:"ticket_class"`
+	Pending      int      `json:"pending"`
+	Confirmed    int      `json:"confirmed"`
+	PendingSeats []string `json:"pending_seats"`
+}
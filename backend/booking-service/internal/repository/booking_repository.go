@@ -5,63 +5,218 @@ import (
 
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/database"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingRepository interface {
 	CreateBooking(booking *models.Booking) error
-	UpdateBookingStatus(id uint, status models.BookingStatus) error
+	// TransitionBooking moves a booking to a new status, together with any
+	// other column updates, records the change in its history and returns
+	// the status it was in, read under the row lock. It returns a
+	// *models.TransitionError when the state machine does not allow it.
+	TransitionBooking(id uint, to models.BookingStatus, reason, actor string, updates map[string]interface{}) (models.BookingStatus, error)
+	RecordStatusHistory(entry *models.BookingStatusHistory) error
+	GetStatusHistory(bookingID uint) ([]models.BookingStatusHistory, error)
+	SetRefundedAmount(id uint, refundedAmount float64) error
+	// SetCancellationRefund sets the amount a cancelled booking is refunded.
+	SetCancellationRefund(id uint, refundAmount float64) error
 	GetBookingsByEventID(eventID uint) ([]models.Booking, error)
 	GetBookingsByEventIDs(eventIDs []uint) ([]models.Booking, error)
 	GetBookingsByUserID(userID uint) ([]models.Booking, error)
 	GetBookingByID(id uint) (*models.Booking, error)
 	GetAllBookings() ([]models.Booking, error)
 	GetStalePendingBookings(olderThan time.Time) ([]models.Booking, error)
+	// GetTierSales counts the tickets of an event's confirmed and pending
+	// bookings per ticket class. Only Code, Sold, Pending and Revenue are set.
+	GetTierSales(eventID uint) ([]models.TierSales, error)
+	// GetActiveBookings returns the pending and confirmed bookings of an
+	// event, or of every event if eventID is 0.
+	GetActiveBookings(eventID uint) ([]models.Booking, error)
+	// GetUserTicketCounts counts the tickets of a user's pending and
+	// confirmed bookings of an event, in total and per ticket class.
+	GetUserTicketCounts(userID, eventID uint) (int, map[string]int, error)
+	// LockUserPurchases makes other transactions booking for the same user
+	// and event wait until this one ends. Only useful inside Transaction.
+	LockUserPurchases(userID, eventID uint) error
+	EnqueueOutboxMessage(message *outbox.Message) error
+	// Transaction runs fn against a repository bound to a single database transaction.
+	Transaction(fn func(repo BookingRepository) error) error
 }
 
-type bookingRepository struct{}
+type bookingRepository struct {
+	tx *gorm.DB
+}
 
 func NewBookingRepository() BookingRepository {
 	return &bookingRepository{}
 }
 
+func (r *bookingRepository) db() *gorm.DB {
+	if r.tx != nil {
+		return r.tx
+	}
+	return database.DB
+}
+
+func (r *bookingRepository) Transaction(fn func(repo BookingRepository) error) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		return fn(&bookingRepository{tx: tx})
+	})
+}
+
+func (r *bookingRepository) EnqueueOutboxMessage(message *outbox.Message) error {
+	return r.db().Create(message).Error
+}
+
 func (r *bookingRepository) GetStalePendingBookings(olderThan time.Time) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := database.DB.Where("status = ? AND created_at < ?", models.BookingStatusPending, olderThan).Find(&bookings).Error
+	err := r.db().Preload("Items").Where("status = ? AND created_at < ?", models.BookingStatusPending, olderThan).Find(&bookings).Error
 	return bookings, err
 }
 
 func (r *bookingRepository) CreateBooking(booking *models.Booking) error {
-	return database.DB.Create(booking).Error
+	return r.db().Create(booking).Error
+}
+
+func (r *bookingRepository) TransitionBooking(id uint, to models.BookingStatus, reason, actor string, updates map[string]interface{}) (models.BookingStatus, error) {
+	var booking models.Booking
+	err := r.db().Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent transitions are checked one after the other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&booking, id).Error; err != nil {
+			return err
+		}
+		if !models.CanTransition(booking.Status, to) {
+			return &models.TransitionError{BookingID: id, From: booking.Status, To: to}
+		}
+
+		values := map[string]interface{}{"status": to}
+		for column, value := range updates {
+			values[column] = value
+		}
+		if err := tx.Model(&models.Booking{}).Where("id = ?", id).Updates(values).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.BookingStatusHistory{
+			BookingID:  id,
+			FromStatus: booking.Status,
+			ToStatus:   to,
+			Reason:     reason,
+			Actor:      actor,
+		}).Error
+	})
+	return booking.Status, err
+}
+
+func (r *bookingRepository) RecordStatusHistory(entry *models.BookingStatusHistory) error {
+	return r.db().Create(entry).Error
+}
+
+func (r *bookingRepository) GetStatusHistory(bookingID uint) ([]models.BookingStatusHistory, error) {
+	var history []models.BookingStatusHistory
+	err := r.db().Where("booking_id = ?", bookingID).Order("id ASC").Find(&history).Error
+	return history, err
 }
 
-func (r *bookingRepository) UpdateBookingStatus(id uint, status models.BookingStatus) error {
-	return database.DB.Model(&models.Booking{}).Where("id = ?", id).Update("status", status).Error
+func (r *bookingRepository) SetRefundedAmount(id uint, refundedAmount float64) error {
+	return r.db().Model(&models.Booking{}).Where("id = ?", id).Update("refunded_amount", refundedAmount).Error
+}
+
+func (r *bookingRepository) SetCancellationRefund(id uint, refundAmount float64) error {
+	return r.db().Model(&models.Booking{}).Where("id = ?", id).Update("refund_amount", refundAmount).Error
 }
 
 func (r *bookingRepository) GetBookingByID(id uint) (*models.Booking, error) {
 	var booking models.Booking
-	err := database.DB.First(&booking, id).Error
+	err := r.db().Preload("Items").First(&booking, id).Error
 	return &booking, err
 }
 
 func (r *bookingRepository) GetBookingsByEventID(eventID uint) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := database.DB.Where("event_id = ?", eventID).Find(&bookings).Error
+	err := r.db().Preload("Items").Where("event_id = ?", eventID).Find(&bookings).Error
 	return bookings, err
 }
 func (r *bookingRepository) GetAllBookings() ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := database.DB.Find(&bookings).Error
+	err := r.db().Preload("Items").Find(&bookings).Error
 	return bookings, err
 }
 func (r *bookingRepository) GetBookingsByEventIDs(eventIDs []uint) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := database.DB.Where("event_id IN ?", eventIDs).Find(&bookings).Error
+	err := r.db().Preload("Items").Where("event_id IN ?", eventIDs).Find(&bookings).Error
+	return bookings, err
+}
+
+func (r *bookingRepository) GetTierSales(eventID uint) ([]models.TierSales, error) {
+	var sales []models.TierSales
+	err := r.db().Table("booking_items").
+		Select(`booking_items.ticket_class AS code,
+			SUM(CASE WHEN bookings.status = ? THEN 1 ELSE 0 END) AS sold,
+			SUM(CASE WHEN bookings.status = ? THEN 1 ELSE 0 END) AS pending,
+			SUM(CASE WHEN bookings.status = ? THEN booking_items.price ELSE 0 END) AS revenue`,
+			models.BookingStatusConfirmed, models.BookingStatusPending, models.BookingStatusConfirmed).
+		Joins("JOIN bookings ON bookings.id = booking_items.booking_id AND bookings.deleted_at IS NULL").
+		Where("bookings.event_id = ? AND booking_items.deleted_at IS NULL", eventID).
+		Group("booking_items.ticket_class").
+		Scan(&sales).Error
+	return sales, err
+}
+
+func (r *bookingRepository) GetActiveBookings(eventID uint) ([]models.Booking, error) {
+	var bookings []models.Booking
+	query := r.db().Preload("Items").Where("status IN ?", []models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed})
+	if eventID != 0 {
+		query = query.Where("event_id = ?", eventID)
+	}
+	err := query.Find(&bookings).Error
 	return bookings, err
 }
 
+func (r *bookingRepository) GetUserTicketCounts(userID, eventID uint) (int, map[string]int, error) {
+	active := []models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed}
+
+	// Bookings from before booking items only have a seat count
+	var total int
+	err := r.db().Model(&models.Booking{}).
+		Select("COALESCE(SUM(seat_count), 0)").
+		Where("user_id = ? AND event_id = ? AND status IN ?", userID, eventID, active).
+		Scan(&total).Error
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var rows []struct {
+		TicketClass string
+		Count       int
+	}
+	err = r.db().Table("booking_items").
+		Select("booking_items.ticket_class, COUNT(*) AS count").
+		Joins("JOIN bookings ON bookings.id = booking_items.booking_id AND bookings.deleted_at IS NULL").
+		Where("bookings.user_id = ? AND bookings.event_id = ? AND bookings.status IN ? AND booking_items.deleted_at IS NULL", userID, eventID, active).
+		Group("booking_items.ticket_class").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.TicketClass] = row.Count
+	}
+	return total, counts, nil
+}
+
+func (r *bookingRepository) LockUserPurchases(userID, eventID uint) error {
+	// A transaction-level advisory lock on the (user, event) pair, released
+	// on commit or rollback
+	return r.db().Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(userID), int32(eventID)).Error
+}
+
 func (r *bookingRepository) GetBookingsByUserID(userID uint) ([]models.Booking, error) {
 	var bookings []models.Booking
-	err := database.DB.Where("user_id = ? AND status = ?", userID, models.BookingStatusConfirmed).Find(&bookings).Error
+	err := r.db().Preload("Items").Where("user_id = ? AND status = ?", userID, models.BookingStatusConfirmed).Find(&bookings).Error
 	return bookings, err
 }
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/database"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type SagaRepository interface {
+	CreateSaga(saga *models.Saga) error
+	AttachBooking(sagaID, bookingID uint) error
+	// RecordStep appends a step to the saga's history and moves the saga to
+	// the given status in one transaction.
+	RecordStep(saga *models.Saga, step *models.SagaStep, status models.SagaStatus, failureReason string) error
+	// GetSagaByBookingID returns the saga with its steps in order.
+	GetSagaByBookingID(bookingID uint) (*models.Saga, error)
+}
+
+type sagaRepository struct{}
+
+func NewSagaRepository() SagaRepository {
+	return &sagaRepository{}
+}
+
+func (r *sagaRepository) CreateSaga(saga *models.Saga) error {
+	return database.DB.Create(saga).Error
+}
+
+func (r *sagaRepository) AttachBooking(sagaID, bookingID uint) error {
+	return database.DB.Model(&models.Saga{}).Where("id = ?", sagaID).Update("booking_id", bookingID).Error
+}
+
+func (r *sagaRepository) RecordStep(saga *models.Saga, step *models.SagaStep, status models.SagaStatus, failureReason string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		step.SagaID = saga.ID
+		if err := tx.Create(step).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"status":       status,
+			"current_step": step.Step,
+		}
+		if failureReason != "" {
+			updates["failure_reason"] = failureReason
+		}
+		return tx.Model(&models.Saga{}).Where("id = ?", saga.ID).Updates(updates).Error
+	})
+}
+
+func (r *sagaRepository) GetSagaByBookingID(bookingID uint) (*models.Saga, error) {
+	var saga models.Saga
+	err := database.DB.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Where("booking_id = ?", bookingID).First(&saga).Error
+	return &saga, err
+}
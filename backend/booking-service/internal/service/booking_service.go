@@ -1,37 +1,52 @@
 package service
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/messaging"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
 	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
 )
 
 type BookingService interface {
-	CreateBooking(userID, eventID uint, seatCount int, amount float64, seats string) (*models.Booking, error)
+	// CreateBooking locks and books tickets; admissionToken is the user's
+	// admission from the event's waiting room, if it has one.
+	CreateBooking(userID, eventID uint, seatCount int, ticketClass string, amount float64, seats, admissionToken string) (*models.Booking, error)
 	ConfirmBooking(bookingID uint) error
 	GetSales(eventID uint) ([]models.Booking, error)
+	// GetTierSales reports the sales of an event to its organizer.
+	GetTierSales(eventID, organizerID uint) ([]models.TierSales, error)
+	GetTicketInventory(eventID uint) ([]models.TicketInventory, error)
 	GetOrganizerSales(token string) ([]models.Booking, error)
 	GetUserBookings(userID uint) ([]models.Booking, error)
 	GetBookingByID(bookingID uint) (*models.Booking, error)
 	GetAllBookings() ([]models.Booking, error)
 	CancelStaleBookings() error
+	CancelBooking(bookingID, requesterID uint, isAdmin bool, reason string) (*models.Booking, error)
+	// CancelEventBookings cancels every pending and confirmed booking of a
+	// cancelled event and asks the payment service to refund the confirmed
+	// ones in full.
+	CancelEventBookings(eventID, organizerID uint, reason string) error
+	RecordRefund(bookingID uint, totalRefunded float64) error
+	TicketIssued(bookingID uint) error
+	GetBookingSaga(bookingID, requesterID uint, isAdmin bool) (*models.Saga, error)
+	GetBookingHistory(bookingID, requesterID uint, isAdmin bool) ([]models.BookingStatusHistory, error)
 }
 
 type bookingService struct {
-	repo repository.BookingRepository
+	repo  repository.BookingRepository
+	sagas repository.SagaRepository
 }
 
-func NewBookingService(repo repository.BookingRepository) BookingService {
-	return &bookingService{repo: repo}
+func NewBookingService(repo repository.BookingRepository, sagas repository.SagaRepository) BookingService {
+	return &bookingService{repo: repo, sagas: sagas}
 }
 
 func (s *bookingService) CancelStaleBookings() error {
@@ -42,50 +57,183 @@ func (s *bookingService) CancelStaleBookings() error {
 		return err
 	}
 
-	eventServiceURL := os.Getenv("EVENT_SERVICE_URL")
-	if eventServiceURL == "" {
-		eventServiceURL = "http://localhost:3003"
-	}
-
 	for _, booking := range bookings {
-		fmt.Printf("Cancelling stale booking: %d\n", booking.ID)
+		fmt.Printf("Expiring stale booking: %d\n", booking.ID)
 
-		// Unlock seats in Event Service
-		var seatList []struct {
-			ID string `json:"id"`
+		// Expire the booking unless a payment confirmed it in the meantime
+		_, err := s.repo.TransitionBooking(booking.ID, models.BookingStatusExpired, "No payment within 15 minutes", models.ActorCleanup, nil)
+		if errors.Is(err, models.ErrInvalidTransition) {
+			continue
 		}
-		var seatIDs []string
-		if err := json.Unmarshal([]byte(booking.Seats), &seatList); err == nil {
-			for _, seat := range seatList {
-				seatIDs = append(seatIDs, seat.ID)
-			}
+		if err != nil {
+			fmt.Printf("Failed to update booking status %d: %v\n", booking.ID, err)
+			continue
 		}
 
-		unlockReq := map[string]interface{}{
-			"count":    booking.SeatCount,
-			"seat_ids": seatIDs,
+		// Compensate the saga: unlock seats in Event Service. Failures are
+		// only logged, as the booking has already expired
+		saga := s.sagaForBooking(booking.ID)
+		s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusFailed, "No payment within 15 minutes", models.SagaStatusCompensating)
+		s.compensateSeats(saga, &booking)
+
+		// Audit Log
+		messaging.PublishAuditLog(booking.UserID, "EXPIRE_BOOKING", fmt.Sprintf("Expired stale booking %d", booking.ID))
+	}
+	return nil
+}
+
+// CancelBooking cancels a pending or confirmed booking on behalf of its owner
+// (or an admin). Confirmed bookings must be cancelled before the event's
+// cancellation deadline and are refunded according to its refund percentage.
+func (s *bookingService) CancelBooking(bookingID, requesterID uint, isAdmin bool, reason string) (*models.Booking, error) {
+	booking, err := s.repo.GetBookingByID(bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBookingNotFound
 		}
-		unlockBody, _ := json.Marshal(unlockReq)
+		return nil, err
+	}
 
-		// We don't strictly check error here, just log it, as we want to proceed with cancellation
-		resp, err := http.Post(fmt.Sprintf("%s/api/events/%d/unlock", eventServiceURL, booking.EventID), "application/json", bytes.NewBuffer(unlockBody))
+	if booking.UserID != requesterID && !isAdmin {
+		return nil, models.ErrForbidden
+	}
+	if booking.Status != models.BookingStatusPending && booking.Status != models.BookingStatusConfirmed {
+		return nil, models.ErrNotCancellable
+	}
+
+	var refundAmount float64
+	if booking.Status == models.BookingStatusConfirmed {
+		event, err := fetchEvent(booking.EventID)
 		if err != nil {
-			fmt.Printf("Failed to unlock seats for booking %d: %v\n", booking.ID, err)
-		} else {
-			resp.Body.Close()
+			return nil, err
 		}
 
-		// Update status to Cancelled
-		if err := s.repo.UpdateBookingStatus(booking.ID, models.BookingStatusCancelled); err != nil {
-			fmt.Printf("Failed to update booking status %d: %v\n", booking.ID, err)
-		} else {
-			// Audit Log
-			messaging.PublishAuditLog(booking.UserID, "CANCEL_BOOKING", fmt.Sprintf("Cancelled stale booking %d", booking.ID))
+		deadline := event.Date.Add(-time.Duration(event.CancellationDeadlineHours) * time.Hour)
+		if time.Now().After(deadline) {
+			return nil, models.ErrDeadlinePassed
+		}
+		refundAmount = roundAmount(booking.TotalAmount * event.RefundPercentage / 100)
+	}
+
+	if reason == "" {
+		reason = "Cancelled by user"
+	}
+
+	correlationID := events.BookingCorrelationID(booking.ID)
+	cancelled, err := events.Marshal(events.TypeBookingCancelled, messaging.Producer, correlationID, events.BookingCancelled{
+		BookingID:    booking.ID,
+		UserID:       booking.UserID,
+		EventID:      booking.EventID,
+		SeatCount:    booking.SeatCount,
+		RefundAmount: refundAmount,
+		Reason:       reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	refund, err := events.Marshal(events.TypeRefundRequested, messaging.Producer, correlationID, events.RefundRequested{
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		Amount:    refundAmount,
+		Reason:    reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Transaction(func(repo repository.BookingRepository) error {
+		_, err := repo.TransitionBooking(booking.ID, models.BookingStatusCancelled, reason, models.UserActor(requesterID, isAdmin), map[string]interface{}{
+			"cancelled_at":        time.Now(),
+			"cancellation_reason": reason,
+			"refund_amount":       refundAmount,
+		})
+		if err != nil {
+			return err
+		}
+		if err := repo.EnqueueOutboxMessage(&outbox.Message{
+			RoutingKey: messaging.BookingCancelledQueue,
+			Payload:    string(cancelled),
+		}); err != nil {
+			return err
+		}
+		if refundAmount <= 0 {
+			return nil
 		}
+		return repo.EnqueueOutboxMessage(&outbox.Message{
+			RoutingKey: messaging.RefundRequestedQueue,
+			Payload:    string(refund),
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	// Return the seats to the inventory. The booking is already cancelled, so
+	// failures are logged rather than surfaced to the user. A pending booking
+	// is still in its saga, which is compensated.
+	if booking.Status == models.BookingStatusConfirmed {
+		if err := releaseBookingSeats(booking); err != nil {
+			fmt.Printf("Failed to return seats for cancelled booking %d: %v\n", booking.ID, err)
+		}
+	} else {
+		saga := s.sagaForBooking(booking.ID)
+		s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusFailed, "Cancelled before payment: "+reason, models.SagaStatusCompensating)
+		s.compensateSeats(saga, booking)
+	}
+
+	// Audit Log
+	messaging.PublishAuditLog(requesterID, "CANCEL_BOOKING", fmt.Sprintf("Cancelled booking %d (refund %.2f)", booking.ID, refundAmount))
+
+	return s.repo.GetBookingByID(booking.ID)
+}
+
+// RecordRefund stores the total amount the payment service has refunded for a booking.
+func (s *bookingService) RecordRefund(bookingID uint, totalRefunded float64) error {
+	booking, err := s.repo.GetBookingByID(bookingID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SetRefundedAmount(booking.ID, totalRefunded); err != nil {
+		return err
+	}
+
+	// The refund of a cancelled or expired booking, or a full refund of a
+	// confirmed one, makes the booking refunded
+	if booking.Status != models.BookingStatusConfirmed || totalRefunded >= booking.TotalAmount {
+		reason := fmt.Sprintf("Refunded %.2f", totalRefunded)
+		_, err := s.repo.TransitionBooking(booking.ID, models.BookingStatusRefunded, reason, models.ActorPaymentService, nil)
+		if err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+			return err
+		}
+	}
+
+	// A refund the saga asked for completes its compensation
+	if saga := s.sagaForBooking(booking.ID); saga != nil && saga.Status == models.SagaStatusCompensating && saga.CurrentStep == models.SagaStepRefundPayment {
+		s.recordSagaStep(saga, models.SagaStepRefundPayment, models.SagaStepStatusSucceeded, fmt.Sprintf("Refunded %.2f", totalRefunded), models.SagaStatusCompensated)
+	}
+
+	// Audit Log
+	messaging.PublishAuditLog(booking.UserID, "REFUND_BOOKING", fmt.Sprintf("Refunded %.2f for booking %d", totalRefunded, booking.ID))
 	return nil
 }
 
+// GetBookingHistory returns a booking's status changes, oldest first.
+func (s *bookingService) GetBookingHistory(bookingID, requesterID uint, isAdmin bool) ([]models.BookingStatusHistory, error) {
+	booking, err := s.repo.GetBookingByID(bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBookingNotFound
+		}
+		return nil, err
+	}
+	if booking.UserID != requesterID && !isAdmin {
+		return nil, models.ErrForbidden
+	}
+
+	return s.repo.GetStatusHistory(booking.ID)
+}
+
 func (s *bookingService) GetBookingByID(bookingID uint) (*models.Booking, error) {
 	return s.repo.GetBookingByID(bookingID)
 }
@@ -95,13 +243,8 @@ func (s *bookingService) GetAllBookings() ([]models.Booking, error) {
 }
 
 func (s *bookingService) GetOrganizerSales(token string) ([]models.Booking, error) {
-	eventServiceURL := os.Getenv("EVENT_SERVICE_URL")
-	if eventServiceURL == "" {
-		eventServiceURL = "http://localhost:3003"
-	}
-
 	// Call Event Service to get organizer's events
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/events/my", eventServiceURL), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/events/my", getEventServiceURL()), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -137,61 +280,140 @@ func (s *bookingService) GetOrganizerSales(token string) ([]models.Booking, erro
 	return s.repo.GetBookingsByEventIDs(eventIDs)
 }
 
-func (s *bookingService) CreateBooking(userID, eventID uint, seatCount int, amount float64, seats string) (*models.Booking, error) {
+func (s *bookingService) CreateBooking(userID, eventID uint, seatCount int, ticketClass string, amount float64, seats, admissionToken string) (*models.Booking, error) {
 	// 1. Validate Token (Already done by middleware)
 
-	// Determine ticket class from seats
-	ticketClass := "normal"
 	var seatList []struct {
 		ID string `json:"id"`
 	}
 	var seatIDs []string
-	if err := json.Unmarshal([]byte(seats), &seatList); err == nil && len(seatList) > 0 {
-		if strings.HasPrefix(seatList[0].ID, "vvip") {
-			ticketClass = "vvip"
-		} else if strings.HasPrefix(seatList[0].ID, "vip") {
-			ticketClass = "vip"
-		}
+	if err := json.Unmarshal([]byte(seats), &seatList); err == nil {
 		for _, seat := range seatList {
 			seatIDs = append(seatIDs, seat.ID)
 		}
 	}
+	if len(seatIDs) > 0 && len(seatIDs) != seatCount {
+		return nil, models.ErrSeatCountMismatch
+	}
+
+	// 2. Price the booking from the Event Service's ticket prices
+	event, err := fetchEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []seatDetails
+	if len(seatIDs) > 0 {
+		resolved, err = resolveSeats(eventID, seatIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// 2. Check Inventory & Lock Seats (Call Event Service)
-	eventServiceURL := os.Getenv("EVENT_SERVICE_URL")
-	if eventServiceURL == "" {
-		eventServiceURL = "http://localhost:3003"
+	items, serviceFee, totalAmount, err := priceBooking(event, seatCount, ticketClass, resolved)
+	if err != nil {
+		return nil, err
+	}
+	if amount > 0 && !amountsMatch(amount, totalAmount) {
+		return nil, models.ErrAmountMismatch
+	}
+	if err := checkSalesWindow(event, items, time.Now()); err != nil {
+		return nil, err
+	}
+	// Bookings over the user's purchase limits are turned away before any
+	// seat is locked; the limits are checked again under a lock below
+	limited := hasPurchaseLimits(event, items)
+	if limited {
+		if err := checkPurchaseLimits(s.repo, userID, event, items); err != nil {
+			return nil, err
+		}
 	}
 
-	lockReq := map[string]interface{}{
-		"count":        seatCount,
-		"ticket_class": ticketClass,
-		"seat_ids":     seatIDs,
+	// The hold token is generated before the saga is started, so a saga
+	// row is never left running without a step
+	holdToken, err := newHoldToken()
+	if err != nil {
+		return nil, err
+	}
+
+	saga, err := s.startSaga(userID, eventID)
+	if err != nil {
+		return nil, err
 	}
-	lockBody, _ := json.Marshal(lockReq)
 
-	lockResp, err := http.Post(fmt.Sprintf("%s/api/events/%d/lock", eventServiceURL, eventID), "application/json", bytes.NewBuffer(lockBody))
-	if err != nil || lockResp.StatusCode != http.StatusOK {
-		return nil, errors.New("failed to lock seats or not enough seats")
+	// 3. Check Inventory & Lock Seats (Call Event Service), one ticket class
+	// at a time under a single hold
+	classes, grouped := seatsByClass(items)
+	counts := countByClass(items)
+	var locked []string
+	for _, class := range classes {
+		if err := lockSeats(eventID, userID, holdToken, admissionToken, counts[class], class, grouped[class]); err != nil {
+			s.recordSagaStep(saga, models.SagaStepLockSeats, models.SagaStepStatusFailed, err.Error(), models.SagaStatusCompensated)
+			if len(locked) > 0 {
+				unlockClasses(eventID, userID, holdToken, locked, counts, grouped)
+			}
+			return nil, err
+		}
+		locked = append(locked, class)
 	}
+	s.recordSagaStep(saga, models.SagaStepLockSeats, models.SagaStepStatusSucceeded, fmt.Sprintf("%d seats", seatCount), models.SagaStatusRunning)
 
-	// 3. Create Booking Record
+	// 4. Create Booking Record
 	booking := &models.Booking{
 		UserID:      userID,
 		EventID:     eventID,
 		SeatCount:   seatCount,
-		TotalAmount: amount,
+		ServiceFee:  serviceFee,
+		TotalAmount: totalAmount,
 		Seats:       seats,
 		Status:      models.BookingStatusPending,
+		Items:       items,
+		HoldToken:   holdToken,
 	}
 
 	fmt.Printf("Creating booking: %+v\n", booking)
-	if err := s.repo.CreateBooking(booking); err != nil {
+	err = s.repo.Transaction(func(repo repository.BookingRepository) error {
+		if limited {
+			// Concurrent bookings of the user for this event wait here, so
+			// each one counts the bookings created before it
+			if err := repo.LockUserPurchases(userID, eventID); err != nil {
+				return err
+			}
+			if err := checkPurchaseLimits(repo, userID, event, items); err != nil {
+				return err
+			}
+		}
+		if err := repo.CreateBooking(booking); err != nil {
+			return err
+		}
+		return repo.RecordStatusHistory(&models.BookingStatusHistory{
+			BookingID: booking.ID,
+			ToStatus:  models.BookingStatusPending,
+			Reason:    "Booking created",
+			Actor:     models.UserActor(userID, false),
+		})
+	})
+	if err != nil {
 		fmt.Printf("Error creating booking: %v\n", err)
+		s.recordSagaStep(saga, models.SagaStepCreateBooking, models.SagaStepStatusFailed, err.Error(), models.SagaStatusCompensating)
+		if unlockErr := unlockClasses(eventID, userID, holdToken, locked, counts, grouped); unlockErr != nil {
+			s.recordSagaStep(saga, models.SagaStepUnlockSeats, models.SagaStepStatusFailed, unlockErr.Error(), models.SagaStatusFailed)
+		} else {
+			s.recordSagaStep(saga, models.SagaStepUnlockSeats, models.SagaStepStatusSucceeded, "", models.SagaStatusCompensated)
+		}
 		return nil, err
 	}
 	fmt.Println("Booking created successfully in DB")
 
+	if err := s.sagas.AttachBooking(saga.ID, booking.ID); err != nil {
+		fmt.Printf("Failed to attach booking %d to saga %d: %v\n", booking.ID, saga.ID, err)
+	}
+	if err := attachHoldBooking(eventID, holdToken, booking.ID); err != nil {
+		fmt.Printf("Failed to attach booking %d to its seat hold: %v\n", booking.ID, err)
+	}
+	s.recordSagaStep(saga, models.SagaStepCreateBooking, models.SagaStepStatusSucceeded, "", models.SagaStatusRunning)
+	s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusStarted, "Awaiting payment", models.SagaStatusRunning)
+
 	// Audit Log
 	messaging.PublishAuditLog(userID, "CREATE_BOOKING", fmt.Sprintf("Created booking %d for event %d", booking.ID, eventID))
 
@@ -199,31 +421,69 @@ func (s *bookingService) CreateBooking(userID, eventID uint, seatCount int, amou
 }
 
 func (s *bookingService) ConfirmBooking(bookingID uint) error {
-	if err := s.repo.UpdateBookingStatus(bookingID, models.BookingStatusConfirmed); err != nil {
+	booking, err := s.repo.GetBookingByID(bookingID)
+	if err != nil {
 		return err
 	}
 
-	// Fetch booking details
-	booking, err := s.repo.GetBookingByID(bookingID)
+	payload, err := events.Marshal(events.TypeBookingConfirmed, messaging.Producer, events.BookingCorrelationID(booking.ID), events.BookingConfirmed{
+		BookingID:     booking.ID,
+		UserID:        booking.UserID,
+		EventID:       booking.EventID,
+		Amount:        booking.TotalAmount,
+		SeatCount:     booking.SeatCount,
+		Seats:         booking.Seats,
+		TicketClasses: countByClass(booking.Items),
+		HoldToken:     booking.HoldToken,
+	})
 	if err != nil {
-		fmt.Printf("Failed to fetch booking for event publishing: %v\n", err)
-		return nil
+		return err
 	}
 
-	// Publish event
-	event := messaging.BookingConfirmedEvent{
-		BookingID: booking.ID,
-		UserID:    booking.UserID,
-		EventID:   booking.EventID,
-		Amount:    booking.TotalAmount,
-		SeatCount: booking.SeatCount,
-		Seats:     booking.Seats,
+	// The status change and its event are committed together; the outbox relay publishes the event
+	err = s.repo.Transaction(func(repo repository.BookingRepository) error {
+		_, err := repo.TransitionBooking(bookingID, models.BookingStatusConfirmed, "Payment received", models.ActorPaymentService, map[string]interface{}{
+			"confirmed_at": time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return repo.EnqueueOutboxMessage(&outbox.Message{
+			Exchange: messaging.BookingConfirmedExchange,
+			Payload:  string(payload),
+		})
+	})
+	// A booking that is no longer pending is not confirmed
+	confirmed := err == nil
+	if err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+		return err
 	}
+	saga := s.sagaForBooking(booking.ID)
+	if !confirmed {
+		current, err := s.repo.GetBookingByID(bookingID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Not confirming booking %d with status %s\n", current.ID, current.Status)
+		if current.Status == models.BookingStatusConfirmed {
+			return nil
+		}
 
-	if err := messaging.PublishBookingConfirmed(event); err != nil {
-		fmt.Printf("Failed to publish booking confirmed event: %v\n", err)
+		// A late payment for a booking that was already cancelled: compensate
+		// with a refund. Bookings without a saga are left to the payment
+		// service's reconciler
+		if saga != nil {
+			s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusSucceeded, "", saga.Status)
+			s.recordSagaStep(saga, models.SagaStepConfirmBooking, models.SagaStepStatusFailed, fmt.Sprintf("Booking is %s", current.Status), models.SagaStatusCompensating)
+			s.requestSagaRefund(saga, current)
+		}
+		return nil
 	}
 
+	s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusSucceeded, "", models.SagaStatusRunning)
+	s.recordSagaStep(saga, models.SagaStepConfirmBooking, models.SagaStepStatusSucceeded, "", models.SagaStatusRunning)
+	s.recordSagaStep(saga, models.SagaStepIssueTicket, models.SagaStepStatusStarted, "Awaiting ticket", models.SagaStatusRunning)
+
 	// Audit Log
 	messaging.PublishAuditLog(booking.UserID, "CONFIRM_BOOKING", fmt.Sprintf("Confirmed booking %d", booking.ID))
 
@@ -234,6 +494,108 @@ func (s *bookingService) GetSales(eventID uint) ([]models.Booking, error) {
 	return s.repo.GetBookingsByEventID(eventID)
 }
 
+// GetTierSales reports, for every tier of an event, its capacity and
+// availability from the Event Service alongside the tickets sold, held and
+// earned from this service's bookings. Bookings made before per-seat pricing
+// have no items and are not counted. Other organizers' events are refused
+// with ErrEventNotOwned.
+func (s *bookingService) GetTierSales(eventID, organizerID uint) ([]models.TierSales, error) {
+	event, err := fetchEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.OrganizerID != organizerID {
+		return nil, models.ErrEventNotOwned
+	}
+	counts, err := s.repo.GetTierSales(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCode := make(map[string]models.TierSales, len(counts))
+	for _, count := range counts {
+		byCode[count.Code] = count
+	}
+
+	report := make([]models.TierSales, 0, len(event.Tiers))
+	for _, tier := range event.Tiers {
+		line := byCode[tier.Code]
+		delete(byCode, tier.Code)
+		line.Code = tier.Code
+		line.Name = tier.Name
+		line.Price = tier.Price
+		line.Capacity = tier.Capacity
+		line.Available = tier.Available
+		line.Revenue = roundAmount(line.Revenue)
+		report = append(report, line)
+	}
+	// Classes the event no longer has, e.g. from before its tiers were migrated
+	for _, count := range counts {
+		if line, ok := byCode[count.Code]; ok {
+			line.Name = line.Code
+			line.Revenue = roundAmount(line.Revenue)
+			report = append(report, line)
+		}
+	}
+	return report, nil
+}
+
+// GetTicketInventory counts the tickets held and sold per event and ticket
+// class. Bookings made before per-seat pricing have no items; their class is
+// derived from their seat IDs.
+func (s *bookingService) GetTicketInventory(eventID uint) ([]models.TicketInventory, error) {
+	bookings, err := s.repo.GetActiveBookings(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	type inventoryKey struct {
+		eventID     uint
+		ticketClass string
+	}
+	index := make(map[inventoryKey]int)
+	inventory := []models.TicketInventory{}
+	add := func(booking *models.Booking, ticketClass, seatID string) {
+		key := inventoryKey{booking.EventID, ticketClass}
+		i, ok := index[key]
+		if !ok {
+			i = len(inventory)
+			index[key] = i
+			inventory = append(inventory, models.TicketInventory{EventID: booking.EventID, TicketClass: ticketClass, PendingSeats: []string{}})
+		}
+		if booking.Status == models.BookingStatusConfirmed {
+			inventory[i].Confirmed++
+			return
+		}
+		inventory[i].Pending++
+		if seatID != "" {
+			inventory[i].PendingSeats = append(inventory[i].PendingSeats, seatID)
+		}
+	}
+
+	for i := range bookings {
+		booking := &bookings[i]
+		if len(booking.Items) > 0 {
+			for _, item := range booking.Items {
+				add(booking, item.TicketClass, item.SeatID)
+			}
+			continue
+		}
+
+		var seatList []struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal([]byte(booking.Seats), &seatList)
+		for _, seat := range seatList {
+			add(booking, ticketClassForSeat(seat.ID), seat.ID)
+		}
+		for n := len(seatList); n < booking.SeatCount; n++ {
+			add(booking, "normal", "")
+		}
+	}
+	return inventory, nil
+}
+
 func (s *bookingService) GetUserBookings(userID uint) ([]models.Booking, error) {
 	return s.repo.GetBookingsByUserID(userID)
 }
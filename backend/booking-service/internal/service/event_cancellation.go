@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+)
+
+// CancelEventBookings winds down the bookings of a cancelled event. Every
+// booking is cancelled and announced on booking_cancelled, so its holder is
+// emailed, in one transaction with a single event_refund_requested message
+// listing the confirmed bookings, which the payment service refunds in full
+// as one refund job. Redeliveries find no bookings left to cancel.
+func (s *bookingService) CancelEventBookings(eventID, organizerID uint, reason string) error {
+	bookings, err := s.repo.GetActiveBookings(eventID)
+	if err != nil {
+		return err
+	}
+	if len(bookings) == 0 {
+		return nil
+	}
+
+	if reason == "" {
+		reason = "Cancelled by the organizer"
+	}
+	reason = "Event cancelled: " + reason
+
+	var cancelled []models.Booking
+	err = s.repo.Transaction(func(repo repository.BookingRepository) error {
+		cancelled = nil
+		refunds := events.EventRefundRequested{EventID: eventID, Reason: reason}
+		for _, booking := range bookings {
+			from, err := repo.TransitionBooking(booking.ID, models.BookingStatusCancelled, reason, models.ActorEventService, map[string]interface{}{
+				"cancelled_at":        time.Now(),
+				"cancellation_reason": reason,
+			})
+			// Bookings that expired or were cancelled in the meantime are left alone
+			if errors.Is(err, models.ErrInvalidTransition) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			// The booking may have been confirmed since it was listed, so
+			// the refund follows the status it had under the row lock
+			booking.Status = from
+			var refundAmount float64
+			if from == models.BookingStatusConfirmed {
+				refundAmount = booking.TotalAmount
+				if err := repo.SetCancellationRefund(booking.ID, refundAmount); err != nil {
+					return err
+				}
+			}
+
+			payload, err := events.Marshal(events.TypeBookingCancelled, messaging.Producer, events.BookingCorrelationID(booking.ID), events.BookingCancelled{
+				BookingID:      booking.ID,
+				UserID:         booking.UserID,
+				EventID:        booking.EventID,
+				SeatCount:      booking.SeatCount,
+				RefundAmount:   refundAmount,
+				Reason:         reason,
+				EventCancelled: true,
+			})
+			if err != nil {
+				return err
+			}
+			if err := repo.EnqueueOutboxMessage(&outbox.Message{
+				RoutingKey: messaging.BookingCancelledQueue,
+				Payload:    string(payload),
+			}); err != nil {
+				return err
+			}
+
+			cancelled = append(cancelled, booking)
+			if refundAmount > 0 {
+				refunds.Bookings = append(refunds.Bookings, events.BookingRefund{
+					BookingID: booking.ID,
+					UserID:    booking.UserID,
+					Amount:    refundAmount,
+				})
+			}
+		}
+
+		if len(refunds.Bookings) == 0 {
+			return nil
+		}
+		payload, err := events.Marshal(events.TypeEventRefundRequested, messaging.Producer, events.EventCorrelationID(eventID), refunds)
+		if err != nil {
+			return err
+		}
+		return repo.EnqueueOutboxMessage(&outbox.Message{
+			RoutingKey: messaging.EventRefundRequestedQueue,
+			Payload:    string(payload),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bookings that were pending when cancelled are still in their saga,
+	// which is compensated. The seats of confirmed bookings stay sold, as the
+	// event will not take place
+	for i := range cancelled {
+		booking := &cancelled[i]
+		if booking.Status != models.BookingStatusPending {
+			continue
+		}
+		saga := s.sagaForBooking(booking.ID)
+		s.recordSagaStep(saga, models.SagaStepPayment, models.SagaStepStatusFailed, reason, models.SagaStatusCompensating)
+		s.compensateSeats(saga, booking)
+	}
+
+	fmt.Printf("Cancelled %d bookings of cancelled event %d\n", len(cancelled), eventID)
+	messaging.PublishAuditLog(organizerID, "CANCEL_EVENT_BOOKINGS", fmt.Sprintf("Cancelled %d bookings of event %d", len(cancelled), eventID))
+	return nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
+)
+
+// cancellationRepository lists bookings as they were read before the
+// transaction, while TransitionBooking sees their current status.
+type cancellationRepository struct {
+	repository.BookingRepository
+	listed  []models.Booking
+	current map[uint]models.BookingStatus
+	refunds map[uint]float64
+	outbox  []outbox.Message
+}
+
+func (r *cancellationRepository) GetActiveBookings(eventID uint) ([]models.Booking, error) {
+	return r.listed, nil
+}
+
+func (r *cancellationRepository) Transaction(fn func(repo repository.BookingRepository) error) error {
+	return fn(r)
+}
+
+func (r *cancellationRepository) TransitionBooking(id uint, to models.BookingStatus, reason, actor string, updates map[string]interface{}) (models.BookingStatus, error) {
+	from := r.current[id]
+	if !models.CanTransition(from, to) {
+		return from, &models.TransitionError{BookingID: id, From: from, To: to}
+	}
+	r.current[id] = to
+	return from, nil
+}
+
+func (r *cancellationRepository) SetCancellationRefund(id uint, refundAmount float64) error {
+	r.refunds[id] = refundAmount
+	return nil
+}
+
+func (r *cancellationRepository) EnqueueOutboxMessage(message *outbox.Message) error {
+	r.outbox = append(r.outbox, *message)
+	return nil
+}
+
+// noSagas finds no saga for any booking.
+type noSagas struct {
+	repository.SagaRepository
+}
+
+func (noSagas) GetSagaByBookingID(bookingID uint) (*models.Saga, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestCancelEventBookingsUsesLockedStatus(t *testing.T) {
+	var unlocks atomic.Int32
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unlocks.Add(1)
+	}))
+	defer eventService.Close()
+	t.Setenv("EVENT_SERVICE_URL", eventService.URL)
+
+	tests := []struct {
+		name        string
+		listed      models.BookingStatus
+		current     models.BookingStatus
+		wantRefund  float64
+		wantUnlocks int32
+	}{
+		{"confirmed since listed", models.BookingStatusPending, models.BookingStatusConfirmed, 120, 0},
+		{"still pending", models.BookingStatusPending, models.BookingStatusPending, 0, 1},
+		{"confirmed", models.BookingStatusConfirmed, models.BookingStatusConfirmed, 120, 0},
+		{"expired since listed", models.BookingStatusPending, models.BookingStatusExpired, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unlocks.Store(0)
+			booking := models.Booking{UserID: 3, EventID: 9, SeatCount: 2, TotalAmount: 120, Status: tt.listed}
+			booking.ID = 1
+			repo := &cancellationRepository{
+				listed:  []models.Booking{booking},
+				current: map[uint]models.BookingStatus{1: tt.current},
+				refunds: map[uint]float64{},
+			}
+
+			if err := NewBookingService(repo, noSagas{}).CancelEventBookings(9, 5, "Venue closed"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := repo.refunds[1]; got != tt.wantRefund {
+				t.Errorf("refund amount %v, want %v", got, tt.wantRefund)
+			}
+			var requested float64
+			for _, message := range repo.outbox {
+				if message.RoutingKey != messaging.EventRefundRequestedQueue {
+					continue
+				}
+				var refunds events.EventRefundRequested
+				if _, err := events.Unmarshal([]byte(message.Payload), events.TypeEventRefundRequested, &refunds); err != nil {
+					t.Fatal(err)
+				}
+				for _, refund := range refunds.Bookings {
+					requested += refund.Amount
+				}
+			}
+			if requested != tt.wantRefund {
+				t.Errorf("refund of %v requested, want %v", requested, tt.wantRefund)
+			}
+			if got := unlocks.Load(); got != tt.wantUnlocks {
+				t.Errorf("%d seat unlocks, want %d", got, tt.wantUnlocks)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+)
+
+// eventDetails is the subset of the Event Service's event payload the
+// booking service needs for pricing, sale windows, purchase limits and
+// cancellation.
+type eventDetails struct {
+	ID          uint          `json:"ID"`
+	OrganizerID uint          `json:"organizer_id"`
+	Date        time.Time     `json:"date"`
+	Status      string        `json:"status"`
+	Tiers       []tierDetails `json:"tiers"`
+
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+
+	// Tickets one user may hold for the event; 0 means no limit
+	MaxTicketsPerUser int `json:"max_tickets_per_user"`
+
+	CancellationDeadlineHours int     `json:"cancellation_deadline_hours"`
+	RefundPercentage          float64 `json:"refund_percentage"`
+}
+
+// tierDetails is a ticket tier of an event; Code is the ticket class used in
+// seat locks and booking items.
+type tierDetails struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Capacity  int     `json:"capacity"`
+	Available int     `json:"available"`
+
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+
+	MaxPerUser int `json:"max_per_user"` // 0 means no limit
+}
+
+func getEventServiceURL() string {
+	eventServiceURL := os.Getenv("EVENT_SERVICE_URL")
+	if eventServiceURL == "" {
+		eventServiceURL = "http://localhost:3003"
+	}
+	return eventServiceURL
+}
+
+// fetchEvent fetches an event as an internal service, which also finds
+// events that are not listed.
+func fetchEvent(eventID uint) (*eventDetails, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/events/%d", getEventServiceURL(), eventID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_SERVICE_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, models.ErrEventNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch event: status %d", resp.StatusCode)
+	}
+
+	var event eventDetails
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// seatDetails is a seat resolved by the Event Service against the event's
+// venue layout.
+type seatDetails struct {
+	ID          string  `json:"id"`
+	TicketClass string  `json:"ticket_class"`
+	Price       float64 `json:"price"`
+}
+
+// resolveSeats looks up the ticket class and price of each seat, in order.
+// It fails with ErrUnknownSeat if a seat is not part of the event's layout.
+func resolveSeats(eventID uint, seatIDs []string) ([]seatDetails, error) {
+	body, _ := json.Marshal(map[string]interface{}{"seat_ids": seatIDs})
+
+	resp, err := http.Post(fmt.Sprintf("%s/api/events/%d/seats/resolve", getEventServiceURL(), eventID), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, models.ErrEventNotFound
+	case http.StatusBadRequest:
+		return nil, models.ErrUnknownSeat
+	default:
+		return nil, fmt.Errorf("failed to resolve seats: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Seats []seatDetails `json:"seats"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Seats, nil
+}
+
+// newHoldToken returns the token a booking's seat locks are held under.
+func newHoldToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// internalRequest builds a request to the Event Service authenticated with
+// the internal service token.
+func internalRequest(method, url string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_SERVICE_TOKEN"))
+	return req, nil
+}
+
+// postSeatAction calls one of the Event Service's seat inventory endpoints:
+// "lock", "unlock" (pending holds) or "release" (confirmed seats). Locks
+// are taken for userID under holdToken, which unlocking them requires;
+// bookings created before hold tokens existed have none. Locks of events
+// with a waiting room carry the user's admissionToken.
+func postSeatAction(action string, eventID, userID uint, holdToken, admissionToken string, count int, ticketClass string, seatIDs []string) error {
+	reqBody := map[string]interface{}{
+		"count":        count,
+		"ticket_class": ticketClass,
+		"seat_ids":     seatIDs,
+		"user_id":      userID,
+		"hold_token":   holdToken,
+	}
+	if admissionToken != "" {
+		reqBody["admission_token"] = admissionToken
+	}
+	req, err := internalRequest(http.MethodPost, fmt.Sprintf("%s/api/events/%d/%s", getEventServiceURL(), eventID, action), reqBody)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Locks are also refused to users the waiting room has not admitted
+	if resp.StatusCode == http.StatusBadRequest || (action == "lock" && resp.StatusCode == http.StatusForbidden) {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &models.SeatsRejectedError{Reason: body.Error}
+	}
+	// The hold lapsed long ago; the inventory reconciler returns its tickets
+	if action == "unlock" && holdToken != "" && resp.StatusCode == http.StatusNotFound {
+		fmt.Printf("Seat hold for event %d no longer exists, nothing to unlock\n", eventID)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s seats: status %d", action, resp.StatusCode)
+	}
+	return nil
+}
+
+func lockSeats(eventID, userID uint, holdToken, admissionToken string, count int, ticketClass string, seatIDs []string) error {
+	if err := postSeatAction("lock", eventID, userID, holdToken, admissionToken, count, ticketClass, seatIDs); err != nil {
+		if errors.Is(err, models.ErrSeatsRejected) {
+			return err
+		}
+		return errors.New("failed to lock seats or not enough seats")
+	}
+	return nil
+}
+
+func unlockSeats(eventID, userID uint, holdToken string, count int, ticketClass string, seatIDs []string) error {
+	return postSeatAction("unlock", eventID, userID, holdToken, "", count, ticketClass, seatIDs)
+}
+
+// attachHoldBooking records the booking a seat hold was taken for, so the
+// Event Service can tell who holds a seat.
+func attachHoldBooking(eventID uint, holdToken string, bookingID uint) error {
+	req, err := internalRequest(http.MethodPut, fmt.Sprintf("%s/api/events/%d/holds/%s", getEventServiceURL(), eventID, holdToken), map[string]interface{}{
+		"booking_id": bookingID,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to attach booking to seat hold: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// unlockBookingSeats releases the seats held by a pending booking.
+func unlockBookingSeats(booking *models.Booking) error {
+	return returnBookingSeats("unlock", booking)
+}
+
+// releaseBookingSeats returns the seats of a confirmed booking to the inventory.
+func releaseBookingSeats(booking *models.Booking) error {
+	return returnBookingSeats("release", booking)
+}
+
+// returnBookingSeats applies action to every seat of a booking, per ticket
+// class. Bookings created before per-seat pricing have no items and fall back
+// to the raw seat list as a single class.
+func returnBookingSeats(action string, booking *models.Booking) error {
+	// Confirmed seats are no longer held
+	holdToken := booking.HoldToken
+	if action == "release" {
+		holdToken = ""
+	}
+
+	if len(booking.Items) == 0 {
+		var seatList []struct {
+			ID string `json:"id"`
+		}
+		var seatIDs []string
+		if err := json.Unmarshal([]byte(booking.Seats), &seatList); err == nil {
+			for _, seat := range seatList {
+				seatIDs = append(seatIDs, seat.ID)
+			}
+		}
+		ticketClass := "normal"
+		if len(seatIDs) > 0 {
+			ticketClass = ticketClassForSeat(seatIDs[0])
+		}
+		return postSeatAction(action, booking.EventID, booking.UserID, holdToken, "", booking.SeatCount, ticketClass, seatIDs)
+	}
+
+	classes, grouped := seatsByClass(booking.Items)
+	counts := countByClass(booking.Items)
+	var firstErr error
+	for _, class := range classes {
+		if err := postSeatAction(action, booking.EventID, booking.UserID, holdToken, "", counts[class], class, grouped[class]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+)
+
+// tier returns the event's tier with the given code, or nil.
+func (e *eventDetails) tier(code string) *tierDetails {
+	for i := range e.Tiers {
+		if e.Tiers[i].Code == code {
+			return &e.Tiers[i]
+		}
+	}
+	return nil
+}
+
+// ticketClassForSeat derives the ticket class from a seat ID such as
+// "vip-1-4". Seats without a known prefix are normal seats. It is only used
+// for bookings made before seats were resolved against the venue layout.
+func ticketClassForSeat(seatID string) string {
+	if strings.HasPrefix(seatID, "vvip") {
+		return "vvip"
+	}
+	if strings.HasPrefix(seatID, "vip") {
+		return "vip"
+	}
+	return "normal"
+}
+
+// serviceFeeRate is the share of the ticket subtotal charged as a service
+// fee, matching the fee shown at checkout.
+func serviceFeeRate() float64 {
+	if rate, err := strconv.ParseFloat(os.Getenv("SERVICE_FEE_RATE"), 64); err == nil && rate >= 0 {
+		return rate
+	}
+	return 0.10
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// priceBooking prices every selected seat by the ticket class the Event
+// Service resolved for it and returns the items, the service fee and the
+// total. Bookings without explicit seats are priced as seatCount tickets of
+// the requested tier, or of the event's first tier if none is given.
+func priceBooking(event *eventDetails, seatCount int, ticketClass string, seats []seatDetails) ([]models.BookingItem, float64, float64, error) {
+	var items []models.BookingItem
+	if len(seats) == 0 {
+		if ticketClass == "" && len(event.Tiers) > 0 {
+			ticketClass = event.Tiers[0].Code
+		}
+		tier := event.tier(ticketClass)
+		if tier == nil {
+			return nil, 0, 0, models.ErrUnknownTier
+		}
+		for i := 0; i < seatCount; i++ {
+			items = append(items, models.BookingItem{TicketClass: tier.Code, Price: tier.Price})
+		}
+	} else {
+		for _, seat := range seats {
+			items = append(items, models.BookingItem{SeatID: seat.ID, TicketClass: seat.TicketClass, Price: seat.Price})
+		}
+	}
+
+	var subtotal float64
+	for _, item := range items {
+		subtotal += item.Price
+	}
+	serviceFee := roundAmount(subtotal * serviceFeeRate())
+	return items, serviceFee, roundAmount(subtotal + serviceFee), nil
+}
+
+// amountsMatch compares two currency amounts, allowing for the client
+// rounding the service fee differently.
+func amountsMatch(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+// seatsByClass groups booking items by ticket class, preserving the order in
+// which classes first appear.
+func seatsByClass(items []models.BookingItem) ([]string, map[string][]string) {
+	var classes []string
+	grouped := make(map[string][]string)
+	for _, item := range items {
+		if _, ok := grouped[item.TicketClass]; !ok {
+			classes = append(classes, item.TicketClass)
+			grouped[item.TicketClass] = []string{}
+		}
+		if item.SeatID != "" {
+			grouped[item.TicketClass] = append(grouped[item.TicketClass], item.SeatID)
+		}
+	}
+	return classes, grouped
+}
+
+func countByClass(items []models.BookingItem) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[item.TicketClass]++
+	}
+	return counts
+}
+
+// checkSalesWindow rejects a booking before any seat is locked unless the
+// event is on sale and the event's and each booked tier's sale windows are
+// open. The Event Service checks the same when locking.
+func checkSalesWindow(event *eventDetails, items []models.BookingItem, now time.Time) error {
+	// Events from before the lifecycle have no status
+	if event.Status != "" && event.Status != "on_sale" {
+		return models.ErrEventNotOnSale
+	}
+	if event.SalesStart != nil && now.Before(*event.SalesStart) {
+		return models.ErrSalesNotStarted
+	}
+	closes := event.Date
+	if event.SalesEnd != nil && event.SalesEnd.Before(closes) {
+		closes = *event.SalesEnd
+	}
+	if !now.Before(closes) {
+		return models.ErrSalesEnded
+	}
+
+	for _, item := range items {
+		tier := event.tier(item.TicketClass)
+		if tier == nil {
+			continue
+		}
+		if (tier.SalesStart != nil && now.Before(*tier.SalesStart)) || (tier.SalesEnd != nil && !now.Before(*tier.SalesEnd)) {
+			return models.ErrTierNotOnSale
+		}
+	}
+	return nil
+}
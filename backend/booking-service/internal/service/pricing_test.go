@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+)
+
+func TestPriceBooking(t *testing.T) {
+	t.Setenv("SERVICE_FEE_RATE", "0.10")
+	event := &eventDetails{Tiers: []tierDetails{
+		{Code: "normal", Price: 100},
+		{Code: "vip", Price: 250.55},
+	}}
+
+	tests := []struct {
+		name        string
+		seatCount   int
+		ticketClass string
+		seats       []seatDetails
+		wantItems   int
+		wantFee     float64
+		wantTotal   float64
+		wantErr     error
+	}{
+		{"first tier by default", 2, "", nil, 2, 20, 220, nil},
+		{"requested tier", 1, "vip", nil, 1, 25.06, 275.61, nil},
+		{"unknown tier", 1, "backstage", nil, 0, 0, 0, models.ErrUnknownTier},
+		{"seats priced as resolved", 0, "normal", []seatDetails{
+			{ID: "A-1", TicketClass: "vip", Price: 250.55},
+			{ID: "A-2", TicketClass: "normal", Price: 100},
+		}, 2, 35.06, 385.61, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, fee, total, err := priceBooking(event, tt.seatCount, tt.ticketClass, tt.seats)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if len(items) != tt.wantItems || fee != tt.wantFee || total != tt.wantTotal {
+				t.Errorf("got %d items, fee %v, total %v; want %d, %v, %v", len(items), fee, total, tt.wantItems, tt.wantFee, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestPriceBookingFeeRate(t *testing.T) {
+	event := &eventDetails{Tiers: []tierDetails{{Code: "normal", Price: 100}}}
+	tests := []struct {
+		rate      string
+		wantTotal float64
+	}{
+		{"", 110},
+		{"0", 100},
+		{"0.05", 105},
+		{"-1", 110},
+		{"abc", 110},
+	}
+	for _, tt := range tests {
+		t.Setenv("SERVICE_FEE_RATE", tt.rate)
+		if _, _, total, _ := priceBooking(event, 1, "", nil); total != tt.wantTotal {
+			t.Errorf("rate %q: total %v, want %v", tt.rate, total, tt.wantTotal)
+		}
+	}
+}
+
+func TestAmountsMatch(t *testing.T) {
+	tests := []struct {
+		a, b float64
+		want bool
+	}{
+		{275.61, 275.61, true},
+		{275.605, 275.61, true},
+		{275.59, 275.61, false},
+		{1, 275.61, false},
+	}
+	for _, tt := range tests {
+		if got := amountsMatch(tt.a, tt.b); got != tt.want {
+			t.Errorf("amountsMatch(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSalesWindow(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		t := now.Add(offset)
+		return &t
+	}
+	items := []models.BookingItem{{TicketClass: "vip"}, {TicketClass: "normal"}}
+
+	tests := []struct {
+		name  string
+		event eventDetails
+		want  error
+	}{
+		{"on sale", eventDetails{Status: "on_sale", Date: now.Add(time.Hour)}, nil},
+		{"no status", eventDetails{Date: now.Add(time.Hour)}, nil},
+		{"published", eventDetails{Status: "published", Date: now.Add(time.Hour)}, models.ErrEventNotOnSale},
+		{"sales not started", eventDetails{Status: "on_sale", Date: now.Add(time.Hour), SalesStart: at(time.Minute)}, models.ErrSalesNotStarted},
+		{"sales ended", eventDetails{Status: "on_sale", Date: now.Add(time.Hour), SalesEnd: at(0)}, models.ErrSalesEnded},
+		{"event started", eventDetails{Status: "on_sale", Date: now}, models.ErrSalesEnded},
+		{"booked tier not started", eventDetails{Status: "on_sale", Date: now.Add(time.Hour), Tiers: []tierDetails{
+			{Code: "normal"}, {Code: "vip", SalesStart: at(time.Minute)},
+		}}, models.ErrTierNotOnSale},
+		{"booked tier ended", eventDetails{Status: "on_sale", Date: now.Add(time.Hour), Tiers: []tierDetails{
+			{Code: "normal", SalesEnd: at(0)}, {Code: "vip"},
+		}}, models.ErrTierNotOnSale},
+		{"other tier closed", eventDetails{Status: "on_sale", Date: now.Add(time.Hour), Tiers: []tierDetails{
+			{Code: "normal"}, {Code: "vip"}, {Code: "backstage", SalesEnd: at(0)},
+		}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSalesWindow(&tt.event, items, now); !errors.Is(err, tt.want) {
+				t.Errorf("checkSalesWindow = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
+)
+
+// hasPurchaseLimits reports whether the event or a booked tier limits the
+// tickets one user may hold.
+func hasPurchaseLimits(event *eventDetails, items []models.BookingItem) bool {
+	if event.MaxTicketsPerUser > 0 {
+		return true
+	}
+	for _, item := range items {
+		if tier := event.tier(item.TicketClass); tier != nil && tier.MaxPerUser > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPurchaseLimits rejects a booking of items that would take a user
+// past the event's or a booked tier's per-user limit, counting the
+// tickets of the user's pending and confirmed bookings as repo sees them.
+func checkPurchaseLimits(repo repository.BookingRepository, userID uint, event *eventDetails, items []models.BookingItem) error {
+	total, held, err := repo.GetUserTicketCounts(userID, event.ID)
+	if err != nil {
+		return err
+	}
+
+	if event.MaxTicketsPerUser > 0 && total+len(items) > event.MaxTicketsPerUser {
+		return &models.PurchaseLimitError{Limit: event.MaxTicketsPerUser, Held: total}
+	}
+	classes, _ := seatsByClass(items)
+	counts := countByClass(items)
+	for _, class := range classes {
+		tier := event.tier(class)
+		if tier == nil || tier.MaxPerUser == 0 {
+			continue
+		}
+		if held[class]+counts[class] > tier.MaxPerUser {
+			return &models.PurchaseLimitError{Tier: tier.Name, Limit: tier.MaxPerUser, Held: held[class]}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/repository"
+)
+
+// heldTickets reports the tickets a user already holds for the event.
+type heldTickets struct {
+	repository.BookingRepository
+	total   int
+	byClass map[string]int
+}
+
+func (r *heldTickets) GetUserTicketCounts(userID, eventID uint) (int, map[string]int, error) {
+	return r.total, r.byClass, nil
+}
+
+func tickets(classes ...string) []models.BookingItem {
+	items := make([]models.BookingItem, len(classes))
+	for i, class := range classes {
+		items[i] = models.BookingItem{TicketClass: class}
+	}
+	return items
+}
+
+func TestCheckPurchaseLimits(t *testing.T) {
+	event := &eventDetails{MaxTicketsPerUser: 6, Tiers: []tierDetails{
+		{Code: "normal", Name: "Normal"},
+		{Code: "vip", Name: "VIP", MaxPerUser: 2},
+	}}
+
+	tests := []struct {
+		name  string
+		held  heldTickets
+		items []models.BookingItem
+		want  *models.PurchaseLimitError
+	}{
+		{"within limits", heldTickets{}, tickets("vip", "vip", "normal"), nil},
+		{"up to the event limit", heldTickets{total: 3}, tickets("normal", "normal", "normal"), nil},
+		{"past the event limit", heldTickets{total: 4}, tickets("normal", "normal", "normal"), &models.PurchaseLimitError{Limit: 6, Held: 4}},
+		{"past the tier limit", heldTickets{}, tickets("vip", "vip", "vip"), &models.PurchaseLimitError{Tier: "VIP", Limit: 2}},
+		{"tier tickets held", heldTickets{total: 1, byClass: map[string]int{"vip": 1}}, tickets("vip", "vip"), &models.PurchaseLimitError{Tier: "VIP", Limit: 2, Held: 1}},
+		{"other tier held", heldTickets{total: 2, byClass: map[string]int{"normal": 2}}, tickets("vip", "vip"), nil},
+		{"unknown tier", heldTickets{}, tickets("backstage"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPurchaseLimits(&tt.held, 3, event, tt.items)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			var limitErr *models.PurchaseLimitError
+			if !errors.As(err, &limitErr) || *limitErr != *tt.want {
+				t.Errorf("error %v, want %+v", err, tt.want)
+			}
+			if !errors.Is(err, models.ErrPurchaseLimit) {
+				t.Error("error does not match ErrPurchaseLimit")
+			}
+		})
+	}
+}
+
+func TestHasPurchaseLimits(t *testing.T) {
+	tiers := []tierDetails{{Code: "normal"}, {Code: "vip", MaxPerUser: 2}}
+	tests := []struct {
+		name  string
+		event eventDetails
+		items []models.BookingItem
+		want  bool
+	}{
+		{"no limits", eventDetails{Tiers: tiers}, tickets("normal"), false},
+		{"event limit", eventDetails{MaxTicketsPerUser: 4, Tiers: tiers}, tickets("normal"), true},
+		{"booked tier limit", eventDetails{Tiers: tiers}, tickets("normal", "vip"), true},
+	}
+	for _, tt := range tests {
+		if got := hasPurchaseLimits(&tt.event, tt.items); got != tt.want {
+			t.Errorf("%s: hasPurchaseLimits = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPurchaseLimitErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  models.PurchaseLimitError
+		want string
+	}{
+		{models.PurchaseLimitError{Limit: 4}, "You can buy at most 4 tickets for this event"},
+		{models.PurchaseLimitError{Tier: "VIP", Limit: 2, Held: 1}, "You can buy at most 2 tickets for the VIP tickets of this event and already have 1 in your bookings"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("message %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// raceRepository reports no tickets before the purchase lock is taken and
+// the tickets of a concurrent booking once it is, as a second booking of
+// the same user sees after the first one commits.
+type raceRepository struct {
+	repository.BookingRepository
+	locked  bool
+	calls   []string
+	created bool
+}
+
+func (r *raceRepository) Transaction(fn func(repo repository.BookingRepository) error) error {
+	return fn(r)
+}
+
+func (r *raceRepository) LockUserPurchases(userID, eventID uint) error {
+	r.calls = append(r.calls, "lock")
+	r.locked = true
+	return nil
+}
+
+func (r *raceRepository) GetUserTicketCounts(userID, eventID uint) (int, map[string]int, error) {
+	r.calls = append(r.calls, "count")
+	if r.locked {
+		return 3, map[string]int{"normal": 3}, nil
+	}
+	return 0, nil, nil
+}
+
+func (r *raceRepository) CreateBooking(booking *models.Booking) error {
+	r.created = true
+	return nil
+}
+
+// recordedSagas keeps the statuses sagas move through.
+type recordedSagas struct {
+	repository.SagaRepository
+	mu       sync.Mutex
+	statuses []models.SagaStatus
+}
+
+func (s *recordedSagas) CreateSaga(saga *models.Saga) error {
+	saga.ID = 1
+	return nil
+}
+
+func (s *recordedSagas) RecordStep(saga *models.Saga, step *models.SagaStep, status models.SagaStatus, failureReason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+func TestCreateBookingRechecksLimitsUnderLock(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	eventService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"ID":                   9,
+				"date":                 time.Now().Add(24 * time.Hour),
+				"status":               "on_sale",
+				"max_tickets_per_user": 4,
+				"tiers":                []map[string]interface{}{{"code": "normal", "name": "Normal", "price": 100}},
+			})
+			return
+		}
+		mu.Lock()
+		actions = append(actions, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		mu.Unlock()
+	}))
+	defer eventService.Close()
+	t.Setenv("EVENT_SERVICE_URL", eventService.URL)
+
+	repo := &raceRepository{}
+	sagas := &recordedSagas{}
+	_, err := NewBookingService(repo, sagas).CreateBooking(3, 9, 2, "normal", 0, "", "")
+
+	var limitErr *models.PurchaseLimitError
+	if !errors.As(err, &limitErr) || limitErr.Held != 3 {
+		t.Fatalf("error %v, want the limit error counting the concurrent booking", err)
+	}
+	if want := "count,lock,count"; strings.Join(repo.calls, ",") != want {
+		t.Errorf("calls %v, want %s", repo.calls, want)
+	}
+	if repo.created {
+		t.Error("booking created past the limit")
+	}
+	if strings.Join(actions, ",") != "lock,unlock" {
+		t.Errorf("seat actions %v, want the lock to be released", actions)
+	}
+	if last := sagas.statuses[len(sagas.statuses)-1]; last != models.SagaStatusCompensated {
+		t.Errorf("saga ended %s, want %s", last, models.SagaStatusCompensated)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/booking-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
+)
+
+// A booking saga drives a purchase through its steps:
+//
+//	lock_seats → create_booking → payment → confirm_booking → issue_ticket
+//
+// CreateBooking runs the first two, the payment service's payment_success
+// message completes payment and triggers confirm_booking, and the
+// notification service's ticket_issued message completes the saga. When a
+// step fails, the steps already done are compensated: held seats are
+// unlocked (unlock_seats) and a payment for a booking that can no longer be
+// confirmed is refunded (refund_payment).
+//
+// Recording a step never fails the step itself; errors are logged and the
+// history may then have a gap.
+
+func (s *bookingService) startSaga(userID, eventID uint) (*models.Saga, error) {
+	saga := &models.Saga{
+		UserID:      userID,
+		EventID:     eventID,
+		Status:      models.SagaStatusRunning,
+		CurrentStep: models.SagaStepLockSeats,
+	}
+	if err := s.sagas.CreateSaga(saga); err != nil {
+		return nil, err
+	}
+	s.recordSagaStep(saga, models.SagaStepLockSeats, models.SagaStepStatusStarted, "", models.SagaStatusRunning)
+	return saga, nil
+}
+
+// sagaForBooking returns the booking's saga, or nil for bookings created
+// before sagas existed.
+func (s *bookingService) sagaForBooking(bookingID uint) *models.Saga {
+	saga, err := s.sagas.GetSagaByBookingID(bookingID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Printf("Failed to load saga for booking %d: %v\n", bookingID, err)
+		}
+		return nil
+	}
+	return saga
+}
+
+func (s *bookingService) recordSagaStep(saga *models.Saga, step models.SagaStepName, stepStatus models.SagaStepStatus, detail string, status models.SagaStatus) {
+	if saga == nil {
+		return
+	}
+
+	compensation := step == models.SagaStepUnlockSeats || step == models.SagaStepRefundPayment
+	failureReason := ""
+	if stepStatus == models.SagaStepStatusFailed && !compensation {
+		failureReason = detail
+	}
+
+	record := &models.SagaStep{
+		Step:         step,
+		Status:       stepStatus,
+		Compensation: compensation,
+		Detail:       detail,
+	}
+	if err := s.sagas.RecordStep(saga, record, status, failureReason); err != nil {
+		fmt.Printf("Failed to record saga %d step %s %s: %v\n", saga.ID, step, stepStatus, err)
+		return
+	}
+	saga.Status = status
+	saga.CurrentStep = step
+}
+
+// compensateSeats unlocks the seats held by a booking that will not be paid
+// for and closes its saga.
+func (s *bookingService) compensateSeats(saga *models.Saga, booking *models.Booking) {
+	if err := unlockBookingSeats(booking); err != nil {
+		fmt.Printf("Failed to unlock seats for booking %d: %v\n", booking.ID, err)
+		s.recordSagaStep(saga, models.SagaStepUnlockSeats, models.SagaStepStatusFailed, err.Error(), models.SagaStatusFailed)
+		return
+	}
+	s.recordSagaStep(saga, models.SagaStepUnlockSeats, models.SagaStepStatusSucceeded, "", models.SagaStatusCompensated)
+}
+
+// unlockClasses releases the holds taken for the given ticket classes when
+// a booking could not be created.
+func unlockClasses(eventID, userID uint, holdToken string, classes []string, counts map[string]int, grouped map[string][]string) error {
+	var firstErr error
+	for _, class := range classes {
+		if err := unlockSeats(eventID, userID, holdToken, counts[class], class, grouped[class]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// requestSagaRefund compensates a payment that arrived for a booking that
+// can no longer be confirmed by asking the payment service for a full refund.
+// The saga is compensated once payment_refunded comes back.
+func (s *bookingService) requestSagaRefund(saga *models.Saga, booking *models.Booking) {
+	reason := fmt.Sprintf("Booking %d was %s before the payment completed", booking.ID, booking.Status)
+
+	// Amount 0 refunds whatever was paid
+	payload, err := events.Marshal(events.TypeRefundRequested, messaging.Producer, events.BookingCorrelationID(booking.ID), events.RefundRequested{
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		Reason:    reason,
+	})
+	if err == nil {
+		err = s.repo.EnqueueOutboxMessage(&outbox.Message{
+			RoutingKey: messaging.RefundRequestedQueue,
+			Payload:    string(payload),
+		})
+	}
+	if err != nil {
+		fmt.Printf("Failed to request refund for booking %d: %v\n", booking.ID, err)
+		s.recordSagaStep(saga, models.SagaStepRefundPayment, models.SagaStepStatusFailed, err.Error(), models.SagaStatusFailed)
+		return
+	}
+
+	s.recordSagaStep(saga, models.SagaStepRefundPayment, models.SagaStepStatusStarted, reason, models.SagaStatusCompensating)
+	messaging.PublishAuditLog(booking.UserID, "REFUND_BOOKING", fmt.Sprintf("Requested refund for unconfirmable booking %d", booking.ID))
+}
+
+// TicketIssued completes the saga of a booking whose ticket has been sent.
+func (s *bookingService) TicketIssued(bookingID uint) error {
+	saga := s.sagaForBooking(bookingID)
+	if saga == nil || saga.Status != models.SagaStatusRunning {
+		return nil
+	}
+	s.recordSagaStep(saga, models.SagaStepIssueTicket, models.SagaStepStatusSucceeded, "", models.SagaStatusCompleted)
+	return nil
+}
+
+// GetBookingSaga returns the saga of a booking with its step history.
+func (s *bookingService) GetBookingSaga(bookingID, requesterID uint, isAdmin bool) (*models.Saga, error) {
+	booking, err := s.repo.GetBookingByID(bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBookingNotFound
+		}
+		return nil, err
+	}
+	if booking.UserID != requesterID && !isAdmin {
+		return nil, models.ErrForbidden
+	}
+
+	saga, err := s.sagas.GetSagaByBookingID(bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrSagaNotFound
+		}
+		return nil, err
+	}
+	return saga, nil
+}
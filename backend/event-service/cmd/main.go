@@ -10,6 +10,9 @@ import (
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/middleware"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/worker"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
@@ -24,14 +27,41 @@ import (
 func main() {
 	database.ConnectDB()
 	database.ConnectRedis()
-	messaging.ConnectRabbitMQ()
+	messaging.ConnectRabbitMQ(database.NewDedupStore())
 
 	eventRepo := repository.NewEventRepository()
-	eventService := service.NewEventService(eventRepo)
+	venueRepo := repository.NewVenueRepository()
+	eventService := service.NewEventService(eventRepo, venueRepo, repository.NewWaitingRoomRepository())
 	eventHandler := handlers.NewEventHandler(eventService)
+	venueService := service.NewVenueService(venueRepo)
+	venueHandler := handlers.NewVenueHandler(venueService)
+	inventoryService := service.NewInventoryService(eventRepo, venueRepo)
+
+	// Move events created before ticket tiers existed onto tiers, then give
+	// events created before seat layouts existed a generated layout
+	if err := eventService.MigrateLegacyTiers(); err != nil {
+		log.Printf("Failed to migrate legacy ticket classes: %v", err)
+	}
+	if err := eventService.EnsureSeatLayouts(); err != nil {
+		log.Printf("Failed to generate seat layouts: %v", err)
+	}
+
+	// Recreate seat counters lost with Redis, then keep them in line with
+	// Postgres and the Booking Service
+	if err := inventoryService.RebuildSeatInventory(); err != nil {
+		log.Printf("Failed to rebuild seat inventory: %v", err)
+	}
+	worker.StartInventoryReconciler(inventoryService)
 
 	// Start RabbitMQ Consumer
-	go messaging.StartConsumer(eventService)
+	messaging.StartConsumer(eventService)
+
+	// Publish lifecycle changes and move events through their lifecycle
+	outbox.StartRelay(outbox.NewStore(database.DB), messaging.Client)
+	worker.StartLifecycleWorker(eventService)
+
+	// Admit queued buyers of events with a waiting room
+	worker.StartWaitingRoomAdmitter(eventService)
 
 	r := gin.Default()
 
@@ -49,9 +79,28 @@ func main() {
 
 	// Public or Internal endpoints
 	api.GET("/events", eventHandler.GetEvents)
-	api.GET("/events/:id", eventHandler.GetEvent)
-	api.POST("/events/:id/lock", eventHandler.LockSeats)
-	api.POST("/events/:id/unlock", eventHandler.UnlockSeats)
+	// Unlisted events only for their organizer, admins and internal services
+	api.GET("/events/:id", middleware.OptionalAuthMiddleware(), eventHandler.GetEvent)
+	api.GET("/events/:id/seatmap", eventHandler.GetSeatMap)
+	api.POST("/events/:id/seats/resolve", eventHandler.ResolveSeats)
+	api.GET("/venues/:id", venueHandler.GetVenue)
+
+	// Waiting room positions; the queue token is the credential
+	api.GET("/events/:id/queue/:token", eventHandler.GetQueueTicket)
+	api.GET("/events/:id/queue/:token/stream", eventHandler.StreamQueueTicket)
+
+	// Seat locks are taken by the Booking Service only, which enforces the
+	// purchase limits
+	api.POST("/events/:id/lock", middleware.InternalAuthMiddleware(), eventHandler.LockSeats)
+
+	// Seat holds: users with their bearer token, or internal services
+	holds := api.Group("", middleware.InternalOrAuthMiddleware())
+	{
+		holds.POST("/events/:id/unlock", eventHandler.UnlockSeats)
+		holds.POST("/events/:id/release", eventHandler.ReleaseSeats)
+		holds.PUT("/events/:id/holds/:token", eventHandler.AttachHoldBooking)
+		holds.GET("/events/:id/seats/:seatId/holder", eventHandler.GetSeatHolder)
+	}
 
 	// Protected endpoints
 	api.Use(middleware.AuthMiddleware())
@@ -59,8 +108,17 @@ func main() {
 		api.POST("/events", eventHandler.CreateEvent)
 		api.GET("/events/my", eventHandler.GetMyEvents)
 		api.PUT("/events/:id", eventHandler.UpdateEvent)
+		api.POST("/events/:id/publish", eventHandler.PublishEvent)
+		api.POST("/events/:id/unpublish", eventHandler.UnpublishEvent)
+		api.POST("/events/:id/cancel", eventHandler.CancelEvent)
+		api.POST("/events/:id/queue", eventHandler.JoinWaitingRoom)
+		api.POST("/venues", venueHandler.CreateVenue)
+		api.GET("/venues", venueHandler.GetMyVenues)
 	}
 
+	// Dead-lettered messages (Admin only)
+	shared.NewDeadLetterHandler(messaging.Client).RegisterRoutes(api.Group("/events/admin"))
+
 	log.Println("Event Service running on port 3003")
 	r.Run(":3003")
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -32,7 +33,18 @@ func ConnectDB() {
 	}
 
 	log.Println("Connected to Database")
-	DB.AutoMigrate(&models.Event{})
+
+	// Events created before the lifecycle existed were bookable straight away
+	hadStatus := DB.Migrator().HasTable(&models.Event{}) && DB.Migrator().HasColumn(&models.Event{}, "status")
+	DB.AutoMigrate(&models.Event{}, &models.TicketTier{}, &models.Venue{}, &models.Section{}, &models.SeatRow{}, &models.Seat{}, &models.SoldSeat{}, &models.BookingSale{}, &outbox.Message{})
+	if !hadStatus {
+		DB.Model(&models.Event{}).Where("1 = 1").Update("status", models.EventStatusOnSale)
+	}
+
+	// Status changes queued before they were optional are returned by the broker; let them through
+	DB.Model(&outbox.Message{}).
+		Where("status = ? AND exchange = ?", outbox.StatusPending, "event_status_changed").
+		Update("optional", true)
 }
 
 func ConnectRedis() {
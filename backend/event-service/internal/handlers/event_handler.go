@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type EventHandler struct {
@@ -24,6 +26,29 @@ type CreateEventRequest struct {
 	Date        time.Time `json:"date" binding:"required"`
 	Location    string    `json:"location" binding:"required"`
 
+	// Optional sale window of the whole event; sales close when the event
+	// starts at the latest
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+
+	// Optional waiting room for high-demand on-sales; a zero rate or window
+	// uses the service's defaults
+	WaitingRoom           bool `json:"waiting_room"`
+	AdmissionRate         int  `json:"admission_rate" binding:"min=0"` // users admitted per minute
+	PurchaseWindowMinutes int  `json:"purchase_window_minutes" binding:"min=0"`
+
+	// Optional limit of tickets one user may hold for the event across their
+	// bookings; tiers take their own max_per_user
+	MaxTicketsPerUser int `json:"max_tickets_per_user" binding:"min=0"`
+
+	// Optional venue; its layout sets the capacity of the seated tiers.
+	// Without a venue a layout is generated with one section per tier.
+	VenueID *uint `json:"venue_id"`
+
+	// Ticket tiers. Requests without tiers use the legacy price_* and
+	// seats_* fields, which become the tiers "normal", "vip" and "vvip".
+	Tiers []CreateTierRequest `json:"tiers" binding:"omitempty,dive"`
+
 	PriceNormal float64 `json:"price_normal"`
 	PriceVIP    float64 `json:"price_vip"`
 	PriceVVIP   float64 `json:"price_vvip"`
@@ -31,6 +56,22 @@ type CreateEventRequest struct {
 	SeatsNormal int `json:"seats_normal"`
 	SeatsVIP    int `json:"seats_vip"`
 	SeatsVVIP   int `json:"seats_vvip"`
+
+	// Optional cancellation policy; defaults to 24 hours and a full refund
+	CancellationDeadlineHours *int     `json:"cancellation_deadline_hours"`
+	RefundPercentage          *float64 `json:"refund_percentage"`
+}
+
+type CreateTierRequest struct {
+	Code        string     `json:"code"` // defaults to the name, e.g. "early-bird"
+	Name        string     `json:"name" binding:"required"`
+	Price       float64    `json:"price" binding:"min=0"`
+	Capacity    int        `json:"capacity" binding:"min=0"`
+	SalesStart  *time.Time `json:"sales_start"`
+	SalesEnd    *time.Time `json:"sales_end"`
+	MinPerOrder int        `json:"min_per_order" binding:"min=0"`
+	MaxPerOrder int        `json:"max_per_order" binding:"min=0"` // 0 means no limit
+	MaxPerUser  int        `json:"max_per_user" binding:"min=0"`  // across the user's bookings; 0 means no limit
 }
 
 // @Summary Create a new event
@@ -60,7 +101,7 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 	}
 
 	totalSeats := req.SeatsNormal + req.SeatsVIP + req.SeatsVVIP
-	if totalSeats == 0 {
+	if totalSeats == 0 && len(req.Tiers) == 0 && req.VenueID == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Total seats must be greater than 0"})
 		return
 	}
@@ -73,6 +114,10 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		TotalSeats:      totalSeats,
 		AvailableSeats:  totalSeats,
 		OrganizerID:     uint(organizerID.(float64)), // JWT claims are often float64
+		VenueID:         req.VenueID,
+		SalesStart:      req.SalesStart,
+		SalesEnd:        req.SalesEnd,
+		WaitingRoom:     req.WaitingRoom,
 		PriceNormal:     req.PriceNormal,
 		PriceVIP:        req.PriceVIP,
 		PriceVVIP:       req.PriceVVIP,
@@ -82,10 +127,51 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		AvailableNormal: req.SeatsNormal,
 		AvailableVIP:    req.SeatsVIP,
 		AvailableVVIP:   req.SeatsVVIP,
+
+		CancellationDeadlineHours: 24,
+		RefundPercentage:          100,
+
+		AdmissionRate:         req.AdmissionRate,
+		PurchaseWindowMinutes: req.PurchaseWindowMinutes,
+		MaxTicketsPerUser:     req.MaxTicketsPerUser,
+	}
+	for _, tierReq := range req.Tiers {
+		event.Tiers = append(event.Tiers, models.TicketTier{
+			Code:        tierReq.Code,
+			Name:        tierReq.Name,
+			Price:       tierReq.Price,
+			Capacity:    tierReq.Capacity,
+			SalesStart:  tierReq.SalesStart,
+			SalesEnd:    tierReq.SalesEnd,
+			MinPerOrder: tierReq.MinPerOrder,
+			MaxPerOrder: tierReq.MaxPerOrder,
+			MaxPerUser:  tierReq.MaxPerUser,
+		})
+	}
+	if len(event.Tiers) == 0 {
+		event.Tiers = event.LegacyTiers()
+	}
+	if req.CancellationDeadlineHours != nil {
+		event.CancellationDeadlineHours = *req.CancellationDeadlineHours
+	}
+	if req.RefundPercentage != nil {
+		event.RefundPercentage = *req.RefundPercentage
+	}
+	if event.CancellationDeadlineHours < 0 || event.RefundPercentage < 0 || event.RefundPercentage > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrInvalidPolicy.Error()})
+		return
 	}
 
 	if err := h.service.CreateEvent(event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+		if err == models.ErrVenueNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else if err == models.ErrInvalidTier || err == models.ErrTierMismatch || err == models.ErrInvalidSalesWindow || err == models.ErrInvalidPurchaseLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+		}
 		return
 	}
 
@@ -93,7 +179,7 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 }
 
 // @Summary Get all events
-// @Description Get list of all events
+// @Description Get the published, on-sale, sold-out and sales-closed events
 // @Tags events
 // @Produce json
 // @Success 200 {array} models.Event
@@ -110,9 +196,10 @@ func (h *EventHandler) GetEvents(c *gin.Context) {
 }
 
 // @Summary Get event by ID
-// @Description Get details of a specific event
+// @Description Get details of a specific event. Events that are not listed (drafts, cancelled and completed events) are only found by their organizer, admins and internal services.
 // @Tags events
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Event ID"
 // @Success 200 {object} models.Event
 // @Failure 404 {object} map[string]interface{}
@@ -131,6 +218,16 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 		return
 	}
 
+	// Unlisted events look like missing ones to everyone else
+	if !event.Status.Listed() {
+		userID, internal := requester(c)
+		role, _ := c.Get("role")
+		if !internal && role != "admin" && (userID == 0 || userID != event.OrganizerID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, event)
 }
 
@@ -200,7 +297,8 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 	if err != nil {
 		if err == models.ErrUnauthorized {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		} else if err == models.ErrInvalidSeatCount {
+		} else if err == models.ErrInvalidPolicy || err == models.ErrSeatLayoutFixed || err == models.ErrEventClosed || err == models.ErrInvalidSalesWindow ||
+			err == models.ErrInvalidWaitingRoom || err == models.ErrInvalidPurchaseLimit || err == models.ErrUnknownTier {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
@@ -215,8 +313,39 @@ type LockSeatsRequest struct {
 	Count       int      `json:"count" binding:"required,min=1"`
 	TicketClass string   `json:"ticket_class"` // "normal", "vip", "vvip"
 	SeatIDs     []string `json:"seat_ids"`
+	// HoldToken adds to an existing hold, e.g. to lock several tiers for one
+	// booking; a new hold is created without it.
+	HoldToken string `json:"hold_token"`
+	// UserID is the user the lock is taken for.
+	UserID uint `json:"user_id"`
+	// AdmissionToken is the queue token of a user admitted by the event's
+	// waiting room, if it has one.
+	AdmissionToken string `json:"admission_token"`
+}
+
+// requester returns the user making a request, or true for a request from
+// an internal service.
+func requester(c *gin.Context) (uint, bool) {
+	if c.GetBool("internal") {
+		return 0, true
+	}
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(float64)
+	return uint(id), false
 }
 
+// @Summary Lock seats
+// @Description Hold tickets of one tier for user_id for 15 minutes while the event and tier sale windows are open. Events with a waiting room need the admission_token of an admitted queue ticket. Returns the hold token needed to unlock them. Internal services only: users lock seats by creating a booking, which checks their purchase limits.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param input body LockSeatsRequest true "Lock Input"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /events/{id}/lock [post]
 func (h *EventHandler) LockSeats(c *gin.Context) {
 	eventIDStr := c.Param("id")
 	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
@@ -231,14 +360,30 @@ func (h *EventHandler) LockSeats(c *gin.Context) {
 		return
 	}
 
+	userID := req.UserID
+	if userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
 	// Default to normal if not specified
 	if req.TicketClass == "" {
 		req.TicketClass = "normal"
 	}
 
-	success, err := h.service.LockSeats(uint(eventID), req.Count, req.TicketClass, req.SeatIDs)
+	holdToken, success, err := h.service.LockSeats(uint(eventID), userID, req.HoldToken, req.AdmissionToken, req.Count, req.TicketClass, req.SeatIDs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock seats"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else if err == models.ErrUnknownSeat || err == models.ErrSeatClassMismatch || err == models.ErrInvalidHoldToken || err == models.ErrEventNotOnSale ||
+			err == models.ErrUnknownTier || err == models.ErrTierNotOnSale || err == models.ErrTierOrderLimit ||
+			err == models.ErrSalesNotStarted || err == models.ErrSalesEnded {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err == models.ErrHoldNotOwned || err == models.ErrAdmissionRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock seats"})
+		}
 		return
 	}
 
@@ -247,15 +392,28 @@ func (h *EventHandler) LockSeats(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Seats locked successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Seats locked successfully", "hold_token": holdToken})
 }
 
 type UnlockSeatsRequest struct {
-	Count       int      `json:"count" binding:"required,min=1"`
+	Count       int      `json:"count" binding:"omitempty,min=1"` // ignored when unlocking a hold
 	TicketClass string   `json:"ticket_class"`
-	SeatIDs     []string `json:"seat_ids"`
+	SeatIDs     []string `json:"seat_ids"` // ignored when unlocking a hold
+	HoldToken   string   `json:"hold_token"`
 }
 
+// @Summary Unlock seats
+// @Description Return the tickets of one tier of a hold. Only the user who took the hold, or an internal service, may unlock it.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param input body UnlockSeatsRequest true "Unlock Input"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/unlock [post]
 func (h *EventHandler) UnlockSeats(c *gin.Context) {
 	eventIDStr := c.Param("id")
 	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
@@ -274,11 +432,227 @@ func (h *EventHandler) UnlockSeats(c *gin.Context) {
 		req.TicketClass = "normal"
 	}
 
-	err = h.service.UnlockSeats(uint(eventID), req.Count, req.TicketClass, req.SeatIDs)
+	userID, internal := requester(c)
+	if req.HoldToken == "" {
+		// Holds taken before hold tokens existed can only be unlocked by
+		// the internal services, by count
+		if !internal {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrHoldTokenRequired.Error()})
+			return
+		}
+		if req.Count < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "count is required"})
+			return
+		}
+		err = h.service.UnlockSeats(uint(eventID), req.Count, req.TicketClass, req.SeatIDs)
+	} else {
+		err = h.service.UnlockHold(uint(eventID), req.HoldToken, userID, req.TicketClass)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock seats"})
+		if err == models.ErrHoldNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrHoldNotOwned {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock seats"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Seats unlocked successfully"})
 }
+
+// ReleaseSeats returns seats of a cancelled, already confirmed booking to the
+// inventory. Unlike UnlockSeats it also restores the persisted availability
+// counts that were decremented when the booking was confirmed. Internal
+// services only.
+func (h *EventHandler) ReleaseSeats(c *gin.Context) {
+	if _, internal := requester(c); !internal {
+		c.JSON(http.StatusForbidden, gin.H{"error": models.ErrInternalOnly.Error()})
+		return
+	}
+
+	eventIDStr := c.Param("id")
+	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var req UnlockSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Count < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count is required"})
+		return
+	}
+
+	if req.TicketClass == "" {
+		req.TicketClass = "normal"
+	}
+
+	if err := h.service.ReleaseSeats(uint(eventID), req.Count, req.TicketClass, req.SeatIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release seats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seats released successfully"})
+}
+
+type AttachHoldBookingRequest struct {
+	BookingID uint `json:"booking_id" binding:"required"`
+}
+
+// @Summary Attach a booking to a seat hold
+// @Description Record the booking a hold was taken for. Only the user who took the hold, or an internal service, may do this.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param token path string true "Hold token"
+// @Param input body AttachHoldBookingRequest true "Booking"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/holds/{token} [put]
+func (h *EventHandler) AttachHoldBooking(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var req AttachHoldBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := requester(c)
+	if err := h.service.AttachHoldBooking(uint(eventID), c.Param("token"), userID, req.BookingID); err != nil {
+		if err == models.ErrHoldNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrHoldNotOwned {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update seat hold"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seat hold updated successfully"})
+}
+
+// @Summary Get who holds a seat
+// @Description Get whether a seat is available, locked or sold, with the pending hold or confirmed booking holding it. Organizers of the event, admins and the holder only.
+// @Tags events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param seatId path string true "Seat ID"
+// @Success 200 {object} models.SeatHolder
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/seats/{seatId}/holder [get]
+func (h *EventHandler) GetSeatHolder(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	userID, internal := requester(c)
+	role, _ := c.Get("role")
+	holder, err := h.service.GetSeatHolder(uint(eventID), c.Param("seatId"), userID, internal || role == "admin")
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else if err == models.ErrUnknownSeat {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrUnauthorized {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seat holder"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, holder)
+}
+
+// @Summary Get an event's seat map
+// @Description Get every seat of the event's venue by section and row, with its status (available, locked or sold) and price
+// @Tags events
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.SeatMap
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/seatmap [get]
+func (h *EventHandler) GetSeatMap(c *gin.Context) {
+	eventIDStr := c.Param("id")
+	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	seatMap, err := h.service.GetSeatMap(uint(eventID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else if err == models.ErrVenueNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch seat map"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, seatMap)
+}
+
+type ResolveSeatsRequest struct {
+	SeatIDs []string `json:"seat_ids" binding:"required,min=1"`
+}
+
+// @Summary Resolve seat IDs
+// @Description Look up seats at the event's venue and return their section, row, ticket class and price
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param input body ResolveSeatsRequest true "Seat IDs"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /events/{id}/seats/resolve [post]
+func (h *EventHandler) ResolveSeats(c *gin.Context) {
+	eventIDStr := c.Param("id")
+	eventID, err := strconv.ParseUint(eventIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	var req ResolveSeatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seats, err := h.service.ResolveSeats(uint(eventID), req.SeatIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		} else if err == models.ErrUnknownSeat {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve seats"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seats": seats})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// lifecycleError writes the response for a failed lifecycle change.
+func lifecycleError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	} else if err == models.ErrUnauthorized {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	} else if errors.Is(err, models.ErrInvalidTransition) || err == models.ErrEventHasSales {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change event status"})
+	}
+}
+
+// @Summary Publish an event
+// @Description List a draft event (Organizer only). It goes on sale at once if a ticket tier is on sale, otherwise when the first tier's sales open.
+// @Tags events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.Event
+// @Failure 409 {object} map[string]interface{}
+// @Router /events/{id}/publish [post]
+func (h *EventHandler) PublishEvent(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "organizer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer role"})
+		return
+	}
+
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	organizerID, _ := c.Get("user_id")
+	event, err := h.service.PublishEvent(uint(eventID), uint(organizerID.(float64)))
+	if err != nil {
+		lifecycleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event published successfully", "event": event})
+}
+
+// @Summary Unpublish an event
+// @Description Take an event without sold tickets back to draft (Organizer only)
+// @Tags events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.Event
+// @Failure 409 {object} map[string]interface{}
+// @Router /events/{id}/unpublish [post]
+func (h *EventHandler) UnpublishEvent(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "organizer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer role"})
+		return
+	}
+
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	organizerID, _ := c.Get("user_id")
+	event, err := h.service.UnpublishEvent(uint(eventID), uint(organizerID.(float64)))
+	if err != nil {
+		lifecycleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event unpublished successfully", "event": event})
+}
+
+type CancelEventRequest struct {
+	Reason string `json:"reason"`
+}
+
+// @Summary Cancel an event
+// @Description Cancel an event for good and stop its ticket sales (Organizer of the event or Admin)
+// @Tags events
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Param input body CancelEventRequest false "Cancellation reason"
+// @Success 200 {object} models.Event
+// @Failure 409 {object} map[string]interface{}
+// @Router /events/{id}/cancel [post]
+func (h *EventHandler) CancelEvent(c *gin.Context) {
+	role, _ := c.Get("role")
+	if role != "organizer" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer or Admin role"})
+		return
+	}
+
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	// The reason is optional
+	var req CancelEventRequest
+	_ = c.ShouldBindJSON(&req)
+
+	userID, _ := c.Get("user_id")
+	event, err := h.service.CancelEvent(uint(eventID), uint(userID.(float64)), role == "admin", req.Reason)
+	if err != nil {
+		lifecycleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event cancelled successfully", "event": event})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type VenueHandler struct {
+	service service.VenueService
+}
+
+func NewVenueHandler(service service.VenueService) *VenueHandler {
+	return &VenueHandler{service: service}
+}
+
+type CreateVenueRequest struct {
+	Name     string                 `json:"name" binding:"required"`
+	Address  string                 `json:"address"`
+	Sections []CreateSectionRequest `json:"sections" binding:"required,min=1,dive"`
+}
+
+type CreateSectionRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	TicketClass string             `json:"ticket_class" binding:"required"` // code of the event tier the section is sold as
+	Rows        []CreateRowRequest `json:"rows" binding:"required,min=1,dive"`
+}
+
+// CreateRowRequest describes a row of Seats seats numbered from 1.
+type CreateRowRequest struct {
+	Label string `json:"label" binding:"required"`
+	Seats int    `json:"seats" binding:"required,min=1,max=500"`
+}
+
+// @Summary Create a venue
+// @Description Create a venue layout of sections, rows and seats (Organizer only). Seat IDs are "<section>-<row>-<number>".
+// @Tags venues
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body CreateVenueRequest true "Venue Input"
+// @Success 201 {object} models.Venue
+// @Failure 400 {object} map[string]interface{}
+// @Router /venues [post]
+func (h *VenueHandler) CreateVenue(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "organizer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer role"})
+		return
+	}
+
+	organizerID, _ := c.Get("user_id")
+
+	var req CreateVenueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	venue := &models.Venue{
+		Name:        req.Name,
+		Address:     req.Address,
+		OrganizerID: uint(organizerID.(float64)),
+	}
+	for _, sectionReq := range req.Sections {
+		section := models.Section{Name: sectionReq.Name, TicketClass: sectionReq.TicketClass}
+		for _, rowReq := range sectionReq.Rows {
+			row := models.SeatRow{Label: rowReq.Label}
+			for number := 1; number <= rowReq.Seats; number++ {
+				row.Seats = append(row.Seats, models.Seat{Number: number})
+			}
+			section.Rows = append(section.Rows, row)
+		}
+		venue.Sections = append(venue.Sections, section)
+	}
+
+	if err := h.service.CreateVenue(venue); err != nil {
+		if err == models.ErrInvalidVenue {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create venue"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Venue created successfully", "venue": venue})
+}
+
+// @Summary Get venues of the logged-in organizer
+// @Description Get the venues created by the organizer, without their layouts
+// @Tags venues
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Venue
+// @Failure 403 {object} map[string]interface{}
+// @Router /venues [get]
+func (h *VenueHandler) GetMyVenues(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "organizer" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Organizer role"})
+		return
+	}
+
+	organizerID, _ := c.Get("user_id")
+	venues, err := h.service.GetVenuesByOrganizer(uint(organizerID.(float64)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch venues"})
+		return
+	}
+
+	c.JSON(http.StatusOK, venues)
+}
+
+// @Summary Get venue by ID
+// @Description Get a venue with its sections, rows and seats
+// @Tags venues
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Success 200 {object} models.Venue
+// @Failure 404 {object} map[string]interface{}
+// @Router /venues/{id} [get]
+func (h *VenueHandler) GetVenue(c *gin.Context) {
+	venueID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid venue ID"})
+		return
+	}
+
+	venue, err := h.service.GetVenue(uint(venueID))
+	if err != nil {
+		if err == models.ErrVenueNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch venue"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// queueStreamInterval is how often a queue stream reports the position.
+const queueStreamInterval = 2 * time.Second
+
+// waitingRoomError writes the response for a failed waiting room request.
+func waitingRoomError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	} else if err == models.ErrQueueTicketNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	} else if err == models.ErrNoWaitingRoom || err == models.ErrEventNotOnSale || err == models.ErrSalesEnded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reach the waiting room"})
+	}
+}
+
+// @Summary Join the waiting room
+// @Description Queue for the tickets of an event with a waiting room, from the time it is published. Returns a queue token with the position in line; once admitted the token is the admission_token seat locks and bookings need until admitted_until. Joining again returns the same ticket.
+// @Tags events
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.QueueTicket
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/queue [post]
+func (h *EventHandler) JoinWaitingRoom(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	ticket, err := h.service.JoinWaitingRoom(uint(eventID), uint(userID.(float64)))
+	if err != nil {
+		waitingRoomError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// @Summary Get a queue ticket
+// @Description Poll the position of a queue ticket in an event's waiting room, or its admission. The queue token is the credential.
+// @Tags events
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param token path string true "Queue Token"
+// @Success 200 {object} models.QueueTicket
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/queue/{token} [get]
+func (h *EventHandler) GetQueueTicket(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	ticket, err := h.service.GetQueueTicket(uint(eventID), c.Param("token"))
+	if err != nil {
+		waitingRoomError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// @Summary Stream a queue ticket
+// @Description Server-sent events following a queue ticket: a "queue" event with the ticket every few seconds until it is admitted, or an "expired" event once it is gone. The queue token is the credential, so EventSource can be used.
+// @Tags events
+// @Produce text/event-stream
+// @Param id path int true "Event ID"
+// @Param token path string true "Queue Token"
+// @Success 200 {object} models.QueueTicket
+// @Failure 404 {object} map[string]interface{}
+// @Router /events/{id}/queue/{token}/stream [get]
+func (h *EventHandler) StreamQueueTicket(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	token := c.Param("token")
+	ticket, err := h.service.GetQueueTicket(uint(eventID), token)
+	if err != nil {
+		waitingRoomError(c, err)
+		return
+	}
+
+	// Keep Nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	ticker := time.NewTicker(queueStreamInterval)
+	defer ticker.Stop()
+
+	sent := false
+	c.Stream(func(w io.Writer) bool {
+		if sent {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-ticker.C:
+			}
+
+			next, err := h.service.GetQueueTicket(uint(eventID), token)
+			if err == models.ErrQueueTicketNotFound {
+				c.SSEvent("expired", gin.H{"error": err.Error()})
+				return false
+			}
+			if err != nil {
+				// Try again on the next tick
+				return true
+			}
+			ticket = next
+		}
+
+		sent = true
+		c.SSEvent("queue", ticket)
+		return ticket.Status != models.QueueStatusAdmitted
+	})
+}
@@ -1,84 +1,70 @@
 package messaging
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
 	"time"
 
-	"github.com/streadway/amqp"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 )
 
-var Channel *amqp.Channel
+const (
+	AuditLogsQueue           = "audit_logs"
+	BookingConfirmedExchange = "booking_confirmed"
+	BookingConfirmedQueue    = "event-service.booking_confirmed"
+	// EventStatusChangedExchange fans out event lifecycle changes. No queue
+	// is bound to it yet, so its messages are published as optional.
+	EventStatusChangedExchange = "event_status_changed"
+	// EventCancelledExchange fans out cancellations so every subscribing
+	// service can wind down the event's bookings.
+	EventCancelledExchange = "event_cancelled"
 
-func ConnectRabbitMQ() {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
+	// Producer is the source attribute of the events this service publishes.
+	Producer = "event-service"
+)
 
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		log.Printf("Failed to connect to RabbitMQ: %v", err)
-		return
-	}
+// EventCancelledSubscriberQueues are the queues bound to EventCancelledExchange.
+var EventCancelledSubscriberQueues = []string{
+	"booking-service.event_cancelled",
+}
 
-	Channel, err = conn.Channel()
-	if err != nil {
-		log.Printf("Failed to open a channel: %v", err)
-		return
-	}
+// Client is the service's long-lived RabbitMQ connection.
+var Client *shared.Client
 
-	// Declare audit_logs queue
-	_, err = Channel.QueueDeclare(
-		"audit_logs", // name
-		true,         // durable
-		false,        // delete when unused
-		false,        // exclusive
-		false,        // no-wait
-		nil,          // arguments
-	)
-	if err != nil {
-		log.Printf("Failed to declare audit_logs queue: %v", err)
-	}
-}
+func ConnectRabbitMQ(dedupStore dedup.Store) {
+	Client = shared.NewClient(shared.URLFromEnv())
+	// Redelivered messages are acked without being processed twice
+	Client.Deduplicate(dedup.NewFilter(dedupStore))
+	Client.DeclareTopology(shared.DeclareQueues(AuditLogsQueue))
+	// Bind our own queue to the booking_confirmed fanout exchange
+	Client.DeclareTopology(shared.DeclareFanout(BookingConfirmedExchange, BookingConfirmedQueue))
+	Client.DeclareTopology(shared.DeclareFanout(EventStatusChangedExchange))
+	// Subscriber queues are declared here too so cancellations are kept until the subscribers start
+	Client.DeclareTopology(shared.DeclareFanout(EventCancelledExchange, EventCancelledSubscriberQueues...))
 
-type AuditLogMessage struct {
-	UserID    uint      `json:"user_id"`
-	Action    string    `json:"action"`
-	Details   string    `json:"details"`
-	IPAddress string    `json:"ip_address"`
-	CreatedAt time.Time `json:"created_at"`
+	if err := Client.Connect(); err != nil {
+		log.Printf("RabbitMQ not available yet, retrying in the background: %v", err)
+	}
 }
 
 func PublishAuditLog(userID uint, action, details string) {
-	if Channel == nil {
+	if Client == nil {
 		return
 	}
 
-	msg := AuditLogMessage{
+	body, err := events.Marshal(events.TypeAuditLogRecorded, Producer, "", events.AuditLogRecorded{
 		UserID:    userID,
 		Action:    action,
 		Details:   details,
 		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode audit log: %v", err)
+		return
 	}
 
-	body, _ := json.Marshal(msg)
-
-	err := Channel.Publish(
-		"",           // exchange
-		"audit_logs", // routing key
-		false,        // mandatory
-		false,        // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-
-	if err != nil {
+	if err := Client.Publish("", AuditLogsQueue, body); err != nil {
 		log.Printf("Failed to publish audit log: %v", err)
 	}
 }
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"os"
@@ -45,3 +46,73 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// InternalTokenHeader carries the INTERNAL_SERVICE_TOKEN shared by the
+// backend services.
+const InternalTokenHeader = "X-Internal-Token"
+
+// InternalOrAuthMiddleware accepts requests from internal services, which
+// are marked "internal", as well as users' bearer tokens like
+// AuthMiddleware.
+func InternalOrAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		secret := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		token := c.GetHeader(InternalTokenHeader)
+		if secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			c.Set("internal", true)
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// InternalAuthMiddleware only accepts requests from internal services,
+// which are marked "internal".
+func InternalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		token := c.GetHeader(InternalTokenHeader)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Internal service token required"})
+			c.Abort()
+			return
+		}
+		c.Set("internal", true)
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware marks internal services and users with a valid
+// bearer token like InternalOrAuthMiddleware, but lets every other request
+// through anonymously, for public endpoints that show more to some callers.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := os.Getenv("INTERNAL_SERVICE_TOKEN")
+		internalToken := c.GetHeader(InternalTokenHeader)
+		if secret != "" && subtle.ConstantTimeCompare([]byte(internalToken), []byte(secret)) == 1 {
+			c.Set("internal", true)
+			c.Next()
+			return
+		}
+
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+				}
+				return []byte(os.Getenv("JWT_SECRET")), nil
+			})
+			if err == nil && token.Valid {
+				if claims, ok := token.Claims.(jwt.MapClaims); ok {
+					c.Set("user_id", claims["user_id"])
+					c.Set("role", claims["role"])
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -15,8 +15,36 @@ type Event struct {
 	TotalSeats     int       `gorm:"not null" json:"total_seats"`
 	AvailableSeats int       `gorm:"not null" json:"available_seats"`
 	OrganizerID    uint      `gorm:"not null" json:"organizer_id"`
+	VenueID        *uint     `gorm:"index" json:"venue_id"`
 
-	// Ticket Classes
+	// Sale window of the whole event; either end may be open. Sales of
+	// every tier close when the event starts at the latest.
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+
+	// Waiting room for high-demand on-sales: buyers queue and are admitted
+	// AdmissionRate per minute, each with PurchaseWindowMinutes to lock
+	// seats. Zero uses the service's defaults.
+	WaitingRoom           bool `gorm:"default:false" json:"waiting_room"`
+	AdmissionRate         int  `gorm:"default:0" json:"admission_rate"`
+	PurchaseWindowMinutes int  `gorm:"default:0" json:"purchase_window_minutes"`
+
+	// Tickets one user may hold across their pending and confirmed bookings
+	// of the event, 0 for no limit; tiers may limit theirs too. The Booking
+	// Service enforces both.
+	MaxTicketsPerUser int `gorm:"default:0" json:"max_tickets_per_user"`
+
+	// Lifecycle; only on-sale events can be booked
+	Status             EventStatus `gorm:"default:'draft';index" json:"status"`
+	CancelledAt        *time.Time  `json:"cancelled_at,omitempty"`
+	CancellationReason string      `json:"cancellation_reason,omitempty"`
+
+	// Tiers are the event's ticket kinds and the source of truth for
+	// prices and availability.
+	Tiers []TicketTier `gorm:"foreignKey:EventID" json:"tiers"`
+
+	// Legacy ticket classes, mirrored from the "normal", "vip" and "vvip"
+	// tiers for clients that predate tiers
 	PriceNormal     float64 `gorm:"default:0" json:"price_normal"`
 	PriceVIP        float64 `gorm:"default:0" json:"price_vip"`
 	PriceVVIP       float64 `gorm:"default:0" json:"price_vvip"`
@@ -26,11 +54,83 @@ type Event struct {
 	AvailableNormal int     `gorm:"default:0" json:"available_normal"`
 	AvailableVIP    int     `gorm:"default:0" json:"available_vip"`
 	AvailableVVIP   int     `gorm:"default:0" json:"available_vvip"`
+
+	// Cancellation Policy: bookings can be cancelled until CancellationDeadlineHours
+	// before the event starts, refunding RefundPercentage of the amount paid.
+	CancellationDeadlineHours int     `gorm:"default:24" json:"cancellation_deadline_hours"`
+	RefundPercentage          float64 `gorm:"default:100" json:"refund_percentage"`
+}
+
+// Tier returns the tier with the given code, or nil.
+func (e *Event) Tier(code string) *TicketTier {
+	for i := range e.Tiers {
+		if e.Tiers[i].Code == code {
+			return &e.Tiers[i]
+		}
+	}
+	return nil
+}
+
+// PriceFor returns the ticket price of a tier.
+func (e *Event) PriceFor(ticketClass string) float64 {
+	if tier := e.Tier(ticketClass); tier != nil {
+		return tier.Price
+	}
+	return 0
+}
+
+// SyncLegacyClasses copies the "normal", "vip" and "vvip" tiers into the
+// legacy price, seat and availability columns.
+func (e *Event) SyncLegacyClasses() {
+	e.PriceNormal, e.SeatsNormal, e.AvailableNormal = 0, 0, 0
+	e.PriceVIP, e.SeatsVIP, e.AvailableVIP = 0, 0, 0
+	e.PriceVVIP, e.SeatsVVIP, e.AvailableVVIP = 0, 0, 0
+	for _, tier := range e.Tiers {
+		switch tier.Code {
+		case TicketClassNormal:
+			e.PriceNormal, e.SeatsNormal, e.AvailableNormal = tier.Price, tier.Capacity, tier.Available
+		case TicketClassVIP:
+			e.PriceVIP, e.SeatsVIP, e.AvailableVIP = tier.Price, tier.Capacity, tier.Available
+		case TicketClassVVIP:
+			e.PriceVVIP, e.SeatsVVIP, e.AvailableVVIP = tier.Price, tier.Capacity, tier.Available
+		}
+	}
+}
+
+// LegacyTiers builds tiers from the legacy price and seat columns.
+func (e *Event) LegacyTiers() []TicketTier {
+	legacy := []struct {
+		code, name       string
+		price            float64
+		seats, available int
+	}{
+		{TicketClassNormal, "Normal", e.PriceNormal, e.SeatsNormal, e.AvailableNormal},
+		{TicketClassVIP, "VIP", e.PriceVIP, e.SeatsVIP, e.AvailableVIP},
+		{TicketClassVVIP, "VVIP", e.PriceVVIP, e.SeatsVVIP, e.AvailableVVIP},
+	}
+
+	var tiers []TicketTier
+	for _, class := range legacy {
+		if class.seats <= 0 && class.price <= 0 {
+			continue
+		}
+		tiers = append(tiers, TicketTier{
+			EventID:     e.ID,
+			Code:        class.code,
+			Name:        class.name,
+			Price:       class.price,
+			Capacity:    class.seats,
+			Available:   class.available,
+			Position:    len(tiers) + 1,
+			MinPerOrder: 1,
+		})
+	}
+	return tiers
 }
 
 var (
-	ErrUnauthorized     = &Error{Message: "Unauthorized access to event"}
-	ErrInvalidSeatCount = &Error{Message: "Cannot decrease total seats below booked count"}
+	ErrUnauthorized  = &Error{Message: "Unauthorized access to event"}
+	ErrInvalidPolicy = &Error{Message: "Refund percentage must be between 0 and 100 and the cancellation deadline cannot be negative"}
 )
 
 type Error struct {
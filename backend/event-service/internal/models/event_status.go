@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type EventStatus string
+
+const (
+	EventStatusDraft     EventStatus = "draft"     // only visible to its organizer
+	EventStatusPublished EventStatus = "published" // listed, sales not open yet
+	EventStatusOnSale    EventStatus = "on_sale"
+	EventStatusSoldOut   EventStatus = "sold_out"
+	// EventStatusSalesClosed is listed with its sales over, before it takes place
+	EventStatusSalesClosed EventStatus = "sales_closed"
+	EventStatusCancelled   EventStatus = "cancelled"
+	EventStatusCompleted   EventStatus = "completed" // the event has taken place
+)
+
+// eventTransitions lists the statuses an event may move to from each
+// status. Cancelled and completed events are final.
+var eventTransitions = map[EventStatus][]EventStatus{
+	EventStatusDraft:       {EventStatusPublished, EventStatusOnSale, EventStatusCancelled},
+	EventStatusPublished:   {EventStatusDraft, EventStatusOnSale, EventStatusSalesClosed, EventStatusCancelled, EventStatusCompleted},
+	EventStatusOnSale:      {EventStatusDraft, EventStatusSoldOut, EventStatusSalesClosed, EventStatusCancelled, EventStatusCompleted},
+	EventStatusSoldOut:     {EventStatusDraft, EventStatusOnSale, EventStatusSalesClosed, EventStatusCancelled, EventStatusCompleted},
+	EventStatusSalesClosed: {EventStatusDraft, EventStatusOnSale, EventStatusCancelled, EventStatusCompleted},
+}
+
+// CanTransition reports whether an event may move from one status to another.
+func (s EventStatus) CanTransition(to EventStatus) bool {
+	for _, allowed := range eventTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ListedEventStatuses are the statuses of events shown to the public.
+var ListedEventStatuses = []EventStatus{EventStatusPublished, EventStatusOnSale, EventStatusSoldOut, EventStatusSalesClosed}
+
+// Listed reports whether events with the status are shown to the public.
+func (s EventStatus) Listed() bool {
+	for _, listed := range ListedEventStatuses {
+		if s == listed {
+			return true
+		}
+	}
+	return false
+}
+
+// salesClose returns when the event's sales close: its sales end, or its
+// start if that is earlier.
+func (e *Event) salesClose() time.Time {
+	if e.SalesEnd != nil && e.SalesEnd.Before(e.Date) {
+		return *e.SalesEnd
+	}
+	return e.Date
+}
+
+// CheckSalesWindow returns an error unless the event's own sale window is
+// open at now. Tiers may narrow it further.
+func (e *Event) CheckSalesWindow(now time.Time) error {
+	if e.SalesStart != nil && now.Before(*e.SalesStart) {
+		return ErrSalesNotStarted
+	}
+	if !now.Before(e.salesClose()) {
+		return ErrSalesEnded
+	}
+	return nil
+}
+
+// TierOnSale reports whether tickets of a tier can be sold at now, within
+// both the event's and the tier's sale windows.
+func (e *Event) TierOnSale(tier *TicketTier, now time.Time) bool {
+	return e.CheckSalesWindow(now) == nil && tier.OnSale(now)
+}
+
+// SalesOpen reports whether any of the event's tiers is on sale at the
+// given time, which decides whether publishing puts it on sale directly.
+func (e *Event) SalesOpen(now time.Time) bool {
+	for i := range e.Tiers {
+		if e.TierOnSale(&e.Tiers[i], now) {
+			return true
+		}
+	}
+	return false
+}
+
+// SalesEnded reports whether no ticket of the event can be sold at now or
+// later, as its sale window or those of all its tiers have closed.
+func (e *Event) SalesEnded(now time.Time) bool {
+	if !now.Before(e.salesClose()) {
+		return true
+	}
+	for _, tier := range e.Tiers {
+		if tier.SalesEnd == nil || now.Before(*tier.SalesEnd) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	ErrEventNotOnSale     = &Error{Message: "Tickets for this event are not on sale"}
+	ErrSalesNotStarted    = &Error{Message: "Ticket sales for this event have not started yet"}
+	ErrSalesEnded         = &Error{Message: "Ticket sales for this event have ended"}
+	ErrInvalidSalesWindow = &Error{Message: "The sale window must end after it starts"}
+	ErrEventHasSales      = &Error{Message: "An event with tickets sold cannot be unpublished; cancel it instead"}
+	ErrEventClosed        = &Error{Message: "Cancelled and completed events cannot be changed"}
+)
+
+// ErrInvalidTransition is matched by every TransitionError with errors.Is.
+var ErrInvalidTransition = &Error{Message: "Event status change is not allowed"}
+
+// TransitionError rejects a status change the lifecycle does not allow.
+type TransitionError struct {
+	EventID uint
+	From    EventStatus
+	To      EventStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("event %d cannot move from %s to %s", e.EventID, e.From, e.To)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var saleNow = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func at(offset time.Duration) *time.Time {
+	t := saleNow.Add(offset)
+	return &t
+}
+
+func TestCheckSalesWindow(t *testing.T) {
+	tests := []struct {
+		name  string
+		start *time.Time
+		end   *time.Time
+		date  time.Time
+		want  error
+	}{
+		{"open window", nil, nil, saleNow.Add(48 * time.Hour), nil},
+		{"not started", at(time.Hour), nil, saleNow.Add(48 * time.Hour), ErrSalesNotStarted},
+		{"starts now", at(0), nil, saleNow.Add(48 * time.Hour), nil},
+		{"ended", nil, at(-time.Minute), saleNow.Add(48 * time.Hour), ErrSalesEnded},
+		{"ends now", nil, at(0), saleNow.Add(48 * time.Hour), ErrSalesEnded},
+		{"event started", nil, nil, saleNow.Add(-time.Minute), ErrSalesEnded},
+		{"sales end after the event starts", nil, at(72 * time.Hour), saleNow, ErrSalesEnded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &Event{Date: tt.date, SalesStart: tt.start, SalesEnd: tt.end}
+			if err := event.CheckSalesWindow(saleNow); !errors.Is(err, tt.want) {
+				t.Errorf("CheckSalesWindow = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSalesOpenAndEnded(t *testing.T) {
+	later := saleNow.Add(48 * time.Hour)
+	tests := []struct {
+		name      string
+		event     Event
+		wantOpen  bool
+		wantEnded bool
+	}{
+		{"tier on sale", Event{Date: later, Tiers: []TicketTier{{}}}, true, false},
+		{"no tiers", Event{Date: later}, false, true},
+		{"tier not started", Event{Date: later, Tiers: []TicketTier{{SalesStart: at(time.Hour)}}}, false, false},
+		{"tier ended", Event{Date: later, Tiers: []TicketTier{{SalesEnd: at(-time.Hour)}}}, false, true},
+		{"one tier left", Event{Date: later, Tiers: []TicketTier{{SalesEnd: at(-time.Hour)}, {SalesEnd: at(time.Hour)}}}, true, false},
+		{"event window not started", Event{Date: later, SalesStart: at(time.Hour), Tiers: []TicketTier{{}}}, false, false},
+		{"event window ended", Event{Date: later, SalesEnd: at(-time.Hour), Tiers: []TicketTier{{}}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.SalesOpen(saleNow); got != tt.wantOpen {
+				t.Errorf("SalesOpen = %v, want %v", got, tt.wantOpen)
+			}
+			if got := tt.event.SalesEnded(saleNow); got != tt.wantEnded {
+				t.Errorf("SalesEnded = %v, want %v", got, tt.wantEnded)
+			}
+		})
+	}
+}
+
+func TestEventCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to EventStatus
+		want     bool
+	}{
+		{EventStatusDraft, EventStatusPublished, true},
+		{EventStatusDraft, EventStatusOnSale, true},
+		{EventStatusDraft, EventStatusSoldOut, false},
+		{EventStatusDraft, EventStatusCompleted, false},
+		{EventStatusPublished, EventStatusDraft, true},
+		{EventStatusOnSale, EventStatusSoldOut, true},
+		{EventStatusSoldOut, EventStatusOnSale, true},
+		{EventStatusSalesClosed, EventStatusOnSale, true},
+		{EventStatusOnSale, EventStatusCancelled, true},
+		{EventStatusCancelled, EventStatusOnSale, false},
+		{EventStatusCancelled, EventStatusDraft, false},
+		{EventStatusCompleted, EventStatusCancelled, false},
+		{EventStatusOnSale, EventStatusOnSale, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s.CanTransition(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestListed(t *testing.T) {
+	for _, status := range []EventStatus{EventStatusDraft, EventStatusCancelled, EventStatusCompleted} {
+		if status.Listed() {
+			t.Errorf("%s events are listed", status)
+		}
+	}
+	for _, status := range ListedEventStatuses {
+		if !status.Listed() {
+			t.Errorf("%s events are not listed", status)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// SeatHold is a claim on tickets of an event taken for a pending booking.
+// It lives in Redis next to the seat locks and records who may unlock or
+// confirm them: the user it was taken for, holding the token, or the
+// internal services.
+type SeatHold struct {
+	Token     string              `json:"hold_token,omitempty"` // shown to the holder and internal services only
+	EventID   uint                `json:"event_id"`
+	UserID    uint                `json:"user_id"`
+	BookingID uint                `json:"booking_id,omitempty"`
+	Counts    map[string]int      `json:"counts"`          // tickets held per tier
+	Seats     map[string][]string `json:"seats,omitempty"` // seat IDs held per tier
+	ExpiresAt time.Time           `json:"expires_at"`      // when the seat locks lapse
+}
+
+// SeatLock is the Redis state of a seat: locked under a hold's token, or
+// sold. Locks taken before holds existed have no token.
+type SeatLock struct {
+	Status    string
+	HoldToken string
+}
+
+// SeatHolder reports who holds a seat of an event.
+type SeatHolder struct {
+	SeatID    string    `json:"seat_id"`
+	Status    string    `json:"status"`
+	BookingID uint      `json:"booking_id,omitempty"` // the confirmed booking of a sold seat
+	Hold      *SeatHold `json:"hold,omitempty"`       // the pending hold of a locked seat
+}
+
+var (
+	ErrHoldNotFound      = &Error{Message: "Seat hold not found or expired"}
+	ErrHoldNotOwned      = &Error{Message: "Seats are held by another user"}
+	ErrHoldTokenRequired = &Error{Message: "A hold token is required to unlock seats"}
+	ErrInvalidHoldToken  = &Error{Message: "Hold tokens are 16 to 64 letters, digits or dashes"}
+	ErrInternalOnly      = &Error{Message: "Only internal services may do this"}
+)
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// TicketTier is a named kind of ticket for an event, such as "Early Bird" or
+// "Backstage", with its own price, capacity, sale window and order limits.
+// Code identifies the tier in seat locks, venue sections and bookings (the
+// "ticket class"); events created from the legacy price and seat columns
+// have the tiers "normal", "vip" and "vvip".
+type TicketTier struct {
+	ID        uint    `gorm:"primaryKey" json:"id"`
+	EventID   uint    `gorm:"not null;uniqueIndex:idx_event_tier_code" json:"event_id"`
+	Code      string  `gorm:"not null;uniqueIndex:idx_event_tier_code" json:"code"`
+	Name      string  `gorm:"not null" json:"name"`
+	Price     float64 `gorm:"not null" json:"price"`
+	Capacity  int     `gorm:"not null" json:"capacity"`
+	Available int     `gorm:"not null" json:"available"`
+	Position  int     `json:"position"`
+
+	// Sale window; either end may be open
+	SalesStart *time.Time `json:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end"`
+
+	// Tickets of this tier allowed in one order; MaxPerOrder 0 means no limit
+	MinPerOrder int `gorm:"default:1" json:"min_per_order"`
+	MaxPerOrder int `gorm:"default:0" json:"max_per_order"`
+	// Tickets of this tier one user may hold across their bookings; 0 means
+	// no limit
+	MaxPerUser int `gorm:"default:0" json:"max_per_user"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OnSale reports whether the tier's sale window is open at now.
+func (t *TicketTier) OnSale(now time.Time) bool {
+	if t.SalesStart != nil && now.Before(*t.SalesStart) {
+		return false
+	}
+	if t.SalesEnd != nil && !now.Before(*t.SalesEnd) {
+		return false
+	}
+	return true
+}
+
+// CheckOrder returns an error if count tickets of this tier cannot be
+// bought in one order at now.
+func (t *TicketTier) CheckOrder(count int, now time.Time) error {
+	if !t.OnSale(now) {
+		return ErrTierNotOnSale
+	}
+	if count < t.MinPerOrder || (t.MaxPerOrder > 0 && count > t.MaxPerOrder) {
+		return ErrTierOrderLimit
+	}
+	return nil
+}
+
+var (
+	ErrUnknownTier    = &Error{Message: "Ticket tier does not exist for this event"}
+	ErrInvalidTier    = &Error{Message: "Every ticket tier needs a unique name, a non-negative price and capacity, a sale window that ends after it starts and order limits of at least 1"}
+	ErrTierNotOnSale  = &Error{Message: "Tickets of this tier are not on sale"}
+	ErrTierOrderLimit = &Error{Message: "Number of tickets is outside this tier's per-order limits"}
+	ErrTierMismatch   = &Error{Message: "Every section of the venue must be sold as one of the event's ticket tiers"}
+
+	ErrInvalidPurchaseLimit = &Error{Message: "Per-user ticket limits cannot be negative or below a tier's minimum order"}
+)
+
+// BookingSale records that the tickets of a confirmed booking were taken
+// off the tiers, so a redelivered confirmation does not sell them twice.
+type BookingSale struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventID   uint      `gorm:"not null;index" json:"event_id"`
+	BookingID uint      `gorm:"not null;uniqueIndex" json:"booking_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTierOnSale(t *testing.T) {
+	event := &Event{Date: saleNow.Add(48 * time.Hour)}
+	tests := []struct {
+		name string
+		tier TicketTier
+		want bool
+	}{
+		{"no window", TicketTier{}, true},
+		{"within", TicketTier{SalesStart: at(-time.Hour), SalesEnd: at(time.Hour)}, true},
+		{"starts now", TicketTier{SalesStart: at(0)}, true},
+		{"not started", TicketTier{SalesStart: at(time.Second)}, false},
+		{"ends now", TicketTier{SalesEnd: at(0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tier.OnSale(saleNow); got != tt.want {
+				t.Errorf("OnSale = %v, want %v", got, tt.want)
+			}
+			if got := event.TierOnSale(&tt.tier, saleNow); got != tt.want {
+				t.Errorf("TierOnSale = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	closed := &Event{Date: saleNow.Add(48 * time.Hour), SalesEnd: at(-time.Hour)}
+	if closed.TierOnSale(&TicketTier{}, saleNow) {
+		t.Error("tier on sale after the event's sales ended")
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		tier  TicketTier
+		count int
+		want  error
+	}{
+		{"no limits", TicketTier{MinPerOrder: 1}, 10, nil},
+		{"at minimum", TicketTier{MinPerOrder: 2, MaxPerOrder: 4}, 2, nil},
+		{"at maximum", TicketTier{MinPerOrder: 2, MaxPerOrder: 4}, 4, nil},
+		{"below minimum", TicketTier{MinPerOrder: 2, MaxPerOrder: 4}, 1, ErrTierOrderLimit},
+		{"above maximum", TicketTier{MinPerOrder: 2, MaxPerOrder: 4}, 5, ErrTierOrderLimit},
+		{"not on sale", TicketTier{MinPerOrder: 1, SalesStart: at(time.Hour)}, 1, ErrTierNotOnSale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tier.CheckOrder(tt.count, saleNow); !errors.Is(err, tt.want) {
+				t.Errorf("CheckOrder(%d) = %v, want %v", tt.count, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Codes of the tiers that replace the legacy ticket class columns.
+const (
+	TicketClassNormal = "normal"
+	TicketClassVIP    = "vip"
+	TicketClassVVIP   = "vvip"
+)
+
+// Venue is a seating layout: sections made of rows of seats. Events that are
+// created without a venue get one generated from their tiers.
+type Venue struct {
+	gorm.Model
+	Name        string    `gorm:"not null" json:"name"`
+	Address     string    `json:"address"`
+	OrganizerID uint      `gorm:"not null;index" json:"organizer_id"`
+	Generated   bool      `gorm:"default:false" json:"generated"`
+	Sections    []Section `gorm:"foreignKey:VenueID" json:"sections,omitempty"`
+}
+
+// Section is a block of rows sold as one ticket class, the code of a tier of
+// the events held at the venue.
+type Section struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	VenueID     uint      `gorm:"not null;index" json:"venue_id"`
+	Name        string    `gorm:"not null" json:"name"`
+	Code        string    `gorm:"not null" json:"code"` // prefix of the section's seat IDs
+	TicketClass string    `gorm:"not null" json:"ticket_class"`
+	Position    int       `json:"position"`
+	Rows        []SeatRow `gorm:"foreignKey:SectionID" json:"rows,omitempty"`
+}
+
+type SeatRow struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	SectionID uint   `gorm:"not null;index" json:"section_id"`
+	Label     string `gorm:"not null" json:"label"`
+	Position  int    `json:"position"`
+	Seats     []Seat `gorm:"foreignKey:RowID" json:"seats,omitempty"`
+}
+
+// Seat is a single seat. Code is the seat ID clients and bookings use, e.g.
+// "vip-3-12" for seat 12 in row 3 of the VIP section; it is unique per venue.
+type Seat struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	VenueID   uint   `gorm:"not null;uniqueIndex:idx_venue_seat_code" json:"venue_id"`
+	SectionID uint   `gorm:"not null;index" json:"section_id"`
+	RowID     uint   `gorm:"not null;index" json:"row_id"`
+	Code      string `gorm:"not null;uniqueIndex:idx_venue_seat_code" json:"code"`
+	Number    int    `gorm:"not null" json:"number"`
+}
+
+// SoldSeat marks a seat of an event as sold to a confirmed booking.
+type SoldSeat struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventID   uint      `gorm:"not null;uniqueIndex:idx_event_sold_seat" json:"event_id"`
+	SeatCode  string    `gorm:"not null;uniqueIndex:idx_event_sold_seat" json:"seat_code"`
+	BookingID uint      `gorm:"index" json:"booking_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SeatInfo is a seat resolved against its venue: where it is, what class it
+// is sold as and its price for an event.
+type SeatInfo struct {
+	ID          string  `json:"id"`
+	Section     string  `json:"section"`
+	Row         string  `json:"row"`
+	RowPosition int     `json:"row_position"`
+	Number      int     `json:"number"`
+	TicketClass string  `json:"ticket_class"`
+	Price       float64 `json:"price"`
+}
+
+// Seat statuses in a seat map.
+const (
+	SeatStatusAvailable = "available"
+	SeatStatusLocked    = "locked"
+	SeatStatusSold      = "sold"
+)
+
+type SeatMapSeat struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	Status string `json:"status"`
+}
+
+type SeatMapRow struct {
+	Label    string        `json:"label"`
+	Position int           `json:"position"`
+	Seats    []SeatMapSeat `json:"seats"`
+}
+
+type SeatMapSection struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	TicketClass string       `json:"ticket_class"`
+	Price       float64      `json:"price"`
+	Available   int          `json:"available"`
+	Rows        []SeatMapRow `json:"rows"`
+}
+
+// SeatMap is the availability of every seat of an event.
+type SeatMap struct {
+	EventID   uint             `json:"event_id"`
+	VenueID   uint             `json:"venue_id"`
+	VenueName string           `json:"venue_name"`
+	Sections  []SeatMapSection `json:"sections"`
+}
+
+var (
+	ErrVenueNotFound     = &Error{Message: "Venue not found"}
+	ErrInvalidVenue      = &Error{Message: "A venue needs at least one section, every section a ticket class and a unique name, and every row at least one seat"}
+	ErrUnknownSeat       = &Error{Message: "One or more seats do not exist at this event's venue"}
+	ErrSeatClassMismatch = &Error{Message: "One or more seats do not belong to the requested ticket class"}
+	ErrSeatLayoutFixed   = &Error{Message: "Seat counts come from the venue layout and cannot be changed directly"}
+)
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	QueueStatusWaiting  = "waiting"
+	QueueStatusAdmitted = "admitted"
+)
+
+// QueueTicket is a user's place in the waiting room of an event. It lives
+// in Redis; once the user is admitted its token is the admission token
+// that seat locks require until AdmittedUntil.
+type QueueTicket struct {
+	Token         string     `json:"queue_token"`
+	EventID       uint       `json:"event_id"`
+	UserID        uint       `json:"user_id"`
+	Status        string     `json:"status"`
+	Position      int64      `json:"position,omitempty"`               // 1 is next in line
+	EstimatedWait int64      `json:"estimated_wait_seconds,omitempty"` // at the event's admission rate
+	AdmittedUntil *time.Time `json:"admitted_until,omitempty"`
+}
+
+var (
+	ErrNoWaitingRoom       = &Error{Message: "This event has no waiting room"}
+	ErrQueueTicketNotFound = &Error{Message: "Queue ticket not found or expired, join the waiting room again"}
+	ErrAdmissionRequired   = &Error{Message: "Tickets for this event are sold through a waiting room; a valid admission token is required"}
+	ErrInvalidWaitingRoom  = &Error{Message: "Admission rate and purchase window cannot be negative"}
+)
@@ -3,23 +3,98 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/database"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type EventRepository interface {
 	CreateEvent(event *models.Event) error
 	InitializeSeats(event *models.Event) error
-	LockSeats(eventID uint, count int, ticketClass string, seatIDs []string) (bool, error)
+	// LockSeats locks tickets of one tier under the hold with the given
+	// token, creating the hold for userID or adding to it. It fails with
+	// ErrHoldNotOwned if the token belongs to another user's hold.
+	LockSeats(eventID, userID uint, holdToken string, count int, ticketClass string, seatIDs []string) (bool, error)
+	// UnlockSeats returns tickets to the counters and deletes seat locks
+	// whatever holds them. Used for confirmed seats and internal callers
+	// unlocking holds taken before hold tokens existed.
 	UnlockSeats(eventID uint, count int, ticketClass string, seatIDs []string) error
+	// UnlockHold returns the tickets of one tier of a hold, deleting the
+	// seat locks still taken under it, and reports how many were returned.
+	// userID 0 skips the owner check for internal callers.
+	UnlockHold(eventID uint, holdToken string, userID uint, ticketClass string) (int, error)
+	GetHold(eventID uint, holdToken string) (*models.SeatHold, error)
+	SetHoldBooking(eventID uint, holdToken string, bookingID uint) error
+	// ConfirmHold marks the seats of a paid hold sold and deletes the hold.
+	// If a seat is locked under another hold nothing is changed and the
+	// seats concerned are returned.
+	ConfirmHold(eventID uint, holdToken string, seatIDs []string) ([]string, error)
 	GetAllEvents() ([]models.Event, error)
+	// GetListedEvents returns the events shown to the public.
+	GetListedEvents() ([]models.Event, error)
 	GetEventsByOrganizerID(organizerID uint) ([]models.Event, error)
 	GetEventByID(eventID uint) (*models.Event, error)
 	UpdateEvent(event *models.Event) error
-	UpdateRedisSeats(eventID uint, newAvailableSeats int) error
+	// TransitionEvent moves an event to another status if its lifecycle
+	// allows it, applying updates alongside. announce is given the event as
+	// it was before the change and returns the outbox messages announcing
+	// it, which are stored in the same transaction; an error aborts the
+	// change.
+	TransitionEvent(eventID uint, to models.EventStatus, updates map[string]interface{}, announce func(event *models.Event) ([]outbox.Message, error)) error
+	// GetEventsWithoutVenue returns events created before seat layouts existed.
+	GetEventsWithoutVenue() ([]models.Event, error)
+	// GetEventsWithoutTiers returns events created before ticket tiers existed.
+	GetEventsWithoutTiers() ([]models.Event, error)
+	CreateTiers(tiers []models.TicketTier) error
+	// SetTierPurchaseLimits sets the per-user limit of the given tiers.
+	SetTierPurchaseLimits(eventID uint, limits map[string]int) error
+	// AdjustTierAvailability adds delta to a tier's available count, keeping
+	// it between 0 and the tier's capacity.
+	AdjustTierAvailability(eventID uint, code string, delta int) error
+	// SellTickets takes the tickets of a confirmed booking off its tiers,
+	// marks its seats sold to it and updates the event's counts in one
+	// transaction, returning the updated event. A booking already sold is
+	// left alone and nil is returned; bookingID 0 is always sold.
+	SellTickets(eventID, bookingID uint, tiers map[string]int, seatIDs []string, seatsBooked int) (*models.Event, error)
+	// GetSeatHolds returns the Redis state of the given seats; seats that
+	// are neither locked nor sold are left out.
+	GetSeatHolds(eventID uint, seatIDs []string) (map[string]models.SeatLock, error)
+	// MarkSeatsSold replaces the expiring locks of paid seats with a
+	// permanent "sold" marker.
+	MarkSeatsSold(eventID uint, seatIDs []string) error
+
+	// Seat counters in Redis; the tier code "" is the event's total counter.
+
+	// GetSeatCounters returns the counters of the given tiers; counters that
+	// do not exist are left out.
+	GetSeatCounters(eventID uint, codes []string) (map[string]int, error)
+	// RestoreSeatCounters creates the counters that do not exist and returns
+	// how many were created. Existing counters are left alone.
+	RestoreSeatCounters(eventID uint, counters map[string]int) (int, error)
+	// RestoreSeatHolds marks sold and locked seats whose keys do not exist
+	// and returns how many were created.
+	RestoreSeatHolds(eventID uint, sold, locked []string) (int, error)
+	// RepairSeatCounter sets a counter to expected if it still holds
+	// observed, so a lock taken since it was read is not overwritten.
+	RepairSeatCounter(eventID uint, code string, observed, expected int) (bool, error)
+	SetTierAvailability(eventID uint, code string, available int) error
 }
 
+const (
+	// seatLockTTL is how long a seat stays locked for a pending booking.
+	seatLockTTL = 15 * time.Minute
+	// holdRecordTTL keeps a hold's record past its locks, so a booking
+	// expired after them can still return its tickets to the counters.
+	holdRecordTTL = seatLockTTL + time.Hour
+)
+
 type eventRepository struct{}
 
 func NewEventRepository() EventRepository {
@@ -30,32 +105,202 @@ func (r *eventRepository) CreateEvent(event *models.Event) error {
 	return database.DB.Create(event).Error
 }
 
+// withTiers preloads the tiers of events in display order.
+func withTiers() *gorm.DB {
+	return preloadTiers(database.DB)
+}
+
+func preloadTiers(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	})
+}
+
 func (r *eventRepository) GetAllEvents() ([]models.Event, error) {
 	var events []models.Event
-	err := database.DB.Find(&events).Error
+	err := withTiers().Find(&events).Error
+	return events, err
+}
+
+func (r *eventRepository) GetListedEvents() ([]models.Event, error) {
+	var events []models.Event
+	err := withTiers().Where("status IN ?", models.ListedEventStatuses).Order("date ASC").Find(&events).Error
 	return events, err
 }
 
 func (r *eventRepository) GetEventsByOrganizerID(organizerID uint) ([]models.Event, error) {
 	var events []models.Event
-	err := database.DB.Where("organizer_id = ?", organizerID).Find(&events).Error
+	err := withTiers().Where("organizer_id = ?", organizerID).Find(&events).Error
 	return events, err
 }
 
 func (r *eventRepository) GetEventByID(eventID uint) (*models.Event, error) {
 	var event models.Event
-	err := database.DB.First(&event, eventID).Error
+	err := withTiers().First(&event, eventID).Error
 	return &event, err
 }
 
+// UpdateEvent saves the event's own columns; tiers are changed through
+// AdjustTierAvailability.
 func (r *eventRepository) UpdateEvent(event *models.Event) error {
-	return database.DB.Save(event).Error
+	// The lifecycle columns only change through TransitionEvent, so a stale
+	// copy cannot undo a status change
+	return database.DB.Omit("Tiers", "Status", "CancelledAt", "CancellationReason").Save(event).Error
+}
+
+func (r *eventRepository) TransitionEvent(eventID uint, to models.EventStatus, updates map[string]interface{}, announce func(event *models.Event) ([]outbox.Message, error)) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent transitions are checked one after the other
+		var event models.Event
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, eventID).Error; err != nil {
+			return err
+		}
+		if !event.Status.CanTransition(to) {
+			return &models.TransitionError{EventID: eventID, From: event.Status, To: to}
+		}
+
+		messages, err := announce(&event)
+		if err != nil {
+			return err
+		}
+
+		values := map[string]interface{}{"status": to}
+		for column, value := range updates {
+			values[column] = value
+		}
+		if err := tx.Model(&models.Event{}).Where("id = ?", eventID).Updates(values).Error; err != nil {
+			return err
+		}
+		return tx.Create(&messages).Error
+	})
+}
+
+func (r *eventRepository) GetEventsWithoutTiers() ([]models.Event, error) {
+	var events []models.Event
+	err := database.DB.Where("NOT EXISTS (SELECT 1 FROM ticket_tiers WHERE ticket_tiers.event_id = events.id)").Find(&events).Error
+	return events, err
+}
+
+func (r *eventRepository) CreateTiers(tiers []models.TicketTier) error {
+	if len(tiers) == 0 {
+		return nil
+	}
+	return database.DB.Create(&tiers).Error
+}
+
+func (r *eventRepository) SetTierPurchaseLimits(eventID uint, limits map[string]int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for code, limit := range limits {
+			if err := tx.Model(&models.TicketTier{}).Where("event_id = ? AND code = ?", eventID, code).Update("max_per_user", limit).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *eventRepository) AdjustTierAvailability(eventID uint, code string, delta int) error {
+	return database.DB.Model(&models.TicketTier{}).
+		Where("event_id = ? AND code = ?", eventID, code).
+		Update("available", gorm.Expr("LEAST(GREATEST(available + ?, 0), capacity)", delta)).Error
+}
+
+func (r *eventRepository) SellTickets(eventID, bookingID uint, tiers map[string]int, seatIDs []string, seatsBooked int) (*models.Event, error) {
+	var event models.Event
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if bookingID != 0 {
+			sale := models.BookingSale{EventID: eventID, BookingID: bookingID}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sale)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errAlreadySold
+			}
+		}
+
+		// Lock the row so concurrent confirmations update the counts one
+		// after the other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Event{}, eventID).Error; err != nil {
+			return err
+		}
+		for code, count := range tiers {
+			if err := tx.Model(&models.TicketTier{}).
+				Where("event_id = ? AND code = ?", eventID, code).
+				Update("available", gorm.Expr("LEAST(GREATEST(available - ?, 0), capacity)", count)).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(seatIDs) > 0 {
+			sold := make([]models.SoldSeat, 0, len(seatIDs))
+			for _, code := range seatIDs {
+				sold = append(sold, models.SoldSeat{EventID: eventID, SeatCode: code, BookingID: bookingID})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sold).Error; err != nil {
+				return err
+			}
+		}
+
+		// Reload the tiers to mirror them into the legacy columns
+		if err := preloadTiers(tx).First(&event, eventID).Error; err != nil {
+			return err
+		}
+		event.AvailableSeats = max(event.AvailableSeats-seatsBooked, 0)
+		event.SyncLegacyClasses()
+		return tx.Omit("Tiers", "Status", "CancelledAt", "CancellationReason").Save(&event).Error
+	})
+	if err == errAlreadySold {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// errAlreadySold rolls back SellTickets for a booking sold before.
+var errAlreadySold = &models.Error{Message: "Booking already sold"}
+
+func (r *eventRepository) GetEventsWithoutVenue() ([]models.Event, error) {
+	var events []models.Event
+	err := database.DB.Where("venue_id IS NULL").Find(&events).Error
+	return events, err
+}
+
+func (r *eventRepository) GetSeatHolds(eventID uint, seatIDs []string) (map[string]models.SeatLock, error) {
+	holds := make(map[string]models.SeatLock)
+	if len(seatIDs) == 0 {
+		return holds, nil
+	}
+
+	keys := make([]string, len(seatIDs))
+	for i, seatID := range seatIDs {
+		keys[i] = seatKey(eventID, seatID)
+	}
+	values, err := database.RedisClient.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if state, ok := value.(string); ok {
+			holds[seatIDs[i]] = seatLock(state)
+		}
+	}
+	return holds, nil
 }
 
-func (r *eventRepository) UpdateRedisSeats(eventID uint, newAvailableSeats int) error {
+func (r *eventRepository) MarkSeatsSold(eventID uint, seatIDs []string) error {
+	if len(seatIDs) == 0 {
+		return nil
+	}
 	ctx := context.Background()
-	key := fmt.Sprintf("event:%d:seats", eventID)
-	return database.RedisClient.Set(ctx, key, newAvailableSeats, 0).Err()
+	pipe := database.RedisClient.Pipeline()
+	for _, seatID := range seatIDs {
+		pipe.Set(ctx, seatKey(eventID, seatID), "sold", 0)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 func (r *eventRepository) InitializeSeats(event *models.Event) error {
@@ -63,118 +308,370 @@ func (r *eventRepository) InitializeSeats(event *models.Event) error {
 	pipe := database.RedisClient.Pipeline()
 
 	// Total
-	keyTotal := fmt.Sprintf("event:%d:seats", event.ID)
-	pipe.Set(ctx, keyTotal, event.TotalSeats, 0)
+	pipe.Set(ctx, counterKey(event.ID, ""), event.TotalSeats, 0)
 
-	// Classes
-	pipe.Set(ctx, fmt.Sprintf("event:%d:seats:normal", event.ID), event.SeatsNormal, 0)
-	pipe.Set(ctx, fmt.Sprintf("event:%d:seats:vip", event.ID), event.SeatsVIP, 0)
-	pipe.Set(ctx, fmt.Sprintf("event:%d:seats:vvip", event.ID), event.SeatsVVIP, 0)
+	// One counter per tier
+	for _, tier := range event.Tiers {
+		pipe.Set(ctx, counterKey(event.ID, tier.Code), tier.Available, 0)
+	}
 
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
-func (r *eventRepository) LockSeats(eventID uint, count int, ticketClass string, seatIDs []string) (bool, error) {
+// counterKey returns the Redis counter of a tier, or the event's total
+// counter for the code "".
+func counterKey(eventID uint, code string) string {
+	if code == "" {
+		return fmt.Sprintf("event:%d:seats", eventID)
+	}
+	return fmt.Sprintf("event:%d:seats:%s", eventID, code)
+}
+
+// seatCounterKeys returns the counter a lock or unlock checks, followed by
+// the event's total counter when that is a different key, so both stay in
+// step.
+func seatCounterKeys(eventID uint, ticketClass string) []string {
+	if ticketClass == "" {
+		return []string{counterKey(eventID, "")}
+	}
+	return []string{counterKey(eventID, ticketClass), counterKey(eventID, "")}
+}
+
+func seatKey(eventID uint, seatID string) string {
+	return seatKeyPrefix(eventID) + seatID
+}
+
+func seatKeyPrefix(eventID uint) string {
+	return fmt.Sprintf("event:%d:seat:", eventID)
+}
+
+// holdKey is the hash recording a hold: "user_id", "booking_id",
+// "expires_at" (Unix seconds) and per tier "count:<code>" and the
+// comma-separated "seats:<code>".
+func holdKey(eventID uint, holdToken string) string {
+	return fmt.Sprintf("event:%d:hold:%s", eventID, holdToken)
+}
+
+// seatLock parses the value of a seat key: "sold", "locked:<hold token>",
+// or "locked" for locks taken before holds existed.
+func seatLock(value string) models.SeatLock {
+	if token, ok := strings.CutPrefix(value, models.SeatStatusLocked+":"); ok {
+		return models.SeatLock{Status: models.SeatStatusLocked, HoldToken: token}
+	}
+	return models.SeatLock{Status: value}
+}
+
+func (r *eventRepository) LockSeats(eventID, userID uint, holdToken string, count int, ticketClass string, seatIDs []string) (bool, error) {
+	keys := append(seatCounterKeys(eventID, ticketClass), holdKey(eventID, holdToken))
+
+	// KEYS: the counters to decrement, then the hold
+	// ARGV: count, ttl, hold ttl, token, user ID, tier, seat key prefix,
+	// expires at, seat IDs...
+	script := `
+		local hold = KEYS[#KEYS]
+		local count = tonumber(ARGV[1])
+		local token = ARGV[4]
+
+		-- Only the user who took a hold may add to it
+		local owner = redis.call("HGET", hold, "user_id")
+		if owner and owner ~= ARGV[5] then
+			return -1
+		end
+
+		-- Check if any seat is already locked
+		for i = 9, #ARGV do
+			if redis.call("EXISTS", ARGV[7] .. ARGV[i]) == 1 then
+				return 0 -- Fail: Seat locked
+			end
+		end
+
+		-- Check available count
+		local current = tonumber(redis.call("GET", KEYS[1]))
+		if current == nil then return 0 end
+		if current < count then return 0 end -- Fail: Not enough seats
+
+		-- Lock seats under the hold
+		for i = 9, #ARGV do
+			redis.call("SET", ARGV[7] .. ARGV[i], "locked:" .. token, "EX", ARGV[2])
+		end
+
+		-- Decrement count
+		for i = 1, #KEYS - 1 do
+			redis.call("DECRBY", KEYS[i], count)
+		end
+
+		-- Record what the hold holds
+		redis.call("HSET", hold, "user_id", ARGV[5], "expires_at", ARGV[8])
+		redis.call("HINCRBY", hold, "count:" .. ARGV[6], count)
+		if #ARGV >= 9 then
+			local seats = table.concat(ARGV, ",", 9)
+			local held = redis.call("HGET", hold, "seats:" .. ARGV[6])
+			if held and held ~= "" then seats = held .. "," .. seats end
+			redis.call("HSET", hold, "seats:" .. ARGV[6], seats)
+		end
+		redis.call("EXPIRE", hold, ARGV[3])
+
+		return 1
+	`
+
+	expiresAt := time.Now().Add(seatLockTTL).Unix()
+	args := []interface{}{count, int(seatLockTTL.Seconds()), int(holdRecordTTL.Seconds()), holdToken, userID, ticketClass, seatKeyPrefix(eventID), expiresAt}
+	for _, seatID := range seatIDs {
+		args = append(args, seatID)
+	}
+
+	result, err := database.RedisClient.Eval(context.Background(), script, keys, args...).Int()
+	if err != nil {
+		return false, err
+	}
+	if result == -1 {
+		return false, models.ErrHoldNotOwned
+	}
+	return result == 1, nil
+}
+
+func (r *eventRepository) UnlockSeats(eventID uint, count int, ticketClass string, seatIDs []string) error {
 	ctx := context.Background()
-	key := fmt.Sprintf("event:%d:seats", eventID)
-	if ticketClass != "" {
-		key = fmt.Sprintf("event:%d:seats:%s", eventID, ticketClass)
-	}
-
-	// If specific seats are provided, check and lock them
-	if len(seatIDs) > 0 {
-		// Lua script to check specific seat locks and available count
-		script := `
-			local countKey = KEYS[1]
-			local decrAmount = tonumber(ARGV[1])
-			local ttl = tonumber(ARGV[2])
-			
-			-- Check if any seat is already locked
-			for i = 3, #ARGV do
-				if redis.call("EXISTS", ARGV[i]) == 1 then
-					return 0 -- Fail: Seat locked
+	keys := seatCounterKeys(eventID, ticketClass)
+
+	// Also used without seats for count-only unlocks
+	script := `
+		local incrAmount = tonumber(ARGV[1])
+
+		-- Unlock seats
+		for i = 2, #ARGV do
+			redis.call("DEL", ARGV[i])
+		end
+
+		-- Increment count
+		for i = 1, #KEYS do
+			redis.call("INCRBY", KEYS[i], incrAmount)
+		end
+		return 1
+	`
+	args := []interface{}{count}
+	for _, seatID := range seatIDs {
+		args = append(args, seatKey(eventID, seatID))
+	}
+
+	return database.RedisClient.Eval(ctx, script, keys, args...).Err()
+}
+
+func (r *eventRepository) UnlockHold(eventID uint, holdToken string, userID uint, ticketClass string) (int, error) {
+	keys := append(seatCounterKeys(eventID, ticketClass), holdKey(eventID, holdToken))
+
+	// ARGV: token, user ID ("" for internal callers), tier, seat key prefix
+	script := `
+		local hold = KEYS[#KEYS]
+		local owner = redis.call("HGET", hold, "user_id")
+		if not owner then return -2 end
+		if ARGV[2] ~= "" and owner ~= ARGV[2] then return -1 end
+
+		local count = tonumber(redis.call("HGET", hold, "count:" .. ARGV[3])) or 0
+		local seats = redis.call("HGET", hold, "seats:" .. ARGV[3])
+
+		-- Only delete locks that have not lapsed and been taken by someone else
+		if seats then
+			for seat in string.gmatch(seats, "[^,]+") do
+				local key = ARGV[4] .. seat
+				if redis.call("GET", key) == "locked:" .. ARGV[1] then
+					redis.call("DEL", key)
 				end
 			end
+		end
 
-			-- Check available count
-			local current = tonumber(redis.call("GET", countKey))
-			if current == nil then return 0 end
-			if current < decrAmount then return 0 end -- Fail: Not enough seats
-
-			-- Lock seats
-			for i = 3, #ARGV do
-				redis.call("SET", ARGV[i], "locked", "EX", ttl)
+		if count > 0 then
+			for i = 1, #KEYS - 1 do
+				redis.call("INCRBY", KEYS[i], count)
 			end
+		end
+		redis.call("HDEL", hold, "count:" .. ARGV[3], "seats:" .. ARGV[3])
+		return count
+	`
+	owner := ""
+	if userID != 0 {
+		owner = strconv.FormatUint(uint64(userID), 10)
+	}
 
-			-- Decrement count
-			redis.call("DECRBY", countKey, decrAmount)
+	result, err := database.RedisClient.Eval(context.Background(), script, keys, holdToken, owner, ticketClass, seatKeyPrefix(eventID)).Int()
+	if err != nil {
+		return 0, err
+	}
+	switch result {
+	case -2:
+		return 0, models.ErrHoldNotFound
+	case -1:
+		return 0, models.ErrHoldNotOwned
+	}
+	return result, nil
+}
 
-			return 1
-		`
+func (r *eventRepository) GetHold(eventID uint, holdToken string) (*models.SeatHold, error) {
+	fields, err := database.RedisClient.HGetAll(context.Background(), holdKey(eventID, holdToken)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if fields["user_id"] == "" {
+		return nil, models.ErrHoldNotFound
+	}
 
-		// Prepare args: count, ttl, seatKey1, seatKey2...
-		args := []interface{}{count, 900} // 900s = 15 mins
-		for _, seatID := range seatIDs {
-			args = append(args, fmt.Sprintf("event:%d:seat:%s", eventID, seatID))
+	hold := &models.SeatHold{
+		Token:   holdToken,
+		EventID: eventID,
+		Counts:  make(map[string]int),
+		Seats:   make(map[string][]string),
+	}
+	for field, value := range fields {
+		switch {
+		case field == "user_id":
+			userID, _ := strconv.ParseUint(value, 10, 32)
+			hold.UserID = uint(userID)
+		case field == "booking_id":
+			bookingID, _ := strconv.ParseUint(value, 10, 32)
+			hold.BookingID = uint(bookingID)
+		case field == "expires_at":
+			expiresAt, _ := strconv.ParseInt(value, 10, 64)
+			hold.ExpiresAt = time.Unix(expiresAt, 0)
+		case strings.HasPrefix(field, "count:"):
+			count, _ := strconv.Atoi(value)
+			hold.Counts[strings.TrimPrefix(field, "count:")] = count
+		case strings.HasPrefix(field, "seats:") && value != "":
+			hold.Seats[strings.TrimPrefix(field, "seats:")] = strings.Split(value, ",")
 		}
+	}
+	return hold, nil
+}
 
-		result, err := database.RedisClient.Eval(ctx, script, []string{key}, args...).Int()
-		if err != nil {
-			return false, err
-		}
-		return result == 1, nil
+func (r *eventRepository) SetHoldBooking(eventID uint, holdToken string, bookingID uint) error {
+	script := `
+		if redis.call("EXISTS", KEYS[1]) == 0 then return 0 end
+		redis.call("HSET", KEYS[1], "booking_id", ARGV[1])
+		return 1
+	`
+	result, err := database.RedisClient.Eval(context.Background(), script, []string{holdKey(eventID, holdToken)}, bookingID).Int()
+	if err != nil {
+		return err
 	}
+	if result == 0 {
+		return models.ErrHoldNotFound
+	}
+	return nil
+}
 
-	// Fallback for count-only locking (old behavior)
+func (r *eventRepository) ConfirmHold(eventID uint, holdToken string, seatIDs []string) ([]string, error) {
+	// A seat may be sold if it is locked under the hold, its lock lapsed
+	// without anyone taking it, or it is already sold (a redelivered
+	// confirmation; the caller checks whom to). Tokenless locks were
+	// restored after Redis lost its data and cannot be told apart.
 	script := `
-		local current = tonumber(redis.call("get", KEYS[1]))
-		if current == nil then return 0 end
-		if current >= tonumber(ARGV[1]) then
-			redis.call("decrby", KEYS[1], ARGV[1])
-			return 1
-		else
-			return 0
+		local taken = {}
+		for i = 3, #ARGV do
+			local value = redis.call("GET", ARGV[2] .. ARGV[i])
+			if value and value ~= "sold" and value ~= "locked" and value ~= "locked:" .. ARGV[1] then
+				table.insert(taken, ARGV[i])
+			end
+		end
+		if #taken > 0 then return taken end
+
+		for i = 3, #ARGV do
+			redis.call("SET", ARGV[2] .. ARGV[i], "sold")
 		end
+		redis.call("DEL", KEYS[1])
+		return taken
 	`
+	args := []interface{}{holdToken, seatKeyPrefix(eventID)}
+	for _, seatID := range seatIDs {
+		args = append(args, seatID)
+	}
+	return database.RedisClient.Eval(context.Background(), script, []string{holdKey(eventID, holdToken)}, args...).StringSlice()
+}
 
-	result, err := database.RedisClient.Eval(ctx, script, []string{key}, count).Int()
+func (r *eventRepository) GetSeatCounters(eventID uint, codes []string) (map[string]int, error) {
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = counterKey(eventID, code)
+	}
+
+	counters := make(map[string]int)
+	if len(keys) == 0 {
+		return counters, nil
+	}
+	values, err := database.RedisClient.MGet(context.Background(), keys...).Result()
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	for i, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("counter %s holds %q", keys[i], str)
+		}
+		counters[codes[i]] = n
 	}
+	return counters, nil
+}
 
-	return result == 1, nil
+func (r *eventRepository) RestoreSeatCounters(eventID uint, counters map[string]int) (int, error) {
+	ctx := context.Background()
+	pipe := database.RedisClient.Pipeline()
+	var results []*redis.BoolCmd
+	for code, value := range counters {
+		results = append(results, pipe.SetNX(ctx, counterKey(eventID, code), value, 0))
+	}
+	return execRestore(ctx, pipe, results)
 }
 
-func (r *eventRepository) UnlockSeats(eventID uint, count int, ticketClass string, seatIDs []string) error {
+func (r *eventRepository) RestoreSeatHolds(eventID uint, sold, locked []string) (int, error) {
 	ctx := context.Background()
-	key := fmt.Sprintf("event:%d:seats", eventID)
-	if ticketClass != "" {
-		key = fmt.Sprintf("event:%d:seats:%s", eventID, ticketClass)
-	}
-
-	if len(seatIDs) > 0 {
-		script := `
-			local countKey = KEYS[1]
-			local incrAmount = tonumber(ARGV[1])
-			
-			-- Unlock seats
-			for i = 2, #ARGV do
-				redis.call("DEL", ARGV[i])
-			end
+	pipe := database.RedisClient.Pipeline()
+	var results []*redis.BoolCmd
+	for _, seatID := range sold {
+		results = append(results, pipe.SetNX(ctx, seatKey(eventID, seatID), models.SeatStatusSold, 0))
+	}
+	for _, seatID := range locked {
+		// The original expiry is unknown; booking-service expires the booking anyway
+		results = append(results, pipe.SetNX(ctx, seatKey(eventID, seatID), models.SeatStatusLocked, seatLockTTL))
+	}
+	return execRestore(ctx, pipe, results)
+}
 
-			-- Increment count
-			redis.call("INCRBY", countKey, incrAmount)
-			return 1
-		`
-		args := []interface{}{count}
-		for _, seatID := range seatIDs {
-			args = append(args, fmt.Sprintf("event:%d:seat:%s", eventID, seatID))
+func execRestore(ctx context.Context, pipe redis.Pipeliner, results []*redis.BoolCmd) (int, error) {
+	if len(results) == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, result := range results {
+		if result.Val() {
+			restored++
 		}
+	}
+	return restored, nil
+}
 
-		return database.RedisClient.Eval(ctx, script, []string{key}, args...).Err()
+func (r *eventRepository) RepairSeatCounter(eventID uint, code string, observed, expected int) (bool, error) {
+	script := `
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			redis.call("SET", KEYS[1], ARGV[2])
+			return 1
+		end
+		return 0
+	`
+	result, err := database.RedisClient.Eval(context.Background(), script, []string{counterKey(eventID, code)}, strconv.Itoa(observed), expected).Int()
+	if err != nil {
+		return false, err
 	}
+	return result == 1, nil
+}
 
-	// Fallback count-only unlock
-	return database.RedisClient.IncrBy(ctx, key, int64(count)).Err()
+func (r *eventRepository) SetTierAvailability(eventID uint, code string, available int) error {
+	return database.DB.Model(&models.TicketTier{}).
+		Where("event_id = ? AND code = ?", eventID, code).
+		Update("available", available).Error
 }
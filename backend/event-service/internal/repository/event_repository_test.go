@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+)
+
+func TestSeatLock(t *testing.T) {
+	tests := []struct {
+		value string
+		want  models.SeatLock
+	}{
+		{"sold", models.SeatLock{Status: models.SeatStatusSold}},
+		{"locked", models.SeatLock{Status: models.SeatStatusLocked}},
+		{"locked:3f2a9c0d1e4b5a6f", models.SeatLock{Status: models.SeatStatusLocked, HoldToken: "3f2a9c0d1e4b5a6f"}},
+	}
+	for _, tt := range tests {
+		if got := seatLock(tt.value); got != tt.want {
+			t.Errorf("seatLock(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
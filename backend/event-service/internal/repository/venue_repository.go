@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/database"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type VenueRepository interface {
+	// CreateVenue stores a venue with its sections, rows and seats.
+	CreateVenue(venue *models.Venue) error
+	// GetVenueByID returns the venue with its full layout in display order.
+	GetVenueByID(venueID uint) (*models.Venue, error)
+	GetVenuesByOrganizerID(organizerID uint) ([]models.Venue, error)
+	// GetSeatsByCodes resolves seat codes of a venue to their section and
+	// row. Codes that do not exist are left out.
+	GetSeatsByCodes(venueID uint, codes []string) ([]models.SeatInfo, error)
+	ReleaseSoldSeats(eventID uint, codes []string) error
+	GetSoldSeatCodes(eventID uint) ([]string, error)
+	// GetSeatBookings returns the booking each of the given seats was sold
+	// to; unsold seats are left out.
+	GetSeatBookings(eventID uint, codes []string) (map[string]uint, error)
+}
+
+type venueRepository struct{}
+
+func NewVenueRepository() VenueRepository {
+	return &venueRepository{}
+}
+
+func (r *venueRepository) CreateVenue(venue *models.Venue) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Sections").Create(venue).Error; err != nil {
+			return err
+		}
+
+		for i := range venue.Sections {
+			section := &venue.Sections[i]
+			section.VenueID = venue.ID
+			if err := tx.Omit("Rows").Create(section).Error; err != nil {
+				return err
+			}
+
+			for j := range section.Rows {
+				row := &section.Rows[j]
+				row.SectionID = section.ID
+				if err := tx.Omit("Seats").Create(row).Error; err != nil {
+					return err
+				}
+				if len(row.Seats) == 0 {
+					continue
+				}
+
+				for k := range row.Seats {
+					row.Seats[k].VenueID = venue.ID
+					row.Seats[k].SectionID = section.ID
+					row.Seats[k].RowID = row.ID
+				}
+				if err := tx.CreateInBatches(row.Seats, 500).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *venueRepository) GetVenueByID(venueID uint) (*models.Venue, error) {
+	var venue models.Venue
+	err := database.DB.
+		Preload("Sections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC, id ASC")
+		}).
+		Preload("Sections.Rows", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC, id ASC")
+		}).
+		Preload("Sections.Rows.Seats", func(db *gorm.DB) *gorm.DB {
+			return db.Order("number ASC")
+		}).
+		First(&venue, venueID).Error
+	return &venue, err
+}
+
+func (r *venueRepository) GetVenuesByOrganizerID(organizerID uint) ([]models.Venue, error) {
+	var venues []models.Venue
+	err := database.DB.Where("organizer_id = ? AND generated = ?", organizerID, false).Find(&venues).Error
+	return venues, err
+}
+
+func (r *venueRepository) GetSeatsByCodes(venueID uint, codes []string) ([]models.SeatInfo, error) {
+	var seats []models.SeatInfo
+	if len(codes) == 0 {
+		return seats, nil
+	}
+	err := database.DB.Table("seats").
+		Select("seats.code AS id, sections.name AS section, seat_rows.label AS row, seat_rows.position AS row_position, seats.number AS number, sections.ticket_class AS ticket_class").
+		Joins("JOIN sections ON sections.id = seats.section_id").
+		Joins("JOIN seat_rows ON seat_rows.id = seats.row_id").
+		Where("seats.venue_id = ? AND seats.code IN ?", venueID, codes).
+		Scan(&seats).Error
+	return seats, err
+}
+
+func (r *venueRepository) ReleaseSoldSeats(eventID uint, codes []string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	return database.DB.Where("event_id = ? AND seat_code IN ?", eventID, codes).Delete(&models.SoldSeat{}).Error
+}
+
+func (r *venueRepository) GetSoldSeatCodes(eventID uint) ([]string, error) {
+	var codes []string
+	err := database.DB.Model(&models.SoldSeat{}).Where("event_id = ?", eventID).Pluck("seat_code", &codes).Error
+	return codes, err
+}
+
+func (r *venueRepository) GetSeatBookings(eventID uint, codes []string) (map[string]uint, error) {
+	bookings := make(map[string]uint)
+	if len(codes) == 0 {
+		return bookings, nil
+	}
+
+	var sold []models.SoldSeat
+	if err := database.DB.Where("event_id = ? AND seat_code IN ?", eventID, codes).Find(&sold).Error; err != nil {
+		return nil, err
+	}
+	for _, seat := range sold {
+		bookings[seat.SeatCode] = seat.BookingID
+	}
+	return bookings, nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/database"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// WaitingRoomRepository keeps the waiting rooms of events in Redis.
+type WaitingRoomRepository interface {
+	// Join puts a user at the back of an event's queue under token and
+	// returns it, or returns the token of the ticket the user already has,
+	// waiting or admitted. ratePerMinute is kept to estimate waits.
+	Join(eventID, userID uint, token string, ratePerMinute int) (string, error)
+	// GetTicket returns a ticket with its position in the queue, or
+	// ErrQueueTicketNotFound once it expired.
+	GetTicket(eventID uint, token string) (*models.QueueTicket, error)
+	// Admit lets the users at the front of the queue in at ratePerMinute,
+	// saving up at most burst admissions while nobody waits, and gives
+	// each the purchase window. It returns how many were admitted.
+	Admit(eventID uint, ratePerMinute int, burst float64, now time.Time, window time.Duration) (int, error)
+}
+
+// queueTicketTTL is how long a user keeps a place in a queue.
+const queueTicketTTL = 6 * time.Hour
+
+type waitingRoomRepository struct{}
+
+func NewWaitingRoomRepository() WaitingRoomRepository {
+	return &waitingRoomRepository{}
+}
+
+// queueKey is the sorted set of waiting tokens, scored by arrival.
+func queueKey(eventID uint) string {
+	return fmt.Sprintf("event:%d:queue", eventID)
+}
+
+// queueStateKey is the hash of a queue's "seq" arrival counter, its
+// admission "rate" per minute and the "credit" of admissions saved up
+// at "updated_at" (Unix milliseconds).
+func queueStateKey(eventID uint) string {
+	return fmt.Sprintf("event:%d:queue:state", eventID)
+}
+
+func queueUserKey(eventID, userID uint) string {
+	return fmt.Sprintf("event:%d:queue:user:%d", eventID, userID)
+}
+
+// queueTicketKeyPrefix prefixes the hash of a ticket: "user_id" and, once
+// admitted, "admitted_until" (Unix seconds). Admitted tickets expire with
+// their purchase window.
+func queueTicketKeyPrefix(eventID uint) string {
+	return fmt.Sprintf("event:%d:queue:ticket:", eventID)
+}
+
+func (r *waitingRoomRepository) Join(eventID, userID uint, token string, ratePerMinute int) (string, error) {
+	keys := []string{queueUserKey(eventID, userID), queueKey(eventID), queueStateKey(eventID)}
+
+	// KEYS: the user's ticket, the queue, its state
+	// ARGV: token, user ID, ticket key prefix, ticket ttl, rate
+	script := `
+		local existing = redis.call("GET", KEYS[1])
+		if existing and redis.call("EXISTS", ARGV[3] .. existing) == 1 then
+			return existing
+		end
+
+		local ticket = ARGV[3] .. ARGV[1]
+		local seq = redis.call("HINCRBY", KEYS[3], "seq", 1)
+		redis.call("ZADD", KEYS[2], seq, ARGV[1])
+		redis.call("HSET", ticket, "user_id", ARGV[2])
+		redis.call("EXPIRE", ticket, ARGV[4])
+		redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[4])
+
+		redis.call("HSET", KEYS[3], "rate", ARGV[5])
+		redis.call("EXPIRE", KEYS[2], ARGV[4])
+		redis.call("EXPIRE", KEYS[3], ARGV[4])
+		return ARGV[1]
+	`
+	args := []interface{}{token, userID, queueTicketKeyPrefix(eventID), int(queueTicketTTL.Seconds()), ratePerMinute}
+	return database.RedisClient.Eval(context.Background(), script, keys, args...).Text()
+}
+
+func (r *waitingRoomRepository) GetTicket(eventID uint, token string) (*models.QueueTicket, error) {
+	ctx := context.Background()
+	fields, err := database.RedisClient.HGetAll(ctx, queueTicketKeyPrefix(eventID)+token).Result()
+	if err != nil {
+		return nil, err
+	}
+	if fields["user_id"] == "" {
+		return nil, models.ErrQueueTicketNotFound
+	}
+
+	userID, _ := strconv.ParseUint(fields["user_id"], 10, 32)
+	ticket := &models.QueueTicket{Token: token, EventID: eventID, UserID: uint(userID)}
+	if value := fields["admitted_until"]; value != "" {
+		until, _ := strconv.ParseInt(value, 10, 64)
+		admittedUntil := time.Unix(until, 0)
+		ticket.Status = models.QueueStatusAdmitted
+		ticket.AdmittedUntil = &admittedUntil
+		return ticket, nil
+	}
+
+	rank, err := database.RedisClient.ZRank(ctx, queueKey(eventID), token).Result()
+	if err == redis.Nil {
+		return nil, models.ErrQueueTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ticket.Status = models.QueueStatusWaiting
+	ticket.Position = rank + 1
+
+	rate, err := database.RedisClient.HGet(ctx, queueStateKey(eventID), "rate").Int64()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if rate > 0 {
+		ticket.EstimatedWait = ticket.Position * 60 / rate
+	}
+	return ticket, nil
+}
+
+func (r *waitingRoomRepository) Admit(eventID uint, ratePerMinute int, burst float64, now time.Time, window time.Duration) (int, error) {
+	keys := []string{queueKey(eventID), queueStateKey(eventID)}
+
+	// A token bucket shared by every instance of the service: credit
+	// accrues at the rate up to burst and each admission spends one.
+	// Tickets that expired while waiting are dropped without spending.
+	// KEYS: the queue, its state
+	// ARGV: now (ms), rate, burst, admitted until (s), window (s), ticket key prefix
+	script := `
+		local now = tonumber(ARGV[1])
+		local rate = tonumber(ARGV[2])
+		local burst = tonumber(ARGV[3])
+		local credit = tonumber(redis.call("HGET", KEYS[2], "credit")) or burst
+		local last = tonumber(redis.call("HGET", KEYS[2], "updated_at")) or now
+		credit = math.min(burst, credit + (now - last) * rate / 60000)
+
+		local admitted = 0
+		while credit >= 1 do
+			local popped = redis.call("ZPOPMIN", KEYS[1])
+			if #popped == 0 then break end
+			local ticket = ARGV[6] .. popped[1]
+			if redis.call("EXISTS", ticket) == 1 then
+				redis.call("HSET", ticket, "admitted_until", ARGV[4])
+				redis.call("EXPIRE", ticket, ARGV[5])
+				credit = credit - 1
+				admitted = admitted + 1
+			end
+		end
+
+		redis.call("HSET", KEYS[2], "credit", tostring(credit), "updated_at", ARGV[1], "rate", ARGV[2])
+		return admitted
+	`
+	args := []interface{}{now.UnixMilli(), ratePerMinute, burst, now.Add(window).Unix(), int(window.Seconds()), queueTicketKeyPrefix(eventID)}
+	return database.RedisClient.Eval(context.Background(), script, keys, args...).Int()
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ticketInventory is the Booking Service's count of tickets of one tier of
+// an event held by pending bookings and sold to confirmed ones.
+type ticketInventory struct {
+	EventID      uint     `json:"event_id"`
+	TicketClass  string   `json:"ticket_class"`
+	Pending      int      `json:"pending"`
+	Confirmed    int      `json:"confirmed"`
+	PendingSeats []string `json:"pending_seats"`
+}
+
+func getBookingServiceURL() string {
+	bookingServiceURL := os.Getenv("BOOKING_SERVICE_URL")
+	if bookingServiceURL == "" {
+		bookingServiceURL = "http://localhost:3002"
+	}
+	return bookingServiceURL
+}
+
+// fetchTicketInventory returns the ticket inventory of every event, keyed
+// by event ID and tier code.
+func fetchTicketInventory() (map[uint]map[string]ticketInventory, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/bookings/inventory", getBookingServiceURL()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_SERVICE_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("booking service returned status %d for ticket inventory", resp.StatusCode)
+	}
+
+	var body struct {
+		Inventory []ticketInventory `json:"inventory"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	inventory := make(map[uint]map[string]ticketInventory)
+	for _, line := range body.Inventory {
+		if inventory[line.EventID] == nil {
+			inventory[line.EventID] = make(map[string]ticketInventory)
+		}
+		inventory[line.EventID][line.TicketClass] = line
+	}
+	return inventory, nil
+}
@@ -2,81 +2,212 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/messaging"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
 	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+	"gorm.io/gorm"
 )
 
 type EventService interface {
 	CreateEvent(event *models.Event) error
-	LockSeats(eventID uint, count int, ticketClass string, seatIDs []string) (bool, error)
+	// LockSeats holds tickets of one tier for userID under holdToken, or a
+	// new token if it is empty, and returns the token. Events with a
+	// waiting room need the user's admission token.
+	LockSeats(eventID, userID uint, holdToken, admissionToken string, count int, ticketClass string, seatIDs []string) (string, bool, error)
+	// UnlockSeats returns tickets whatever holds them, for internal callers
+	// unlocking holds taken before hold tokens existed.
 	UnlockSeats(eventID uint, count int, ticketClass string, seatIDs []string) error
+	// UnlockHold returns the tickets of one tier of a hold. A requesterID
+	// of 0 is an internal caller and may unlock any hold.
+	UnlockHold(eventID uint, holdToken string, requesterID uint, ticketClass string) error
+	AttachHoldBooking(eventID uint, holdToken string, requesterID, bookingID uint) error
+	GetSeatHolder(eventID uint, seatID string, requesterID uint, privileged bool) (*models.SeatHolder, error)
 	GetAllEvents() ([]models.Event, error)
 	GetEventsByOrganizer(organizerID uint) ([]models.Event, error)
 	GetEventByID(eventID uint) (*models.Event, error)
 	UpdateEvent(eventID uint, organizerID uint, updates map[string]interface{}) (*models.Event, error)
-	UpdateEventSeats(eventID uint, seatsBooked int, seatsJSON string) error
+	UpdateEventSeats(eventID, bookingID, userID uint, seatsBooked int, seatsJSON string, ticketClasses map[string]int, holdToken string) error
+	ReleaseSeats(eventID uint, count int, ticketClass string, seatIDs []string) error
+	GetSeatMap(eventID uint) (*models.SeatMap, error)
+	ResolveSeats(eventID uint, seatIDs []string) ([]models.SeatInfo, error)
+	// Lifecycle
+	PublishEvent(eventID, organizerID uint) (*models.Event, error)
+	UnpublishEvent(eventID, organizerID uint) (*models.Event, error)
+	CancelEvent(eventID, requesterID uint, isAdmin bool, reason string) (*models.Event, error)
+	AdvanceEventLifecycles() error
+	// Waiting room
+	JoinWaitingRoom(eventID, userID uint) (*models.QueueTicket, error)
+	GetQueueTicket(eventID uint, token string) (*models.QueueTicket, error)
+	AdmitFromWaitingRooms() error
+	// MigrateLegacyTiers creates tiers from the legacy price and seat
+	// columns for every event that has none.
+	MigrateLegacyTiers() error
+	// EnsureSeatLayouts generates a venue for every event that has none.
+	EnsureSeatLayouts() error
 }
 
 type eventService struct {
-	repo repository.EventRepository
+	repo   repository.EventRepository
+	venues repository.VenueRepository
+	rooms  repository.WaitingRoomRepository
 }
 
-func NewEventService(repo repository.EventRepository) EventService {
-	return &eventService{repo: repo}
+func NewEventService(repo repository.EventRepository, venues repository.VenueRepository, rooms repository.WaitingRoomRepository) EventService {
+	return &eventService{repo: repo, venues: venues, rooms: rooms}
 }
 
-func (s *eventService) UpdateEventSeats(eventID uint, seatsBooked int, seatsJSON string) error {
+// UpdateEventSeats records the tickets of a confirmed booking as sold.
+// ticketClasses holds the number of tickets per tier; producers older than
+// booking_confirmed 1.1 leave it empty and the tiers are derived from the
+// seats instead. Bookings made under a hold (booking_confirmed 1.2) only
+// get seats still locked under it or already sold to them. Each booking is
+// sold once, so a redelivered confirmation leaves the counts alone.
+func (s *eventService) UpdateEventSeats(eventID, bookingID, userID uint, seatsBooked int, seatsJSON string, ticketClasses map[string]int, holdToken string) error {
 	event, err := s.repo.GetEventByID(eventID)
 	if err != nil {
 		return err
 	}
 
-	// Update total available seats
-	event.AvailableSeats -= seatsBooked
-	if event.AvailableSeats < 0 {
-		event.AvailableSeats = 0
-	}
-
 	// Parse seats to update specific ticket class availability
 	var seats []struct {
 		ID string `json:"id"`
 	}
+	var seatIDs []string
 	if err := json.Unmarshal([]byte(seatsJSON), &seats); err == nil {
 		for _, seat := range seats {
-			if strings.HasPrefix(seat.ID, "vvip") {
-				event.AvailableVVIP--
-				if event.AvailableVVIP < 0 {
-					event.AvailableVVIP = 0
-				}
-			} else if strings.HasPrefix(seat.ID, "vip") {
-				event.AvailableVIP--
-				if event.AvailableVIP < 0 {
-					event.AvailableVIP = 0
-				}
-			} else {
-				// Default to normal if not vip/vvip or explicitly normal
-				event.AvailableNormal--
-				if event.AvailableNormal < 0 {
-					event.AvailableNormal = 0
-				}
-			}
+			seatIDs = append(seatIDs, seat.ID)
+		}
+	}
+
+	if holdToken != "" {
+		if err := s.confirmHold(eventID, bookingID, userID, holdToken, seatIDs); err != nil {
+			return err
+		}
+	}
+
+	if len(ticketClasses) == 0 {
+		ticketClasses, err = s.seatClasses(event, seatIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Redis is updated first: confirming a hold is repeatable, while the
+	// sale is recorded once per booking
+	if holdToken == "" {
+		if err := s.repo.MarkSeatsSold(eventID, seatIDs); err != nil {
+			return err
+		}
+	}
+
+	event, err = s.repo.SellTickets(eventID, bookingID, ticketClasses, seatIDs, seatsBooked)
+	if err != nil {
+		return err
+	}
+	if event == nil {
+		fmt.Printf("Booking %d of event %d was already sold\n", bookingID, eventID)
+		return nil
+	}
+	s.syncSoldOut(event)
+	return nil
+}
+
+// seatClasses counts seats per tier using the event's venue layout.
+func (s *eventService) seatClasses(event *models.Event, seatIDs []string) (map[string]int, error) {
+	classes := make(map[string]string)
+	if event.VenueID != nil && len(seatIDs) > 0 {
+		infos, err := s.venues.GetSeatsByCodes(*event.VenueID, seatIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			classes[info.ID] = info.TicketClass
 		}
 	}
 
-	return s.repo.UpdateEvent(event)
+	counts := make(map[string]int)
+	for _, seatID := range seatIDs {
+		class, ok := classes[seatID]
+		if !ok {
+			// Seats booked before the event had a layout
+			class = legacyTicketClass(seatID)
+		}
+		counts[class]++
+	}
+	return counts, nil
 }
 
 func (s *eventService) GetEventByID(eventID uint) (*models.Event, error) {
 	return s.repo.GetEventByID(eventID)
 }
 
+// CreateEvent stores an event with its tiers and seat inventory. An event
+// on an existing venue takes the capacity of each seated tier from the
+// venue's layout; tiers without a section are sold as unseated tickets.
+// Otherwise a layout is generated with one section per tier.
 func (s *eventService) CreateEvent(event *models.Event) error {
+	if err := normalizeTiers(event.Tiers); err != nil {
+		return err
+	}
+	if event.SalesStart != nil && event.SalesEnd != nil && !event.SalesEnd.After(*event.SalesStart) {
+		return models.ErrInvalidSalesWindow
+	}
+
+	var venue *models.Venue
+	if event.VenueID != nil {
+		var err error
+		venue, err = s.venues.GetVenueByID(*event.VenueID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrVenueNotFound
+			}
+			return err
+		}
+		if venue.OrganizerID != event.OrganizerID || venue.Generated {
+			return models.ErrUnauthorized
+		}
+
+		for class, count := range seatCounts(venue) {
+			tier := event.Tier(class)
+			if tier == nil {
+				return models.ErrTierMismatch
+			}
+			tier.Capacity = count
+		}
+	} else {
+		capacity := 0
+		for _, tier := range event.Tiers {
+			capacity += tier.Capacity
+		}
+		if capacity == 0 {
+			return models.ErrInvalidTier
+		}
+
+		venue = generateVenue(event)
+		if err := s.venues.CreateVenue(venue); err != nil {
+			return err
+		}
+		event.VenueID = &venue.ID
+	}
+
+	event.TotalSeats = 0
+	for i := range event.Tiers {
+		event.Tiers[i].Available = event.Tiers[i].Capacity
+		event.TotalSeats += event.Tiers[i].Capacity
+	}
+	event.SyncLegacyClasses()
+
 	// Set available seats to total seats initially
 	event.AvailableSeats = event.TotalSeats
 
+	// Events are drafts until the organizer publishes them
+	event.Status = models.EventStatusDraft
+
 	if err := s.repo.CreateEvent(event); err != nil {
 		return err
 	}
@@ -92,8 +223,9 @@ func (s *eventService) CreateEvent(event *models.Event) error {
 	return nil
 }
 
+// GetAllEvents returns the events listed to the public.
 func (s *eventService) GetAllEvents() ([]models.Event, error) {
-	return s.repo.GetAllEvents()
+	return s.repo.GetListedEvents()
 }
 
 func (s *eventService) GetEventsByOrganizer(organizerID uint) ([]models.Event, error) {
@@ -109,25 +241,13 @@ func (s *eventService) UpdateEvent(eventID uint, organizerID uint, updates map[s
 	if event.OrganizerID != organizerID {
 		return nil, models.ErrUnauthorized
 	}
+	if event.Status == models.EventStatusCancelled || event.Status == models.EventStatusCompleted {
+		return nil, models.ErrEventClosed
+	}
 
-	// Handle seat updates
-	if newTotalSeats, ok := updates["total_seats"].(float64); ok {
-		newTotal := int(newTotalSeats)
-		bookedSeats := event.TotalSeats - event.AvailableSeats
-
-		if newTotal < bookedSeats {
-			return nil, models.ErrInvalidSeatCount
-		}
-
-		// Update available seats
-		diff := newTotal - event.TotalSeats
-		event.TotalSeats = newTotal
-		event.AvailableSeats += diff
-
-		// Update Redis
-		if err := s.repo.UpdateRedisSeats(event.ID, event.AvailableSeats); err != nil {
-			return nil, err
-		}
+	// Capacity comes from the tiers and the venue layout
+	if _, ok := updates["total_seats"]; ok {
+		return nil, models.ErrSeatLayoutFixed
 	}
 
 	// Update other fields
@@ -140,19 +260,230 @@ func (s *eventService) UpdateEvent(eventID uint, organizerID uint, updates map[s
 	if loc, ok := updates["location"].(string); ok {
 		event.Location = loc
 	}
+	if hours, ok := updates["cancellation_deadline_hours"].(float64); ok {
+		if hours < 0 {
+			return nil, models.ErrInvalidPolicy
+		}
+		event.CancellationDeadlineHours = int(hours)
+	}
+	if pct, ok := updates["refund_percentage"].(float64); ok {
+		if pct < 0 || pct > 100 {
+			return nil, models.ErrInvalidPolicy
+		}
+		event.RefundPercentage = pct
+	}
+	if limit, ok := updates["max_tickets_per_user"].(float64); ok {
+		if limit < 0 {
+			return nil, models.ErrInvalidPurchaseLimit
+		}
+		event.MaxTicketsPerUser = int(limit)
+	}
+	// Per-user limits of tiers, by tier code
+	tierLimits := make(map[string]int)
+	if limits, ok := updates["tier_max_per_user"].(map[string]interface{}); ok {
+		for code, value := range limits {
+			tier := event.Tier(code)
+			if tier == nil {
+				return nil, models.ErrUnknownTier
+			}
+			limit, _ := value.(float64)
+			if limit != float64(int(limit)) || !validPurchaseLimit(tier, int(limit)) {
+				return nil, models.ErrInvalidPurchaseLimit
+			}
+			tierLimits[code] = int(limit)
+		}
+	}
+	if enabled, ok := updates["waiting_room"].(bool); ok {
+		event.WaitingRoom = enabled
+	}
+	if rate, ok := updates["admission_rate"].(float64); ok {
+		if rate < 0 {
+			return nil, models.ErrInvalidWaitingRoom
+		}
+		event.AdmissionRate = int(rate)
+	}
+	if minutes, ok := updates["purchase_window_minutes"].(float64); ok {
+		if minutes < 0 {
+			return nil, models.ErrInvalidWaitingRoom
+		}
+		event.PurchaseWindowMinutes = int(minutes)
+	}
+	// The sale window; null opens an end
+	for column, field := range map[string]**time.Time{"sales_start": &event.SalesStart, "sales_end": &event.SalesEnd} {
+		value, ok := updates[column]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			*field = nil
+			continue
+		}
+		text, _ := value.(string)
+		at, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, models.ErrInvalidSalesWindow
+		}
+		*field = &at
+	}
+	if event.SalesStart != nil && event.SalesEnd != nil && !event.SalesEnd.After(*event.SalesStart) {
+		return nil, models.ErrInvalidSalesWindow
+	}
 	// Add date update if needed
 
 	if err := s.repo.UpdateEvent(event); err != nil {
 		return nil, err
 	}
+	if len(tierLimits) > 0 {
+		if err := s.repo.SetTierPurchaseLimits(eventID, tierLimits); err != nil {
+			return nil, err
+		}
+		for code, limit := range tierLimits {
+			event.Tier(code).MaxPerUser = limit
+		}
+	}
 
 	return event, nil
 }
 
-func (s *eventService) LockSeats(eventID uint, count int, ticketClass string, seatIDs []string) (bool, error) {
-	return s.repo.LockSeats(eventID, count, ticketClass, seatIDs)
+// LockSeats holds tickets of one tier for a pending booking. The event and
+// the tier must be on sale and count within the tier's per-order limits,
+// and named seats must exist at the event's venue and belong to the tier.
+// Events with a waiting room only sell to users it admitted.
+func (s *eventService) LockSeats(eventID, userID uint, holdToken, admissionToken string, count int, ticketClass string, seatIDs []string) (string, bool, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return "", false, err
+	}
+	if event.Status != models.EventStatusOnSale {
+		return "", false, models.ErrEventNotOnSale
+	}
+	// The status follows the sale window within a minute; the window itself is exact
+	now := time.Now()
+	if err := event.CheckSalesWindow(now); err != nil {
+		return "", false, err
+	}
+	if err := s.checkAdmission(event, userID, admissionToken, now); err != nil {
+		return "", false, err
+	}
+	tier := event.Tier(ticketClass)
+	if tier == nil {
+		return "", false, models.ErrUnknownTier
+	}
+	if err := tier.CheckOrder(count, now); err != nil {
+		return "", false, err
+	}
+
+	if len(seatIDs) > 0 {
+		seats, err := s.ResolveSeats(eventID, seatIDs)
+		if err != nil {
+			return "", false, err
+		}
+		for _, seat := range seats {
+			if seat.TicketClass != ticketClass {
+				return "", false, models.ErrSeatClassMismatch
+			}
+		}
+	}
+
+	if holdToken == "" {
+		holdToken, err = newHoldToken()
+		if err != nil {
+			return "", false, err
+		}
+	} else if !holdTokenPattern.MatchString(holdToken) {
+		return "", false, models.ErrInvalidHoldToken
+	}
+
+	locked, err := s.repo.LockSeats(eventID, userID, holdToken, count, ticketClass, seatIDs)
+	if err != nil {
+		return "", false, err
+	}
+	return holdToken, locked, nil
 }
 
 func (s *eventService) UnlockSeats(eventID uint, count int, ticketClass string, seatIDs []string) error {
 	return s.repo.UnlockSeats(eventID, count, ticketClass, seatIDs)
 }
+
+func (s *eventService) ReleaseSeats(eventID uint, count int, ticketClass string, seatIDs []string) error {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UnlockSeats(eventID, count, ticketClass, seatIDs); err != nil {
+		return err
+	}
+	if err := s.venues.ReleaseSoldSeats(eventID, seatIDs); err != nil {
+		return err
+	}
+
+	if err := s.repo.AdjustTierAvailability(eventID, ticketClass, count); err != nil {
+		return err
+	}
+
+	// Reload the tiers to mirror them into the legacy columns
+	event, err = s.repo.GetEventByID(eventID)
+	if err != nil {
+		return err
+	}
+	event.AvailableSeats = min(event.AvailableSeats+count, event.TotalSeats)
+	event.SyncLegacyClasses()
+	if err := s.repo.UpdateEvent(event); err != nil {
+		return err
+	}
+	s.syncSoldOut(event)
+	return nil
+}
+
+// legacyTicketClass derives the ticket class from the prefix of a seat ID
+// that is not part of the event's layout.
+func legacyTicketClass(seatID string) string {
+	if strings.HasPrefix(seatID, "vvip") {
+		return models.TicketClassVVIP
+	}
+	if strings.HasPrefix(seatID, "vip") {
+		return models.TicketClassVIP
+	}
+	return models.TicketClassNormal
+}
+
+func (s *eventService) MigrateLegacyTiers() error {
+	events, err := s.repo.GetEventsWithoutTiers()
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		tiers := events[i].LegacyTiers()
+		if err := s.repo.CreateTiers(tiers); err != nil {
+			return fmt.Errorf("creating tiers for event %d: %w", events[i].ID, err)
+		}
+		fmt.Printf("Migrated %d legacy ticket classes of event %d to tiers\n", len(tiers), events[i].ID)
+	}
+	return nil
+}
+
+func (s *eventService) EnsureSeatLayouts() error {
+	events, err := s.repo.GetEventsWithoutVenue()
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		event, err := s.repo.GetEventByID(events[i].ID)
+		if err != nil {
+			return err
+		}
+		venue := generateVenue(event)
+		if err := s.venues.CreateVenue(venue); err != nil {
+			return fmt.Errorf("generating seat layout for event %d: %w", event.ID, err)
+		}
+		event.VenueID = &venue.ID
+		if err := s.repo.UpdateEvent(event); err != nil {
+			return fmt.Errorf("attaching seat layout to event %d: %w", event.ID, err)
+		}
+		fmt.Printf("Generated seat layout for event %d (venue %d)\n", event.ID, venue.ID)
+	}
+	return nil
+}
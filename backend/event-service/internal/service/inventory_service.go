@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Seat inventory lives in three places that can drift apart: the tier rows
+// (sold tickets, from booking_confirmed and releases), the event row's
+// available_seats, and the Redis counters that seat locks decrement. The
+// Booking Service's pending and confirmed bookings are the reference:
+//
+//	tier.available       = capacity - confirmed
+//	event.available      = sum of tier.available
+//	event:<id>:seats:<t> = tier.available - pending
+//	event:<id>:seats     = sum of the tier counters
+
+// Stores reported in the drift metric.
+const (
+	storeRedis    = "redis"
+	storeDatabase = "database"
+	storeEvent    = "event"
+	storeRebuild  = "rebuild"
+)
+
+var (
+	seatInventoryDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "seat_inventory_drift",
+			Help: "Observed minus expected available seats per event, tier and store at the last reconciliation",
+		},
+		[]string{"event_id", "tier", "store"},
+	)
+
+	seatInventoryRepairs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "seat_inventory_repairs_total",
+			Help: "Seat counters repaired by the reconciler, or Redis keys restored, per store",
+		},
+		[]string{"store"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(seatInventoryDrift)
+	prometheus.MustRegister(seatInventoryRepairs)
+}
+
+type InventoryService interface {
+	// RebuildSeatInventory recreates missing Redis counters and seat keys
+	// from Postgres and the Booking Service's pending bookings, e.g. after
+	// Redis lost its data. Existing keys are left alone.
+	RebuildSeatInventory() error
+	// ReconcileSeatInventory compares every store against the Booking
+	// Service, reports the drift and repairs drift that persisted since the
+	// previous run.
+	ReconcileSeatInventory() error
+}
+
+type observedDrift struct {
+	observed, expected int
+}
+
+type inventoryService struct {
+	repo   repository.EventRepository
+	venues repository.VenueRepository
+
+	// Drift seen by the previous reconciliation, by event, tier and store
+	lastDrift map[string]observedDrift
+}
+
+func NewInventoryService(repo repository.EventRepository, venues repository.VenueRepository) InventoryService {
+	return &inventoryService{repo: repo, venues: venues, lastDrift: make(map[string]observedDrift)}
+}
+
+func (s *inventoryService) RebuildSeatInventory() error {
+	events, err := s.repo.GetAllEvents()
+	if err != nil {
+		return err
+	}
+
+	inventory, err := fetchTicketInventory()
+	if err != nil {
+		// Better counters without pending holds than none; the reconciler
+		// corrects them once the Booking Service answers
+		fmt.Printf("Rebuilding seat inventory without pending bookings: %v\n", err)
+		inventory = nil
+	}
+
+	restored := 0
+	for i := range events {
+		event := &events[i]
+		holds := inventory[event.ID]
+
+		counters := map[string]int{"": 0}
+		var locked []string
+		for _, tier := range event.Tiers {
+			counter := max(tier.Available-holds[tier.Code].Pending, 0)
+			counters[tier.Code] = counter
+			counters[""] += counter
+			locked = append(locked, holds[tier.Code].PendingSeats...)
+		}
+
+		n, err := s.repo.RestoreSeatCounters(event.ID, counters)
+		if err != nil {
+			return fmt.Errorf("restoring seat counters of event %d: %w", event.ID, err)
+		}
+		restored += n
+
+		sold, err := s.venues.GetSoldSeatCodes(event.ID)
+		if err != nil {
+			return err
+		}
+		n, err = s.repo.RestoreSeatHolds(event.ID, sold, locked)
+		if err != nil {
+			return fmt.Errorf("restoring seat holds of event %d: %w", event.ID, err)
+		}
+		restored += n
+	}
+
+	if restored > 0 {
+		seatInventoryRepairs.WithLabelValues(storeRebuild).Add(float64(restored))
+		fmt.Printf("Restored %d missing seat inventory keys in Redis\n", restored)
+	}
+	return nil
+}
+
+func (s *inventoryService) ReconcileSeatInventory() error {
+	events, err := s.repo.GetAllEvents()
+	if err != nil {
+		return err
+	}
+	inventory, err := fetchTicketInventory()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]observedDrift)
+	for i := range events {
+		if err := s.reconcileEvent(&events[i], inventory[events[i].ID], seen); err != nil {
+			fmt.Printf("Failed to reconcile seat inventory of event %d: %v\n", events[i].ID, err)
+		}
+	}
+	s.lastDrift = seen
+	return nil
+}
+
+func (s *inventoryService) reconcileEvent(event *models.Event, holds map[string]ticketInventory, seen map[string]observedDrift) error {
+	// Tier rows against confirmed bookings
+	available := 0
+	for i := range event.Tiers {
+		tier := &event.Tiers[i]
+		expected := max(tier.Capacity-holds[tier.Code].Confirmed, 0)
+		if s.drifted(seen, event.ID, tier.Code, storeDatabase, tier.Available, expected) {
+			if err := s.repo.SetTierAvailability(event.ID, tier.Code, expected); err != nil {
+				return err
+			}
+			fmt.Printf("Repaired available seats of event %d tier %s: %d -> %d\n", event.ID, tier.Code, tier.Available, expected)
+			seatInventoryRepairs.WithLabelValues(storeDatabase).Inc()
+			tier.Available = expected
+		}
+		available += tier.Available
+	}
+
+	// Event row against its tiers
+	if s.drifted(seen, event.ID, "", storeEvent, event.AvailableSeats, available) {
+		fmt.Printf("Repaired available seats of event %d: %d -> %d\n", event.ID, event.AvailableSeats, available)
+		event.AvailableSeats = available
+		event.SyncLegacyClasses()
+		if err := s.repo.UpdateEvent(event); err != nil {
+			return err
+		}
+		seatInventoryRepairs.WithLabelValues(storeEvent).Inc()
+	}
+
+	// Redis counters against the tiers minus pending bookings
+	codes := []string{""}
+	expected := map[string]int{"": 0}
+	for _, tier := range event.Tiers {
+		codes = append(codes, tier.Code)
+		expected[tier.Code] = max(tier.Available-holds[tier.Code].Pending, 0)
+		expected[""] += expected[tier.Code]
+	}
+
+	counters, err := s.repo.GetSeatCounters(event.ID, codes)
+	if err != nil {
+		return err
+	}
+	missing := make(map[string]int)
+	for _, code := range codes {
+		observed, ok := counters[code]
+		if !ok {
+			// Locks fail without a counter, so restore it right away
+			missing[code] = expected[code]
+			continue
+		}
+		if !s.drifted(seen, event.ID, code, storeRedis, observed, expected[code]) {
+			continue
+		}
+		repaired, err := s.repo.RepairSeatCounter(event.ID, code, observed, expected[code])
+		if err != nil {
+			return err
+		}
+		if repaired {
+			fmt.Printf("Repaired seat counter of event %d tier %q: %d -> %d\n", event.ID, code, observed, expected[code])
+			seatInventoryRepairs.WithLabelValues(storeRedis).Inc()
+		}
+	}
+	if len(missing) > 0 {
+		restored, err := s.repo.RestoreSeatCounters(event.ID, missing)
+		if err != nil {
+			return err
+		}
+		seatInventoryRepairs.WithLabelValues(storeRebuild).Add(float64(restored))
+	}
+	return nil
+}
+
+// drifted records the difference between an observed and expected count
+// and reports whether it should be repaired. Only drift seen unchanged in
+// two runs in a row is repaired, so bookings still moving between the
+// services are not mistaken for it.
+func (s *inventoryService) drifted(seen map[string]observedDrift, eventID uint, code, store string, observed, expected int) bool {
+	tier := code
+	if tier == "" {
+		tier = "total"
+	}
+	seatInventoryDrift.WithLabelValues(strconv.FormatUint(uint64(eventID), 10), tier, store).Set(float64(observed - expected))
+	if observed == expected {
+		return false
+	}
+
+	key := fmt.Sprintf("%d:%s:%s", eventID, code, store)
+	drift := observedDrift{observed: observed, expected: expected}
+	seen[key] = drift
+	return s.lastDrift[key] == drift
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+)
+
+// PublishEvent lists a draft event. It goes on sale straight away if one of
+// its tiers is on sale, and is only listed until then otherwise.
+func (s *eventService) PublishEvent(eventID, organizerID uint) (*models.Event, error) {
+	event, err := s.ownedEvent(eventID, organizerID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != models.EventStatusDraft {
+		return nil, &models.TransitionError{EventID: eventID, From: event.Status, To: models.EventStatusPublished}
+	}
+
+	to := models.EventStatusPublished
+	if event.SalesOpen(time.Now()) {
+		to = models.EventStatusOnSale
+	}
+	if err := s.transition(event, to, "Published by the organizer", nil, nil); err != nil {
+		return nil, err
+	}
+
+	messaging.PublishAuditLog(organizerID, "PUBLISH_EVENT", fmt.Sprintf("Published event %d: %s", event.ID, event.Title))
+	return s.repo.GetEventByID(eventID)
+}
+
+// UnpublishEvent takes an event back to draft. Events with tickets sold
+// must be cancelled instead.
+func (s *eventService) UnpublishEvent(eventID, organizerID uint) (*models.Event, error) {
+	event, err := s.ownedEvent(eventID, organizerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transition(event, models.EventStatusDraft, "Unpublished by the organizer", nil, func(current *models.Event) error {
+		if current.AvailableSeats < current.TotalSeats {
+			return models.ErrEventHasSales
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messaging.PublishAuditLog(organizerID, "UNPUBLISH_EVENT", fmt.Sprintf("Unpublished event %d: %s", event.ID, event.Title))
+	return s.repo.GetEventByID(eventID)
+}
+
+// CancelEvent cancels an event for good on behalf of its organizer or an
+// admin. Ticket sales stop at once, and event_cancelled has the other
+// services cancel, refund and notify its bookings.
+func (s *eventService) CancelEvent(eventID, requesterID uint, isAdmin bool, reason string) (*models.Event, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.OrganizerID != requesterID && !isAdmin {
+		return nil, models.ErrUnauthorized
+	}
+	if reason == "" {
+		reason = "Cancelled by the organizer"
+	}
+
+	cancelledAt := time.Now()
+	err = s.repo.TransitionEvent(eventID, models.EventStatusCancelled, map[string]interface{}{
+		"cancelled_at":        cancelledAt,
+		"cancellation_reason": reason,
+	}, func(current *models.Event) ([]outbox.Message, error) {
+		changed, err := statusChangedMessage(current, models.EventStatusCancelled, reason)
+		if err != nil {
+			return nil, err
+		}
+
+		// The Booking Service cancels and refunds the event's bookings
+		payload, err := events.Marshal(events.TypeEventCancelled, messaging.Producer, events.EventCorrelationID(current.ID), events.EventCancelled{
+			EventID:     current.ID,
+			OrganizerID: current.OrganizerID,
+			Title:       current.Title,
+			Date:        current.Date,
+			Reason:      reason,
+			CancelledAt: cancelledAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The cancellation goes first: the bookings depend on it, while the
+		// status change is informational
+		return []outbox.Message{{
+			Exchange: messaging.EventCancelledExchange,
+			Payload:  string(payload),
+		}, *changed}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messaging.PublishAuditLog(requesterID, "CANCEL_EVENT", fmt.Sprintf("Cancelled event %d: %s (%s)", event.ID, event.Title, reason))
+	return s.repo.GetEventByID(eventID)
+}
+
+// AdvanceEventLifecycles follows the sale windows of listed events: it puts
+// them on sale once one of their tiers is on sale, closes their sales once
+// no tier can be sold any more, and completes events that have taken place.
+func (s *eventService) AdvanceEventLifecycles() error {
+	listed, err := s.repo.GetListedEvents()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range listed {
+		event := &listed[i]
+		switch {
+		case !event.Date.After(now):
+			err = s.transition(event, models.EventStatusCompleted, "The event has taken place", nil, nil)
+		case event.Status != models.EventStatusSalesClosed && event.SalesEnded(now):
+			err = s.transition(event, models.EventStatusSalesClosed, "Ticket sales closed", nil, nil)
+		case (event.Status == models.EventStatusPublished || event.Status == models.EventStatusSalesClosed) && event.SalesOpen(now):
+			err = s.transition(event, models.EventStatusOnSale, "Ticket sales opened", nil, nil)
+		default:
+			continue
+		}
+		if err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+			fmt.Printf("Failed to advance lifecycle of event %d: %v\n", event.ID, err)
+		}
+	}
+	return nil
+}
+
+// syncSoldOut moves an on-sale event to sold out once no tickets are left,
+// and back on sale when tickets are returned, unless its sales have ended.
+func (s *eventService) syncSoldOut(event *models.Event) {
+	var err error
+	switch {
+	case event.Status == models.EventStatusOnSale && event.AvailableSeats <= 0:
+		err = s.transition(event, models.EventStatusSoldOut, "All tickets sold", nil, nil)
+	case event.Status == models.EventStatusSoldOut && event.AvailableSeats > 0 && event.SalesEnded(time.Now()):
+		err = s.transition(event, models.EventStatusSalesClosed, "Ticket sales closed", nil, nil)
+	case event.Status == models.EventStatusSoldOut && event.AvailableSeats > 0:
+		err = s.transition(event, models.EventStatusOnSale, "Tickets available again", nil, nil)
+	default:
+		return
+	}
+	if err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+		fmt.Printf("Failed to update sold out status of event %d: %v\n", event.ID, err)
+	}
+}
+
+// ownedEvent returns an event if organizerID organizes it.
+func (s *eventService) ownedEvent(eventID, organizerID uint) (*models.Event, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.OrganizerID != organizerID {
+		return nil, models.ErrUnauthorized
+	}
+	return event, nil
+}
+
+// transition moves an event to another status and announces the change on
+// the event_status_changed exchange through the outbox. check, if set, can
+// veto the change against the locked current row.
+func (s *eventService) transition(event *models.Event, to models.EventStatus, reason string, updates map[string]interface{}, check func(current *models.Event) error) error {
+	return s.repo.TransitionEvent(event.ID, to, updates, func(current *models.Event) ([]outbox.Message, error) {
+		if check != nil {
+			if err := check(current); err != nil {
+				return nil, err
+			}
+		}
+
+		message, err := statusChangedMessage(current, to, reason)
+		if err != nil {
+			return nil, err
+		}
+		return []outbox.Message{*message}, nil
+	})
+}
+
+// statusChangedMessage builds the event_status_changed message announcing
+// that event moves to another status.
+func statusChangedMessage(event *models.Event, to models.EventStatus, reason string) (*outbox.Message, error) {
+	payload, err := events.Marshal(events.TypeEventStatusChanged, messaging.Producer, events.EventCorrelationID(event.ID), events.EventStatusChanged{
+		EventID:     event.ID,
+		OrganizerID: event.OrganizerID,
+		Title:       event.Title,
+		Date:        event.Date,
+		FromStatus:  string(event.Status),
+		ToStatus:    string(to),
+		Reason:      reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// No service subscribes to status changes yet, so the broker may drop them
+	return &outbox.Message{
+		Exchange: messaging.EventStatusChangedExchange,
+		Payload:  string(payload),
+		Optional: true,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+)
+
+// lifecycleRepository keeps one event and the outbox rows its transitions
+// store.
+type lifecycleRepository struct {
+	repository.EventRepository
+	event  models.Event
+	outbox []outbox.Message
+}
+
+func (r *lifecycleRepository) GetEventByID(eventID uint) (*models.Event, error) {
+	event := r.event
+	return &event, nil
+}
+
+func (r *lifecycleRepository) TransitionEvent(eventID uint, to models.EventStatus, updates map[string]interface{}, announce func(event *models.Event) ([]outbox.Message, error)) error {
+	if !r.event.Status.CanTransition(to) {
+		return &models.TransitionError{EventID: eventID, From: r.event.Status, To: to}
+	}
+	current := r.event
+	messages, err := announce(&current)
+	if err != nil {
+		return err
+	}
+	r.event.Status = to
+	for _, message := range messages {
+		message.Status = outbox.StatusPending
+		r.outbox = append(r.outbox, message)
+	}
+	return nil
+}
+
+// broker routes messages like RabbitMQ with this service's topology:
+// mandatory messages to an exchange without a bound queue are returned.
+type broker struct {
+	published map[string]int
+}
+
+func (b *broker) Publish(exchange, routingKey string, body []byte) error {
+	if exchange != messaging.EventCancelledExchange || len(messaging.EventCancelledSubscriberQueues) == 0 {
+		return errors.New("message was returned: NO_ROUTE")
+	}
+	b.published[exchange]++
+	return nil
+}
+
+func (b *broker) PublishOptional(exchange, routingKey string, body []byte) error {
+	if exchange == messaging.EventCancelledExchange {
+		b.published[exchange]++
+	}
+	return nil
+}
+
+func TestLifecycleMessagesAreRelayed(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          models.EventStatus
+		change        func(s EventService) error
+		wantMessages  int
+		wantCancelled int
+	}{
+		{
+			name: "publish",
+			from: models.EventStatusDraft,
+			change: func(s EventService) error {
+				_, err := s.PublishEvent(1, 7)
+				return err
+			},
+			wantMessages: 1,
+		},
+		{
+			name: "unpublish",
+			from: models.EventStatusPublished,
+			change: func(s EventService) error {
+				_, err := s.UnpublishEvent(1, 7)
+				return err
+			},
+			wantMessages: 1,
+		},
+		{
+			name: "cancel",
+			from: models.EventStatusOnSale,
+			change: func(s EventService) error {
+				_, err := s.CancelEvent(1, 7, false, "Venue closed")
+				return err
+			},
+			wantMessages:  2,
+			wantCancelled: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &lifecycleRepository{event: models.Event{OrganizerID: 7, Title: "Concert", Status: tt.from}}
+			repo.event.ID = 1
+			if err := tt.change(NewEventService(repo, nil, nil)); err != nil {
+				t.Fatal(err)
+			}
+			if len(repo.outbox) != tt.wantMessages {
+				t.Fatalf("%d outbox messages, want %d", len(repo.outbox), tt.wantMessages)
+			}
+
+			b := &broker{published: map[string]int{}}
+			outbox.Deliver(repo.outbox, outbox.Send(b))
+			for _, message := range repo.outbox {
+				if message.Status != outbox.StatusSent {
+					t.Errorf("message to %q is %s (%s), want sent", message.Exchange, message.Status, message.LastError)
+				}
+			}
+			if got := b.published[messaging.EventCancelledExchange]; got != tt.wantCancelled {
+				t.Errorf("%d event_cancelled messages reached subscribers, want %d", got, tt.wantCancelled)
+			}
+		})
+	}
+}
+
+func TestCancellationIsRelayedWhenStatusChangeFails(t *testing.T) {
+	repo := &lifecycleRepository{event: models.Event{OrganizerID: 7, Status: models.EventStatusOnSale}}
+	repo.event.ID = 1
+	if _, err := NewEventService(repo, nil, nil).CancelEvent(1, 0, true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.Deliver(repo.outbox, func(message *outbox.Message) error {
+		if message.Exchange == messaging.EventStatusChangedExchange {
+			return errors.New("channel closed")
+		}
+		return nil
+	})
+	for _, message := range repo.outbox {
+		wantStatus := outbox.StatusSent
+		if message.Exchange == messaging.EventStatusChangedExchange {
+			wantStatus = outbox.StatusPending
+		}
+		if message.Status != wantStatus {
+			t.Errorf("message to %q is %s, want %s", message.Exchange, message.Status, wantStatus)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+)
+
+// holdTokenPattern accepts the tokens of newHoldToken as well as tokens
+// chosen by callers that lock several tiers under one hold.
+var holdTokenPattern = regexp.MustCompile(`^[A-Za-z0-9-]{16,64}$`)
+
+func newHoldToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *eventService) UnlockHold(eventID uint, holdToken string, requesterID uint, ticketClass string) error {
+	_, err := s.repo.UnlockHold(eventID, holdToken, requesterID, ticketClass)
+	return err
+}
+
+// AttachHoldBooking records the booking a hold was taken for, once the
+// Booking Service has created it.
+func (s *eventService) AttachHoldBooking(eventID uint, holdToken string, requesterID, bookingID uint) error {
+	hold, err := s.repo.GetHold(eventID, holdToken)
+	if err != nil {
+		return err
+	}
+	if requesterID != 0 && hold.UserID != requesterID {
+		return models.ErrHoldNotOwned
+	}
+	return s.repo.SetHoldBooking(eventID, holdToken, bookingID)
+}
+
+// GetSeatHolder reports whether a seat is locked or sold and to whom. The
+// event's organizer, admins and internal services may look up any seat,
+// other users only seats they hold; hold tokens are only shown to the
+// holder and privileged callers.
+func (s *eventService) GetSeatHolder(eventID uint, seatID string, requesterID uint, privileged bool) (*models.SeatHolder, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ResolveSeats(eventID, []string{seatID}); err != nil {
+		return nil, err
+	}
+
+	holder := &models.SeatHolder{SeatID: seatID, Status: models.SeatStatusAvailable}
+	bookings, err := s.venues.GetSeatBookings(eventID, []string{seatID})
+	if err != nil {
+		return nil, err
+	}
+	if bookingID, ok := bookings[seatID]; ok {
+		holder.Status = models.SeatStatusSold
+		holder.BookingID = bookingID
+	} else {
+		locks, err := s.repo.GetSeatHolds(eventID, []string{seatID})
+		if err != nil {
+			return nil, err
+		}
+		if lock, ok := locks[seatID]; ok {
+			holder.Status = lock.Status
+			if lock.HoldToken != "" {
+				hold, err := s.repo.GetHold(eventID, lock.HoldToken)
+				if err != nil && err != models.ErrHoldNotFound {
+					return nil, err
+				}
+				holder.Hold = hold
+			}
+		}
+	}
+
+	holds := holder.Hold != nil && holder.Hold.UserID == requesterID
+	if !privileged && !holds && event.OrganizerID != requesterID {
+		return nil, models.ErrUnauthorized
+	}
+	if !privileged && !holds && holder.Hold != nil {
+		holder.Hold.Token = ""
+	}
+	return holder, nil
+}
+
+// confirmHold checks that the seats of a paid booking are still its own
+// before they are sold, and marks them sold in Redis. Seats must be locked
+// under the booking's hold, or be free or already sold to the booking if
+// the hold lapsed or the confirmation is redelivered.
+func (s *eventService) confirmHold(eventID, bookingID, userID uint, holdToken string, seatIDs []string) error {
+	hold, err := s.repo.GetHold(eventID, holdToken)
+	if err != nil && err != models.ErrHoldNotFound {
+		return err
+	}
+	if hold != nil && hold.UserID != userID {
+		return fmt.Errorf("hold of booking %d belongs to user %d: %w", bookingID, hold.UserID, models.ErrHoldNotOwned)
+	}
+
+	sales, err := s.venues.GetSeatBookings(eventID, seatIDs)
+	if err != nil {
+		return err
+	}
+	for seatID, soldTo := range sales {
+		if soldTo != bookingID {
+			return fmt.Errorf("seat %s of event %d was sold to booking %d: %w", seatID, eventID, soldTo, models.ErrHoldNotOwned)
+		}
+	}
+
+	taken, err := s.repo.ConfirmHold(eventID, holdToken, seatIDs)
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 {
+		return fmt.Errorf("seats %v of event %d are held for another booking: %w", taken, eventID, models.ErrHoldNotOwned)
+	}
+	return nil
+}
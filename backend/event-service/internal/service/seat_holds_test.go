@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+)
+
+// holdRepository keeps one hold of user 3 and the seats locked under other
+// holds.
+type holdRepository struct {
+	repository.EventRepository
+	hold      *models.SeatHold
+	taken     []string
+	confirmed bool
+	attached  uint
+}
+
+func (r *holdRepository) GetHold(eventID uint, holdToken string) (*models.SeatHold, error) {
+	if r.hold == nil || holdToken != r.hold.Token {
+		return nil, models.ErrHoldNotFound
+	}
+	hold := *r.hold
+	return &hold, nil
+}
+
+func (r *holdRepository) ConfirmHold(eventID uint, holdToken string, seatIDs []string) ([]string, error) {
+	if len(r.taken) > 0 {
+		return r.taken, nil
+	}
+	r.confirmed = true
+	return nil, nil
+}
+
+func (r *holdRepository) SetHoldBooking(eventID uint, holdToken string, bookingID uint) error {
+	r.attached = bookingID
+	return nil
+}
+
+// soldSeats is a venue repository that knows whom seats were sold to.
+type soldSeats struct {
+	repository.VenueRepository
+	bookings map[string]uint
+}
+
+func (v *soldSeats) GetSeatBookings(eventID uint, codes []string) (map[string]uint, error) {
+	return v.bookings, nil
+}
+
+const testHoldToken = "0123456789abcdef"
+
+func TestConfirmHold(t *testing.T) {
+	tests := []struct {
+		name          string
+		hold          *models.SeatHold
+		sold          map[string]uint
+		taken         []string
+		wantErr       error
+		wantConfirmed bool
+	}{
+		{"own hold", &models.SeatHold{Token: testHoldToken, UserID: 3}, nil, nil, nil, true},
+		{"hold lapsed", nil, nil, nil, nil, true},
+		{"redelivered", nil, map[string]uint{"A-1": 8}, nil, nil, true},
+		{"another user's hold", &models.SeatHold{Token: testHoldToken, UserID: 4}, nil, nil, models.ErrHoldNotOwned, false},
+		{"seat sold to another booking", nil, map[string]uint{"A-1": 9}, nil, models.ErrHoldNotOwned, false},
+		{"seat taken by another hold", nil, nil, []string{"A-2"}, models.ErrHoldNotOwned, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &holdRepository{hold: tt.hold, taken: tt.taken}
+			s := NewEventService(repo, &soldSeats{bookings: tt.sold}, nil).(*eventService)
+
+			err := s.confirmHold(1, 8, 3, testHoldToken, []string{"A-1", "A-2"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error %v, want %v", err, tt.wantErr)
+			}
+			if repo.confirmed != tt.wantConfirmed {
+				t.Errorf("confirmed %v, want %v", repo.confirmed, tt.wantConfirmed)
+			}
+		})
+	}
+}
+
+func TestAttachHoldBooking(t *testing.T) {
+	tests := []struct {
+		name         string
+		requesterID  uint
+		wantErr      error
+		wantAttached uint
+	}{
+		{"holder", 3, nil, 8},
+		{"internal service", 0, nil, 8},
+		{"another user", 4, models.ErrHoldNotOwned, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &holdRepository{hold: &models.SeatHold{Token: testHoldToken, UserID: 3}}
+			err := NewEventService(repo, nil, nil).AttachHoldBooking(1, testHoldToken, tt.requesterID, 8)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error %v, want %v", err, tt.wantErr)
+			}
+			if repo.attached != tt.wantAttached {
+				t.Errorf("attached booking %d, want %d", repo.attached, tt.wantAttached)
+			}
+		})
+	}
+
+	repo := &holdRepository{}
+	if err := NewEventService(repo, nil, nil).AttachHoldBooking(1, testHoldToken, 3, 8); !errors.Is(err, models.ErrHoldNotFound) {
+		t.Errorf("unknown hold: error %v, want ErrHoldNotFound", err)
+	}
+}
+
+func TestHoldTokenPattern(t *testing.T) {
+	token, err := newHoldToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{token, true},
+		{"booking-42-0123456789", true},
+		{"short", false},
+		{"0123456789abcdef:sold", false},
+		{"0123456789abcdef 1", false},
+	}
+	for _, tt := range tests {
+		if got := holdTokenPattern.MatchString(tt.token); got != tt.want {
+			t.Errorf("holdTokenPattern matches %q = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// eventVenue loads an event's venue layout.
+func (s *eventService) eventVenue(eventID uint) (*models.Event, *models.Venue, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if event.VenueID == nil {
+		return event, nil, models.ErrVenueNotFound
+	}
+
+	venue, err := s.venues.GetVenueByID(*event.VenueID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return event, nil, models.ErrVenueNotFound
+		}
+		return event, nil, err
+	}
+	return event, venue, nil
+}
+
+// GetSeatMap returns every seat of the event's venue with its status: sold
+// once a booking for it is confirmed, locked while a pending booking holds
+// it and available otherwise.
+func (s *eventService) GetSeatMap(eventID uint) (*models.SeatMap, error) {
+	event, venue, err := s.eventVenue(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	for _, section := range venue.Sections {
+		for _, row := range section.Rows {
+			for _, seat := range row.Seats {
+				codes = append(codes, seat.Code)
+			}
+		}
+	}
+
+	holds, err := s.repo.GetSeatHolds(eventID, codes)
+	if err != nil {
+		return nil, err
+	}
+	soldCodes, err := s.venues.GetSoldSeatCodes(eventID)
+	if err != nil {
+		return nil, err
+	}
+	sold := make(map[string]bool, len(soldCodes))
+	for _, code := range soldCodes {
+		sold[code] = true
+	}
+
+	seatMap := &models.SeatMap{
+		EventID:   event.ID,
+		VenueID:   venue.ID,
+		VenueName: venue.Name,
+		Sections:  []models.SeatMapSection{},
+	}
+	for _, section := range venue.Sections {
+		mapSection := models.SeatMapSection{
+			ID:          section.ID,
+			Name:        section.Name,
+			TicketClass: section.TicketClass,
+			Price:       event.PriceFor(section.TicketClass),
+			Rows:        []models.SeatMapRow{},
+		}
+		for _, row := range section.Rows {
+			mapRow := models.SeatMapRow{Label: row.Label, Position: row.Position, Seats: []models.SeatMapSeat{}}
+			for _, seat := range row.Seats {
+				status := models.SeatStatusAvailable
+				if sold[seat.Code] || holds[seat.Code].Status == models.SeatStatusSold {
+					status = models.SeatStatusSold
+				} else if holds[seat.Code].Status != "" {
+					status = models.SeatStatusLocked
+				} else {
+					mapSection.Available++
+				}
+				mapRow.Seats = append(mapRow.Seats, models.SeatMapSeat{ID: seat.Code, Number: seat.Number, Status: status})
+			}
+			mapSection.Rows = append(mapSection.Rows, mapRow)
+		}
+		seatMap.Sections = append(seatMap.Sections, mapSection)
+	}
+	return seatMap, nil
+}
+
+// ResolveSeats looks up seat IDs at the event's venue and returns them in
+// the requested order with their ticket class and price. It fails with
+// ErrUnknownSeat if any seat is not part of the layout.
+func (s *eventService) ResolveSeats(eventID uint, seatIDs []string) ([]models.SeatInfo, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.VenueID == nil {
+		return nil, models.ErrUnknownSeat
+	}
+
+	infos, err := s.venues.GetSeatsByCodes(*event.VenueID, seatIDs)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.SeatInfo, len(infos))
+	for _, info := range infos {
+		byCode[info.ID] = info
+	}
+
+	seats := make([]models.SeatInfo, 0, len(seatIDs))
+	for _, seatID := range seatIDs {
+		info, ok := byCode[seatID]
+		if !ok {
+			return nil, models.ErrUnknownSeat
+		}
+		info.Price = event.PriceFor(info.TicketClass)
+		seats = append(seats, info)
+	}
+	return seats, nil
+}
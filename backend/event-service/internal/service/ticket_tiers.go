@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+)
+
+// normalizeTiers validates the tiers of a new event, derives missing codes
+// from the tier names ("Early Bird" becomes "early-bird") and numbers the
+// tiers in the given order.
+func normalizeTiers(tiers []models.TicketTier) error {
+	if len(tiers) == 0 {
+		return models.ErrInvalidTier
+	}
+
+	codes := make(map[string]bool)
+	for i := range tiers {
+		tier := &tiers[i]
+		if tier.Code == "" {
+			tier.Code = slug(tier.Name)
+		} else {
+			tier.Code = slug(tier.Code)
+		}
+		if tier.MinPerOrder == 0 {
+			tier.MinPerOrder = 1
+		}
+
+		if tier.Name == "" || tier.Code == "" || codes[tier.Code] {
+			return models.ErrInvalidTier
+		}
+		if tier.Price < 0 || tier.Capacity < 0 || tier.MinPerOrder < 1 {
+			return models.ErrInvalidTier
+		}
+		if tier.MaxPerOrder < 0 || (tier.MaxPerOrder > 0 && tier.MaxPerOrder < tier.MinPerOrder) {
+			return models.ErrInvalidTier
+		}
+		if !validPurchaseLimit(tier, tier.MaxPerUser) {
+			return models.ErrInvalidPurchaseLimit
+		}
+		if tier.SalesStart != nil && tier.SalesEnd != nil && !tier.SalesEnd.After(*tier.SalesStart) {
+			return models.ErrInvalidTier
+		}
+
+		codes[tier.Code] = true
+		tier.Position = i + 1
+	}
+	return nil
+}
+
+// validPurchaseLimit reports whether a user could still buy a tier under a
+// per-user limit of maxPerUser.
+func validPurchaseLimit(tier *models.TicketTier, maxPerUser int) bool {
+	return maxPerUser == 0 || (maxPerUser > 0 && maxPerUser >= tier.MinPerOrder)
+}
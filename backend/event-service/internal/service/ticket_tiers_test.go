@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+)
+
+func TestValidPurchaseLimit(t *testing.T) {
+	tests := []struct {
+		minPerOrder int
+		maxPerUser  int
+		want        bool
+	}{
+		{1, 0, true},
+		{1, 1, true},
+		{2, 4, true},
+		{2, 2, true},
+		{4, 2, false},
+		{1, -1, false},
+	}
+	for _, tt := range tests {
+		tier := &models.TicketTier{MinPerOrder: tt.minPerOrder}
+		if got := validPurchaseLimit(tier, tt.maxPerUser); got != tt.want {
+			t.Errorf("validPurchaseLimit(min %d, max per user %d) = %v, want %v", tt.minPerOrder, tt.maxPerUser, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// generatedSeatsPerRow is the row width of layouts generated for events
+// created without a venue. It matches the grid the frontend used to draw.
+const generatedSeatsPerRow = 10
+
+type VenueService interface {
+	// CreateVenue validates the layout and assigns every seat its code.
+	CreateVenue(venue *models.Venue) error
+	GetVenue(venueID uint) (*models.Venue, error)
+	GetVenuesByOrganizer(organizerID uint) ([]models.Venue, error)
+}
+
+type venueService struct {
+	venues repository.VenueRepository
+}
+
+func NewVenueService(venues repository.VenueRepository) VenueService {
+	return &venueService{venues: venues}
+}
+
+func (s *venueService) CreateVenue(venue *models.Venue) error {
+	if err := assignSeatCodes(venue); err != nil {
+		return err
+	}
+	if err := s.venues.CreateVenue(venue); err != nil {
+		return err
+	}
+
+	messaging.PublishAuditLog(venue.OrganizerID, "CREATE_VENUE", fmt.Sprintf("Created venue %d: %s", venue.ID, venue.Name))
+	return nil
+}
+
+func (s *venueService) GetVenue(venueID uint) (*models.Venue, error) {
+	venue, err := s.venues.GetVenueByID(venueID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrVenueNotFound
+		}
+		return nil, err
+	}
+	return venue, nil
+}
+
+func (s *venueService) GetVenuesByOrganizer(organizerID uint) ([]models.Venue, error) {
+	return s.venues.GetVenuesByOrganizerID(organizerID)
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(s string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// assignSeatCodes checks a venue layout and gives every seat its code,
+// "<section>-<row>-<number>", e.g. "balcony-c-7". Section codes must be
+// unique within the venue and row labels within their section.
+func assignSeatCodes(venue *models.Venue) error {
+	if len(venue.Sections) == 0 {
+		return models.ErrInvalidVenue
+	}
+
+	sectionCodes := make(map[string]bool)
+	for i := range venue.Sections {
+		section := &venue.Sections[i]
+		section.Code = slug(section.Name)
+		section.TicketClass = slug(section.TicketClass)
+		if section.Code == "" || sectionCodes[section.Code] || section.TicketClass == "" || len(section.Rows) == 0 {
+			return models.ErrInvalidVenue
+		}
+		sectionCodes[section.Code] = true
+		section.Position = i + 1
+
+		rowLabels := make(map[string]bool)
+		for j := range section.Rows {
+			row := &section.Rows[j]
+			label := slug(row.Label)
+			if label == "" || rowLabels[label] || len(row.Seats) == 0 {
+				return models.ErrInvalidVenue
+			}
+			rowLabels[label] = true
+			row.Position = j + 1
+
+			for k := range row.Seats {
+				row.Seats[k].Code = fmt.Sprintf("%s-%s-%d", section.Code, label, row.Seats[k].Number)
+			}
+		}
+	}
+	return nil
+}
+
+// generateVenue builds the layout of an event created without a venue: one
+// section per tier, rows of generatedSeatsPerRow seats and seat codes
+// "<tier>-<row>-<number>" such as "vip-2-5".
+func generateVenue(event *models.Event) *models.Venue {
+	venue := &models.Venue{
+		Name:        event.Title,
+		Address:     event.Location,
+		OrganizerID: event.OrganizerID,
+		Generated:   true,
+	}
+
+	for _, tier := range event.Tiers {
+		if tier.Capacity <= 0 {
+			continue
+		}
+
+		section := models.Section{
+			Name:        tier.Name,
+			Code:        tier.Code,
+			TicketClass: tier.Code,
+			Position:    len(venue.Sections) + 1,
+		}
+		for row := 1; (row-1)*generatedSeatsPerRow < tier.Capacity; row++ {
+			seatRow := models.SeatRow{Label: strconv.Itoa(row), Position: row}
+			for number := 1; number <= generatedSeatsPerRow && (row-1)*generatedSeatsPerRow+number <= tier.Capacity; number++ {
+				seatRow.Seats = append(seatRow.Seats, models.Seat{
+					Code:   fmt.Sprintf("%s-%d-%d", tier.Code, row, number),
+					Number: number,
+				})
+			}
+			section.Rows = append(section.Rows, seatRow)
+		}
+		venue.Sections = append(venue.Sections, section)
+	}
+	return venue
+}
+
+// seatCounts returns the number of seats per ticket class of a venue.
+func seatCounts(venue *models.Venue) map[string]int {
+	counts := make(map[string]int)
+	for _, section := range venue.Sections {
+		for _, row := range section.Rows {
+			counts[section.TicketClass] += len(row.Seats)
+		}
+	}
+	return counts
+}
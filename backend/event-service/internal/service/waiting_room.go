@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+)
+
+const (
+	defaultAdmissionRate  = 100 // users per minute
+	defaultPurchaseWindow = 10 * time.Minute
+	// admissionBurst caps the admissions saved up while nobody waits, so a
+	// quiet queue does not let a crowd in at once when it fills.
+	admissionBurst = 5 * time.Second
+)
+
+// waitingRoomSettings returns an event's admission rate per minute and
+// purchase window, falling back to WAITING_ROOM_ADMISSION_RATE and
+// WAITING_ROOM_PURCHASE_WINDOW.
+func waitingRoomSettings(event *models.Event) (int, time.Duration) {
+	rate := event.AdmissionRate
+	if rate <= 0 {
+		rate, _ = strconv.Atoi(os.Getenv("WAITING_ROOM_ADMISSION_RATE"))
+		if rate <= 0 {
+			rate = defaultAdmissionRate
+		}
+	}
+	window := time.Duration(event.PurchaseWindowMinutes) * time.Minute
+	if window <= 0 {
+		window, _ = time.ParseDuration(os.Getenv("WAITING_ROOM_PURCHASE_WINDOW"))
+		if window <= 0 {
+			window = defaultPurchaseWindow
+		}
+	}
+	return rate, window
+}
+
+// JoinWaitingRoom queues a user for an event with a waiting room. Users
+// may queue as soon as the event is published, ahead of the on-sale, and
+// joining again returns the ticket they have.
+func (s *eventService) JoinWaitingRoom(eventID, userID uint) (*models.QueueTicket, error) {
+	event, err := s.repo.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if !event.WaitingRoom {
+		return nil, models.ErrNoWaitingRoom
+	}
+	if event.Status != models.EventStatusPublished && event.Status != models.EventStatusOnSale {
+		return nil, models.ErrEventNotOnSale
+	}
+	if event.SalesEnded(time.Now()) {
+		return nil, models.ErrSalesEnded
+	}
+
+	// Queue tokens are made like hold tokens
+	token, err := newHoldToken()
+	if err != nil {
+		return nil, err
+	}
+	rate, _ := waitingRoomSettings(event)
+	token, err = s.rooms.Join(eventID, userID, token, rate)
+	if err != nil {
+		return nil, err
+	}
+	return s.rooms.GetTicket(eventID, token)
+}
+
+// GetQueueTicket reports a ticket's place in the queue or its admission.
+// The token is the credential, so browsers can follow it without a
+// bearer token.
+func (s *eventService) GetQueueTicket(eventID uint, token string) (*models.QueueTicket, error) {
+	if !holdTokenPattern.MatchString(token) {
+		return nil, models.ErrQueueTicketNotFound
+	}
+	return s.rooms.GetTicket(eventID, token)
+}
+
+// checkAdmission lets a user lock seats of an event with a waiting room
+// only under a ticket admitted for them whose purchase window is open.
+func (s *eventService) checkAdmission(event *models.Event, userID uint, admissionToken string, now time.Time) error {
+	if !event.WaitingRoom {
+		return nil
+	}
+	if !holdTokenPattern.MatchString(admissionToken) {
+		return models.ErrAdmissionRequired
+	}
+	ticket, err := s.rooms.GetTicket(event.ID, admissionToken)
+	if err == models.ErrQueueTicketNotFound {
+		return models.ErrAdmissionRequired
+	}
+	if err != nil {
+		return err
+	}
+	if ticket.UserID != userID || ticket.Status != models.QueueStatusAdmitted || !now.Before(*ticket.AdmittedUntil) {
+		return models.ErrAdmissionRequired
+	}
+	return nil
+}
+
+// AdmitFromWaitingRooms lets the next users waiting for on-sale events in,
+// at each event's admission rate.
+func (s *eventService) AdmitFromWaitingRooms() error {
+	events, err := s.repo.GetListedEvents()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range events {
+		event := &events[i]
+		if !event.WaitingRoom || event.Status != models.EventStatusOnSale || event.CheckSalesWindow(now) != nil {
+			continue
+		}
+		rate, window := waitingRoomSettings(event)
+		burst := max(1, float64(rate)*admissionBurst.Minutes())
+		admitted, err := s.rooms.Admit(event.ID, rate, burst, now, window)
+		if err != nil {
+			fmt.Printf("Failed to admit users to event %d: %v\n", event.ID, err)
+			continue
+		}
+		if admitted > 0 {
+			fmt.Printf("Admitted %d users from the waiting room of event %d\n", admitted, event.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/repository"
+)
+
+func TestWaitingRoomSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		rate       int
+		minutes    int
+		envRate    string
+		envWindow  string
+		wantRate   int
+		wantWindow time.Duration
+	}{
+		{"defaults", 0, 0, "", "", defaultAdmissionRate, defaultPurchaseWindow},
+		{"environment", 0, 0, "30", "5m", 30, 5 * time.Minute},
+		{"event overrides environment", 60, 15, "30", "5m", 60, 15 * time.Minute},
+		{"invalid environment", 0, 0, "-3", "soon", defaultAdmissionRate, defaultPurchaseWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WAITING_ROOM_ADMISSION_RATE", tt.envRate)
+			t.Setenv("WAITING_ROOM_PURCHASE_WINDOW", tt.envWindow)
+			rate, window := waitingRoomSettings(&models.Event{AdmissionRate: tt.rate, PurchaseWindowMinutes: tt.minutes})
+			if rate != tt.wantRate || window != tt.wantWindow {
+				t.Errorf("got %d/min and %s, want %d/min and %s", rate, window, tt.wantRate, tt.wantWindow)
+			}
+		})
+	}
+}
+
+// roomRepository keeps queue tickets by token and records admissions.
+type roomRepository struct {
+	repository.WaitingRoomRepository
+	tickets map[string]*models.QueueTicket
+	bursts  map[uint]float64
+}
+
+func (r *roomRepository) GetTicket(eventID uint, token string) (*models.QueueTicket, error) {
+	ticket, ok := r.tickets[token]
+	if !ok {
+		return nil, models.ErrQueueTicketNotFound
+	}
+	return ticket, nil
+}
+
+func (r *roomRepository) Admit(eventID uint, ratePerMinute int, burst float64, now time.Time, window time.Duration) (int, error) {
+	r.bursts[eventID] = burst
+	return 0, nil
+}
+
+func TestCheckAdmission(t *testing.T) {
+	now := time.Now()
+	open, lapsed := now.Add(time.Minute), now.Add(-time.Second)
+	rooms := &roomRepository{tickets: map[string]*models.QueueTicket{
+		"admitted-0123456789": {UserID: 3, Status: models.QueueStatusAdmitted, AdmittedUntil: &open},
+		"waiting-0123456789a": {UserID: 3, Status: models.QueueStatusWaiting},
+		"lapsed-0123456789ab": {UserID: 3, Status: models.QueueStatusAdmitted, AdmittedUntil: &lapsed},
+	}}
+	s := NewEventService(nil, nil, rooms).(*eventService)
+
+	tests := []struct {
+		name        string
+		waitingRoom bool
+		userID      uint
+		token       string
+		want        error
+	}{
+		{"no waiting room", false, 3, "", nil},
+		{"admitted", true, 3, "admitted-0123456789", nil},
+		{"no token", true, 3, "", models.ErrAdmissionRequired},
+		{"malformed token", true, 3, "admitted", models.ErrAdmissionRequired},
+		{"unknown token", true, 3, "unknown-0123456789a", models.ErrAdmissionRequired},
+		{"still waiting", true, 3, "waiting-0123456789a", models.ErrAdmissionRequired},
+		{"purchase window over", true, 3, "lapsed-0123456789ab", models.ErrAdmissionRequired},
+		{"another user's ticket", true, 4, "admitted-0123456789", models.ErrAdmissionRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &models.Event{WaitingRoom: tt.waitingRoom}
+			if err := s.checkAdmission(event, tt.userID, tt.token, now); !errors.Is(err, tt.want) {
+				t.Errorf("checkAdmission = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// listedEvents returns the given events as the public listing.
+type listedEvents struct {
+	repository.EventRepository
+	events []models.Event
+}
+
+func (r *listedEvents) GetListedEvents() ([]models.Event, error) {
+	return r.events, nil
+}
+
+func TestAdmitFromWaitingRooms(t *testing.T) {
+	later := time.Now().Add(24 * time.Hour)
+	event := func(id uint, waitingRoom bool, status models.EventStatus, rate int) models.Event {
+		e := models.Event{WaitingRoom: waitingRoom, Status: status, AdmissionRate: rate, Date: later}
+		e.ID = id
+		return e
+	}
+	repo := &listedEvents{events: []models.Event{
+		event(1, true, models.EventStatusOnSale, 120),
+		event(2, true, models.EventStatusOnSale, 6),
+		event(3, false, models.EventStatusOnSale, 120),
+		event(4, true, models.EventStatusPublished, 120),
+	}}
+	rooms := &roomRepository{bursts: map[uint]float64{}}
+
+	if err := NewEventService(repo, nil, rooms).AdmitFromWaitingRooms(); err != nil {
+		t.Fatal(err)
+	}
+	// Bursts hold five seconds of admissions, and at least one
+	want := map[uint]float64{1: 10, 2: 1}
+	if len(rooms.bursts) != len(want) {
+		t.Fatalf("admitted from events %v, want %v", rooms.bursts, want)
+	}
+	for id, burst := range want {
+		if rooms.bursts[id] != burst {
+			t.Errorf("event %d admitted with burst %v, want %v", id, rooms.bursts[id], burst)
+		}
+	}
+}
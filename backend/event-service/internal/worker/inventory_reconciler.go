@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
+)
+
+// StartInventoryReconciler periodically compares the seat counters in
+// Redis and Postgres with the Booking Service and repairs lasting drift.
+func StartInventoryReconciler(inventoryService service.InventoryService) {
+	interval, err := time.ParseDuration(os.Getenv("SEAT_RECONCILE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 1 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := inventoryService.ReconcileSeatInventory(); err != nil {
+				fmt.Printf("Error in seat inventory reconciler: %v\n", err)
+			}
+		}
+	}()
+}
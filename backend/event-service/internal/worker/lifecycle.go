@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
+)
+
+// StartLifecycleWorker periodically opens the sales of published events
+// and completes events that have taken place.
+func StartLifecycleWorker(eventService service.EventService) {
+	interval, err := time.ParseDuration(os.Getenv("EVENT_LIFECYCLE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 1 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := eventService.AdvanceEventLifecycles(); err != nil {
+				fmt.Printf("Error in event lifecycle worker: %v\n", err)
+			}
+		}
+	}()
+}
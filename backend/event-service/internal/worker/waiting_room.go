@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/event-service/internal/service"
+)
+
+// StartWaitingRoomAdmitter periodically admits the next users waiting for
+// on-sale events. Admissions follow each event's rate whatever the
+// interval, which only sets how smoothly they are spread.
+func StartWaitingRoomAdmitter(eventService service.EventService) {
+	interval, err := time.ParseDuration(os.Getenv("WAITING_ROOM_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 1 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := eventService.AdmitFromWaitingRooms(); err != nil {
+				fmt.Printf("Error in waiting room admitter: %v\n", err)
+			}
+		}
+	}()
+}
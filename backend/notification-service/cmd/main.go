@@ -7,6 +7,7 @@ import (
 	"github.com/Antiaastu/distributed-event-ticketing/notification-service/internal/messaging"
 	"github.com/Antiaastu/distributed-event-ticketing/notification-service/internal/middleware"
 	"github.com/Antiaastu/distributed-event-ticketing/notification-service/internal/service"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -16,8 +17,8 @@ func main() {
 
 	notificationService := service.NewNotificationService()
 
-	// Start Consumer in a goroutine
-	go messaging.StartConsumer(notificationService)
+	// Start Consumer
+	messaging.StartConsumer(notificationService)
 
 	// Start HTTP Server
 	r := gin.Default()
@@ -33,6 +34,11 @@ func main() {
 	handler := handlers.NewNotificationHandler(notificationService)
 	r.GET("/api/notifications/tickets/:bookingID/pdf", handler.DownloadTicket)
 
+	// Dead-lettered messages (Admin only)
+	admin := r.Group("/api/notifications/admin")
+	admin.Use(middleware.AuthMiddleware())
+	shared.NewDeadLetterHandler(messaging.Client).RegisterRoutes(admin)
+
 	log.Println("Notification Service HTTP server running on port 3005")
 	r.Run(":3005")
 }
@@ -1,109 +1,124 @@
 package messaging
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
 	"github.com/Antiaastu/distributed-event-ticketing/notification-service/internal/service"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 	"github.com/streadway/amqp"
 )
 
+const (
+	bookingConfirmedExchange = "booking_confirmed"
+	bookingConfirmedQueue    = "notification-service.booking_confirmed"
+	paymentRefundedExchange  = "payment_refunded"
+	paymentRefundedQueue     = "notification-service.payment_refunded"
+
+	// TicketIssuedQueue tells the booking service a ticket was sent.
+	TicketIssuedQueue = "ticket_issued"
+
+	// Producer is the source attribute of the events this service publishes.
+	Producer = "notification-service"
+)
+
+// Client is the service's long-lived RabbitMQ connection.
+var Client *shared.Client
+
 func StartConsumer(svc service.NotificationService) {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
-
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ: ", err)
+	Client = shared.NewClient(shared.URLFromEnv())
+	// Redelivered messages are acked without sending a second email
+	Client.Deduplicate(dedup.NewFilter(newDedupStore()))
+
+	// Declare queues
+	queues := []string{"booking_cancelled", "email_verification", "password_reset"}
+	Client.DeclareTopology(shared.DeclareQueues(queues...))
+	Client.DeclareTopology(shared.DeclareQueues(TicketIssuedQueue))
+	Client.DeclareTopology(shared.DeclareFanout(bookingConfirmedExchange, bookingConfirmedQueue))
+	Client.DeclareTopology(shared.DeclareFanout(paymentRefundedExchange, paymentRefundedQueue))
+
+	for _, qName := range append(queues, bookingConfirmedQueue, paymentRefundedQueue) {
+		queueName := qName
+		Client.Consume(queueName, func(d amqp.Delivery) error {
+			return handleMessage(svc, queueName, d)
+		})
 	}
-	defer conn.Close()
 
-	ch, err := conn.Channel()
-	if err != nil {
-		log.Fatal("Failed to open a channel: ", err)
+	if err := Client.Connect(); err != nil {
+		log.Printf("RabbitMQ not available yet, retrying in the background: %v", err)
 	}
-	defer ch.Close()
 
-	forever := make(chan bool)
+	log.Printf(" [*] Waiting for messages. To exit press CTRL+C")
+}
 
-	// Declare queues
-	queues := []string{"booking_confirmed", "email_verification", "password_reset"}
-	for _, qName := range queues {
-		q, err := ch.QueueDeclare(
-			qName, // name
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			log.Fatal("Failed to declare queue: ", err)
+func handleMessage(svc service.NotificationService, queueName string, d amqp.Delivery) error {
+	log.Printf("Received a message from %s: %s", queueName, d.Body)
+
+	if queueName == bookingConfirmedQueue {
+		var event events.BookingConfirmed
+		if _, err := events.Unmarshal(d.Body, events.TypeBookingConfirmed, &event); err != nil {
+			return shared.Permanent(fmt.Errorf("error parsing message: %v", err))
 		}
 
-		msgs, err := ch.Consume(
-			q.Name, // queue
-			"",     // consumer
-			true,   // auto-ack
-			false,  // exclusive
-			false,  // no-local
-			false,  // no-wait
-			nil,    // args
-		)
-		if err != nil {
-			log.Fatal("Failed to register a consumer: ", err)
+		if err := svc.ProcessBookingConfirmation(event.BookingID, event.UserID, event.EventID, event.Amount, event.SeatCount, event.Seats); err != nil {
+			return fmt.Errorf("failed to process booking confirmation: %v", err)
+		}
+		publishTicketIssued(event.BookingID, event.UserID)
+	} else if queueName == "booking_cancelled" {
+		var event events.BookingCancelled
+		if _, err := events.Unmarshal(d.Body, events.TypeBookingCancelled, &event); err != nil {
+			return shared.Permanent(fmt.Errorf("error parsing message: %v", err))
 		}
 
-		go func(queueName string, messages <-chan amqp.Delivery) {
-			for d := range messages {
-				log.Printf("Received a message from %s: %s", queueName, d.Body)
-
-				if queueName == "booking_confirmed" {
-					var event struct {
-						BookingID uint    `json:"booking_id"`
-						UserID    uint    `json:"user_id"`
-						EventID   uint    `json:"event_id"`
-						Amount    float64 `json:"amount"`
-						SeatCount int     `json:"seat_count"`
-						Seats     string  `json:"seats"`
-					}
-					if err := json.Unmarshal(d.Body, &event); err != nil {
-						log.Println("Error parsing message:", err)
-						continue
-					}
-
-					if err := svc.ProcessBookingConfirmation(event.BookingID, event.UserID, event.EventID, event.Amount, event.SeatCount, event.Seats); err != nil {
-						log.Println("Failed to process booking confirmation:", err)
-					}
-				} else if queueName == "email_verification" {
-					var event map[string]string
-					if err := json.Unmarshal(d.Body, &event); err != nil {
-						log.Println("Error parsing message:", err)
-						continue
-					}
-					if err := svc.SendVerificationEmail(event["email"], event["code"]); err != nil {
-						log.Println("Failed to send verification email:", err)
-					}
-				} else if queueName == "password_reset" {
-					var event map[string]string
-					if err := json.Unmarshal(d.Body, &event); err != nil {
-						log.Println("Error parsing message:", err)
-						continue
-					}
-					if err := svc.SendPasswordResetEmail(event["email"], event["code"]); err != nil {
-						log.Println("Failed to send password reset email:", err)
-					}
-				}
-			}
-		}(qName, msgs)
+		if err := svc.ProcessBookingCancellation(event.BookingID, event.UserID, event.EventID, event.RefundAmount, event.Reason, event.EventCancelled); err != nil {
+			return fmt.Errorf("failed to process booking cancellation: %v", err)
+		}
+	} else if queueName == paymentRefundedQueue {
+		var event events.PaymentRefunded
+		if _, err := events.Unmarshal(d.Body, events.TypePaymentRefunded, &event); err != nil {
+			return shared.Permanent(fmt.Errorf("error parsing message: %v", err))
+		}
+
+		if err := svc.SendRefundEmail(event.BookingID, event.UserID, event.Amount, event.TotalRefunded, event.Reason); err != nil {
+			return fmt.Errorf("failed to send refund email: %v", err)
+		}
+	} else if queueName == "email_verification" {
+		var event events.EmailVerificationRequested
+		if _, err := events.Unmarshal(d.Body, events.TypeEmailVerificationRequested, &event); err != nil {
+			return shared.Permanent(fmt.Errorf("error parsing message: %v", err))
+		}
+		if err := svc.SendVerificationEmail(event.Email, event.Code); err != nil {
+			return fmt.Errorf("failed to send verification email: %v", err)
+		}
+	} else if queueName == "password_reset" {
+		var event events.PasswordResetRequested
+		if _, err := events.Unmarshal(d.Body, events.TypePasswordResetRequested, &event); err != nil {
+			return shared.Permanent(fmt.Errorf("error parsing message: %v", err))
+		}
+		if err := svc.SendPasswordResetEmail(event.Email, event.Code); err != nil {
+			return fmt.Errorf("failed to send password reset email: %v", err)
+		}
 	}
+	return nil
+}
 
-	log.Printf(" [*] Waiting for messages. To exit press CTRL+C")
-	<-forever
+// publishTicketIssued reports a sent ticket so the booking saga can complete.
+// The email has already gone out, so failures are only logged.
+func publishTicketIssued(bookingID, userID uint) {
+	body, err := events.Marshal(events.TypeTicketIssued, Producer, events.BookingCorrelationID(bookingID), events.TicketIssued{
+		BookingID: bookingID,
+		UserID:    userID,
+		IssuedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Failed to encode ticket issued event: %v", err)
+		return
+	}
+
+	if err := Client.Publish("", TicketIssuedQueue, body); err != nil {
+		log.Printf("Failed to publish ticket issued event for booking %d: %v", bookingID, err)
+	}
 }
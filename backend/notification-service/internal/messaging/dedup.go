@@ -0,0 +1,36 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup/redisstore"
+	"github.com/redis/go-redis/v9"
+)
+
+// newDedupStore returns the store consumers record processed message IDs in,
+// chosen by DEDUP_STORE: memory (default) or redis. The service has no
+// database, so use redis to keep IDs across restarts and replicas.
+func newDedupStore() dedup.Store {
+	switch kind := dedup.StoreKindFromEnv("memory"); kind {
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+
+		client := redis.NewClient(&redis.Options{
+			Addr: addr,
+		})
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			log.Fatal("Failed to connect to Redis: ", err)
+		}
+		return redisstore.NewStore(client)
+	case "memory":
+	default:
+		log.Printf("Unknown DEDUP_STORE %q, using memory", kind)
+	}
+	return dedup.NewMemoryStore()
+}
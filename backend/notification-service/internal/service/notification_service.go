@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"net/http"
 	"os"
 	"strings"
@@ -16,6 +17,10 @@ type NotificationService interface {
 	SendVerificationEmail(email, code string) error
 	SendPasswordResetEmail(email, code string) error
 	ProcessBookingConfirmation(bookingID, userID, eventID uint, amount float64, seatCount int, seats string) error
+	// ProcessBookingCancellation emails the holder of a cancelled booking;
+	// eventCancelled tells them the whole event was called off.
+	ProcessBookingCancellation(bookingID, userID, eventID uint, refundAmount float64, reason string, eventCancelled bool) error
+	SendRefundEmail(bookingID, userID uint, amount, totalRefunded float64, reason string) error
 	DownloadTicket(bookingID uint) (string, error)
 }
 
@@ -47,7 +52,25 @@ func (s *notificationService) DownloadTicket(bookingID uint) (string, error) {
 	seatCount := int(booking["seat_count"].(float64))
 	seats, _ := booking["seats"].(string)
 
-	return s.generatePDFTicket(bookingID, amount, event, seatCount, seats)
+	return s.generatePDFTicket(bookingID, amount, event, seatCount, seats, seatPrices(booking))
+}
+
+// seatPrices maps seat IDs to the price the booking service charged for them.
+func seatPrices(booking map[string]interface{}) map[string]float64 {
+	prices := make(map[string]float64)
+	items, _ := booking["items"].([]interface{})
+	for _, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		seatID, _ := item["seat_id"].(string)
+		price, ok := item["price"].(float64)
+		if seatID != "" && ok {
+			prices[seatID] = price
+		}
+	}
+	return prices
 }
 
 func (s *notificationService) fetchBookingDetails(bookingID uint) (map[string]interface{}, error) {
@@ -157,7 +180,13 @@ func (s *notificationService) ProcessBookingConfirmation(bookingID, userID, even
 		return fmt.Errorf("failed to fetch event details: %v", err)
 	}
 
-	pdfPath, err := s.generatePDFTicket(bookingID, amount, eventDetails, seatCount, seats)
+	// The price breakdown is optional on the ticket, so a failed lookup is not fatal
+	var prices map[string]float64
+	if booking, err := s.fetchBookingDetails(bookingID); err == nil {
+		prices = seatPrices(booking)
+	}
+
+	pdfPath, err := s.generatePDFTicket(bookingID, amount, eventDetails, seatCount, seats, prices)
 	if err != nil {
 		return fmt.Errorf("failed to generate PDF: %v", err)
 	}
@@ -166,6 +195,104 @@ func (s *notificationService) ProcessBookingConfirmation(bookingID, userID, even
 	return s.sendEmailWithAttachment(userEmail, bookingID, amount, eventDetails, pdfPath)
 }
 
+func (s *notificationService) ProcessBookingCancellation(bookingID, userID, eventID uint, refundAmount float64, reason string, eventCancelled bool) error {
+	userEmail, err := s.fetchUserEmail(userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user email: %v", err)
+	}
+
+	eventDetails, err := s.fetchEventDetails(eventID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch event details: %v", err)
+	}
+
+	refundText := "No refund is due for this booking."
+	if refundAmount > 0 {
+		refundText = fmt.Sprintf("A refund of <b>$%.2f</b> has been requested and will be returned to your original payment method.", refundAmount)
+	}
+
+	title := "Booking Cancelled"
+	intro := fmt.Sprintf("Your booking <b>#%d</b> for <b>%v</b> on %v has been cancelled.", bookingID, eventDetails["title"], eventDetails["date"])
+	subject := fmt.Sprintf("Booking #%d cancelled - TicketHub", bookingID)
+	if eventCancelled {
+		title = "Event Cancelled"
+		intro = fmt.Sprintf("We're sorry: <b>%v</b> on %v has been cancelled by the organizer, so your booking <b>#%d</b> has been cancelled too.", eventDetails["title"], eventDetails["date"], bookingID)
+		subject = fmt.Sprintf("%v has been cancelled - TicketHub", eventDetails["title"])
+		if refundAmount > 0 {
+			refundText = fmt.Sprintf("You will receive a full refund of <b>$%.2f</b> to your original payment method. We'll email you again once it has been processed.", refundAmount)
+		}
+	}
+
+	htmlBody := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<style>
+				body { font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px; }
+				.container { max-width: 600px; margin: 0 auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+				.header { text-align: center; margin-bottom: 30px; }
+				.footer { margin-top: 30px; text-align: center; font-size: 12px; color: #666; }
+			</style>
+		</head>
+		<body>
+			<div class="container">
+				<div class="header">
+					<h2>%s</h2>
+				</div>
+				<p>Hi there,</p>
+				<p>%s</p>
+				<p>Reason: %s</p>
+				<p>%s</p>
+				<div class="footer">
+					<p>&copy; 2025 TicketHub. All rights reserved.</p>
+					<p>Need help? Contact support@tickethub.com</p>
+				</div>
+			</div>
+		</body>
+		</html>
+	`, title, intro, html.EscapeString(reason), refundText)
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_EMAIL"))
+	m.SetHeader("To", userEmail)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", htmlBody)
+
+	d := gomail.NewDialer(
+		os.Getenv("SMTP_HOST"),
+		587,
+		os.Getenv("SMTP_EMAIL"),
+		os.Getenv("SMTP_PASSWORD"),
+	)
+
+	return d.DialAndSend(m)
+}
+
+func (s *notificationService) SendRefundEmail(bookingID, userID uint, amount, totalRefunded float64, reason string) error {
+	userEmail, err := s.fetchUserEmail(userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user email: %v", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", os.Getenv("SMTP_EMAIL"))
+	m.SetHeader("To", userEmail)
+	m.SetHeader("Subject", fmt.Sprintf("Refund for booking #%d - TicketHub", bookingID))
+	m.SetBody("text/html", fmt.Sprintf(
+		"<h1>Refund Processed</h1><p>We have refunded <b>$%.2f</b> for booking #%d (total refunded: $%.2f).</p><p>Reason: %s</p><p>It may take a few business days to appear on your statement.</p>",
+		amount, bookingID, totalRefunded, html.EscapeString(reason),
+	))
+
+	d := gomail.NewDialer(
+		os.Getenv("SMTP_HOST"),
+		587,
+		os.Getenv("SMTP_EMAIL"),
+		os.Getenv("SMTP_PASSWORD"),
+	)
+
+	return d.DialAndSend(m)
+}
+
 func (s *notificationService) fetchUserEmail(userID uint) (string, error) {
 	resp, err := http.Get(fmt.Sprintf("http://auth-service:3001/api/auth/users/%d", userID))
 	if err != nil {
@@ -186,8 +313,16 @@ func (s *notificationService) fetchUserEmail(userID uint) (string, error) {
 	return user.Email, nil
 }
 
+// fetchEventDetails fetches an event as an internal service, so cancelled
+// events are found too.
 func (s *notificationService) fetchEventDetails(eventID uint) (map[string]interface{}, error) {
-	resp, err := http.Get(fmt.Sprintf("http://event-service:3003/api/events/%d", eventID))
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://event-service:3003/api/events/%d", eventID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Internal-Token", os.Getenv("INTERNAL_SERVICE_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +339,7 @@ func (s *notificationService) fetchEventDetails(eventID uint) (map[string]interf
 	return event, nil
 }
 
-func (s *notificationService) generatePDFTicket(bookingID uint, amount float64, event map[string]interface{}, seatCount int, seats string) (string, error) {
+func (s *notificationService) generatePDFTicket(bookingID uint, amount float64, event map[string]interface{}, seatCount int, seats string, prices map[string]float64) (string, error) {
 	pdf := fpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
 
@@ -296,7 +431,11 @@ func (s *notificationService) generatePDFTicket(bookingID uint, amount float64,
 					class = strings.ToUpper(parts[0])
 				}
 
-				seatStrs = append(seatStrs, fmt.Sprintf("[%s] Row %v, Seat %v", class, row, number))
+				seatStr := fmt.Sprintf("[%s] Row %v, Seat %v", class, row, number)
+				if price, ok := prices[id]; ok {
+					seatStr += fmt.Sprintf(" - $%.2f", price)
+				}
+				seatStrs = append(seatStrs, seatStr)
 			}
 
 			// Print seats in columns or list
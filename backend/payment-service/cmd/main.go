@@ -2,14 +2,21 @@ package main
 
 import (
 	"log"
+	"os"
 
 	_ "github.com/Antiaastu/distributed-event-ticketing/payment-service/docs"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/chapa"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/database"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/handlers"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/messaging"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/middleware"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/repository"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/service"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/worker"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/idempotency"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
@@ -23,11 +30,25 @@ import (
 // @BasePath /api
 func main() {
 	database.ConnectDB()
-	messaging.ConnectRabbitMQ()
+	messaging.ConnectRabbitMQ(database.NewDedupStore())
 
 	paymentRepo := repository.NewPaymentRepository()
-	paymentService := service.NewPaymentService(paymentRepo)
+	paymentProvider := newPaymentProvider()
+	paymentService := service.NewPaymentService(paymentRepo, repository.NewRefundJobRepository(), paymentProvider)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	idempotencyRepo := idempotency.NewRepository(database.DB)
+
+	// Start Outbox Relay
+	outbox.StartRelay(outbox.NewStore(database.DB), messaging.Client)
+
+	// Start Payment Reconciler
+	worker.StartReconciler(paymentService)
+
+	// Start Refund Consumer
+	messaging.StartRefundConsumer(paymentService)
+
+	// Start Refund Job Worker
+	worker.StartRefundJobWorker(paymentService)
 
 	r := gin.Default()
 
@@ -43,10 +64,42 @@ func main() {
 
 	api := r.Group("/api")
 	{
-		api.POST("/payments/initialize", paymentHandler.InitializePayment)
 		api.GET("/payments/verify/:tx_ref", paymentHandler.VerifyPayment)
+		api.POST("/payments/callback", paymentHandler.HandleWebhook)
+		api.GET("/payments/callback", paymentHandler.PaymentCallback)
 	}
 
-	log.Println("Payment Service running on port 3004")
+	// The offline provider serves its own checkout page
+	if mock, ok := paymentProvider.(*provider.MockProvider); ok {
+		mock.RegisterRoutes(api)
+	}
+
+	protected := api.Group("")
+	protected.Use(middleware.AuthMiddleware())
+	{
+		protected.POST("/payments/initialize", idempotency.Middleware(idempotencyRepo), paymentHandler.InitializePayment)
+		protected.GET("/payments/:tx_ref/refunds", paymentHandler.GetRefunds)
+		protected.POST("/payments/:tx_ref/refunds", paymentHandler.RefundPayment)
+		protected.GET("/payments/reconciliation/flagged", paymentHandler.GetFlaggedPayments)
+		protected.GET("/payments/refund-jobs", paymentHandler.GetRefundJobs)
+		protected.GET("/payments/refund-jobs/:id", paymentHandler.GetRefundJob)
+		protected.POST("/payments/refund-jobs/:id/retry", paymentHandler.RetryRefundJob)
+	}
+
+	// Dead-lettered messages (Admin only)
+	shared.NewDeadLetterHandler(messaging.Client).RegisterRoutes(protected.Group("/payments/admin"))
+
+	log.Printf("Payment Service running on port 3004 (provider: %s)", paymentProvider.Name())
 	r.Run(":3004")
 }
+
+// newPaymentProvider selects the payment gateway from PAYMENT_PROVIDER:
+// "chapa" (default) or "mock" for the offline simulated gateway.
+func newPaymentProvider() provider.PaymentProvider {
+	switch os.Getenv("PAYMENT_PROVIDER") {
+	case "mock":
+		return provider.NewMockProvider()
+	default:
+		return chapa.NewChapaClient()
+	}
+}
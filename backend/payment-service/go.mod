@@ -3,7 +3,9 @@ module github.com/Antiaastu/distributed-event-ticketing/payment-service
 go 1.24.5
 
 require (
+	github.com/Antiaastu/distributed-event-ticketing/shared v0.0.0-00010101000000-000000000000
 	github.com/gin-gonic/gin v1.11.0
+	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/prometheus/client_golang v1.23.2
 	github.com/streadway/amqp v1.1.0
 	github.com/swaggo/files v1.0.1
@@ -70,3 +72,5 @@ require (
 	google.golang.org/protobuf v1.36.9 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
+
+replace github.com/Antiaastu/distributed-event-ticketing/shared => ../shared
@@ -14,11 +14,18 @@ const (
 
 type ChapaClient struct {
 	SecretKey string
+	BaseURL   string
 }
 
 func NewChapaClient() *ChapaClient {
+	baseURL := os.Getenv("CHAPA_BASE_URL")
+	if baseURL == "" {
+		baseURL = ChapaBaseURL
+	}
+
 	return &ChapaClient{
 		SecretKey: os.Getenv("CHAPA_SECRET_KEY"),
+		BaseURL:   baseURL,
 	}
 }
 
@@ -42,7 +49,7 @@ type InitializeResponse struct {
 }
 
 func (c *ChapaClient) InitializeTransaction(req *InitializeRequest) (*InitializeResponse, error) {
-	url := fmt.Sprintf("%s/transaction/initialize", ChapaBaseURL)
+	url := fmt.Sprintf("%s/transaction/initialize", c.BaseURL)
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
@@ -85,7 +92,7 @@ type VerifyResponse struct {
 }
 
 func (c *ChapaClient) VerifyTransaction(txRef string) (*VerifyResponse, error) {
-	url := fmt.Sprintf("%s/transaction/verify/%s", ChapaBaseURL, txRef)
+	url := fmt.Sprintf("%s/transaction/verify/%s", c.BaseURL, txRef)
 
 	r, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -108,3 +115,53 @@ func (c *ChapaClient) VerifyTransaction(txRef string) (*VerifyResponse, error) {
 
 	return &chapaResp, nil
 }
+
+type RefundRequest struct {
+	Reason string `json:"reason,omitempty"`
+	Amount string `json:"amount,omitempty"`
+}
+
+type RefundResponse struct {
+	Message interface{} `json:"message"`
+	Status  string      `json:"status"`
+	Data    struct {
+		RefundReference string `json:"ref_id"`
+		Amount          string `json:"amount"`
+	} `json:"data"`
+}
+
+// RefundTransaction refunds a verified transaction. An empty amount refunds
+// the full transaction.
+func (c *ChapaClient) RefundTransaction(txRef string, req *RefundRequest) (*RefundResponse, error) {
+	url := fmt.Sprintf("%s/refund/%s", c.BaseURL, txRef)
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Authorization", "Bearer "+c.SecretKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chapaResp RefundResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chapaResp); err != nil {
+		return nil, err
+	}
+
+	if chapaResp.Status != "success" {
+		return nil, fmt.Errorf("chapa refund failed: %v", chapaResp.Message)
+	}
+
+	return &chapaResp, nil
+}
@@ -0,0 +1,67 @@
+package chapa
+
+import (
+	"fmt"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
+)
+
+// ChapaClient implements provider.PaymentProvider on top of the Chapa API.
+var _ provider.PaymentProvider = (*ChapaClient)(nil)
+
+func (c *ChapaClient) Name() string {
+	return "chapa"
+}
+
+func (c *ChapaClient) Initialize(req *provider.InitializeRequest) (string, error) {
+	resp, err := c.InitializeTransaction(&InitializeRequest{
+		Amount:      fmt.Sprintf("%.2f", req.Amount),
+		Currency:    req.Currency,
+		Email:       req.Email,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		TxRef:       req.TxRef,
+		CallbackURL: req.CallbackURL,
+		ReturnURL:   req.ReturnURL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Data.CheckoutURL, nil
+}
+
+func (c *ChapaClient) Verify(txRef string) (*provider.VerifyResult, error) {
+	resp, err := c.VerifyTransaction(txRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// The top-level status only says the API call worked; the transaction
+	// status is in data. Older responses without it are treated as paid.
+	status := provider.StatusFailed
+	switch resp.Data.Status {
+	case "success":
+		status = provider.StatusSuccess
+	case "pending":
+		status = provider.StatusPending
+	case "":
+		if resp.Status == "success" {
+			status = provider.StatusSuccess
+		}
+	}
+
+	return &provider.VerifyResult{TxRef: txRef, Status: status}, nil
+}
+
+func (c *ChapaClient) Refund(txRef string, amount float64, reason string) (*provider.RefundResult, error) {
+	req := &RefundRequest{Reason: reason}
+	if amount > 0 {
+		req.Amount = fmt.Sprintf("%.2f", amount)
+	}
+
+	resp, err := c.RefundTransaction(txRef, req)
+	if err != nil {
+		return nil, err
+	}
+	return &provider.RefundResult{Reference: resp.Data.RefundReference}, nil
+}
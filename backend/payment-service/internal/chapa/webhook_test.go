@@ -0,0 +1,59 @@
+package chapa
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseWebhook(t *testing.T) {
+	t.Setenv("CHAPA_WEBHOOK_SECRET", "")
+	client := &ChapaClient{SecretKey: "secret"}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   string
+		wantAmount   float64
+		wantCurrency string
+	}{
+		{"amount as string", `{"event":"charge.success","tx_ref":"tx-1","status":"success","amount":"100.50","currency":"ETB"}`, provider.StatusSuccess, 100.5, "ETB"},
+		{"amount as number", `{"event":"charge.success","tx_ref":"tx-1","status":"success","amount":100.5,"currency":"USD"}`, provider.StatusSuccess, 100.5, "USD"},
+		{"no amount", `{"event":"charge.failed","tx_ref":"tx-1","status":"failed"}`, provider.StatusFailed, 0, ""},
+		{"pending", `{"tx_ref":"tx-1","status":"pending","amount":"5"}`, provider.StatusPending, 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification, err := client.ParseWebhook([]byte(tt.body), sign("secret", tt.body))
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if notification.TxRef != "tx-1" || notification.Status != tt.wantStatus ||
+				notification.Amount != tt.wantAmount || notification.Currency != tt.wantCurrency {
+				t.Errorf("got %+v, want status %s amount %v %s", notification, tt.wantStatus, tt.wantAmount, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestParseWebhookRejectsBadSignature(t *testing.T) {
+	t.Setenv("CHAPA_WEBHOOK_SECRET", "")
+	client := &ChapaClient{SecretKey: "secret"}
+	body := `{"tx_ref":"tx-1","status":"success","amount":"100"}`
+
+	for _, signature := range []string{"", sign("other", body), sign("secret", body+" ")} {
+		if _, err := client.ParseWebhook([]byte(body), signature); !errors.Is(err, provider.ErrInvalidSignature) {
+			t.Errorf("signature %q: got %v, want ErrInvalidSignature", signature, err)
+		}
+	}
+}
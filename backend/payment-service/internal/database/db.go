@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/idempotency"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -31,5 +33,5 @@ func ConnectDB() {
 	}
 
 	log.Println("Connected to Database")
-	DB.AutoMigrate(&models.Payment{})
+	DB.AutoMigrate(&models.Payment{}, &models.Refund{}, &idempotency.Record{}, &outbox.Message{}, &models.WebhookEvent{}, &models.RefundJob{}, &models.RefundJobItem{})
 }
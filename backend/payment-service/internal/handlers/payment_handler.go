@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"io"
 	"net/http"
 
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -16,22 +18,26 @@ func NewPaymentHandler(service service.PaymentService) *PaymentHandler {
 }
 
 type InitializePaymentRequest struct {
-	BookingID uint    `json:"booking_id" binding:"required"`
-	UserID    uint    `json:"user_id" binding:"required"`
-	Amount    float64 `json:"amount" binding:"required,gt=0"`
-	Email     string  `json:"email" binding:"required,email"`
-	FirstName string  `json:"first_name" binding:"required"`
-	LastName  string  `json:"last_name" binding:"required"`
+	BookingID uint   `json:"booking_id" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	FirstName string `json:"first_name" binding:"required"`
+	LastName  string `json:"last_name" binding:"required"`
 }
 
 // @Summary Initialize Payment
-// @Description Initialize a payment with Chapa
+// @Description Initialize a payment for the caller's pending booking with the configured payment provider. The amount and currency are taken from the booking.
 // @Tags payments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param input body InitializePaymentRequest true "Payment Input"
+// @Param Idempotency-Key header string false "Replays the first response for retries of the same request"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/initialize [post]
 func (h *PaymentHandler) InitializePayment(c *gin.Context) {
 	var req InitializePaymentRequest
@@ -40,9 +46,24 @@ func (h *PaymentHandler) InitializePayment(c *gin.Context) {
 		return
 	}
 
-	checkoutURL, err := h.service.InitializePayment(req.BookingID, req.UserID, req.Amount, req.Email, req.FirstName, req.LastName)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	checkoutURL, err := h.service.InitializePayment(req.BookingID, uint(userID.(float64)), req.Email, req.FirstName, req.LastName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err {
+		case models.ErrBookingNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case models.ErrBookingNotPayable:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -57,6 +78,7 @@ func (h *PaymentHandler) InitializePayment(c *gin.Context) {
 // @Param tx_ref path string true "Transaction Reference"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /payments/verify/{tx_ref} [get]
 func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 	txRef := c.Param("tx_ref")
@@ -67,9 +89,183 @@ func (h *PaymentHandler) VerifyPayment(c *gin.Context) {
 
 	payment, err := h.service.VerifyPayment(txRef)
 	if err != nil {
+		if err == models.ErrPaymentPending {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Payment verified successfully", "payment": payment})
 }
+
+// @Summary Payment Webhook
+// @Description Receive a signed transaction update from the payment provider. A successful charge whose amount or currency differs from the payment is rejected and the payment flagged for an admin.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param Chapa-Signature header string false "Hex HMAC-SHA256 of the body"
+// @Param x-chapa-signature header string false "Hex HMAC-SHA256 of the body"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /payments/callback [post]
+func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	signature := c.GetHeader("x-chapa-signature")
+	if signature == "" {
+		signature = c.GetHeader("Chapa-Signature")
+	}
+
+	if err := h.service.HandleWebhook(body, signature); err != nil {
+		switch err {
+		case models.ErrInvalidSignature:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case models.ErrPaymentNotFound, models.ErrWebhookUnsupported:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case models.ErrWebhookMismatch:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			// Any other failure is retried by the provider
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed"})
+}
+
+// @Summary Payment Callback
+// @Description Callback the provider redirects to after checkout; the transaction is verified with the provider
+// @Tags payments
+// @Produce json
+// @Param trx_ref query string true "Transaction Reference"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /payments/callback [get]
+func (h *PaymentHandler) PaymentCallback(c *gin.Context) {
+	txRef := c.Query("trx_ref")
+	if txRef == "" {
+		txRef = c.Query("tx_ref")
+	}
+	if txRef == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transaction reference is required"})
+		return
+	}
+
+	// Query parameters are unsigned, so the status is taken from the provider
+	payment, err := h.service.VerifyPayment(txRef)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment verified successfully", "payment": payment})
+}
+
+type RefundPaymentRequest struct {
+	// Amount to refund; omit or send 0 to refund the remaining balance
+	Amount float64 `json:"amount" binding:"gte=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// @Summary Refund Payment
+// @Description Fully or partially refund a successful payment (Admin only)
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tx_ref path string true "Transaction Reference"
+// @Param input body RefundPaymentRequest true "Refund Input"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /payments/{tx_ref}/refunds [post]
+func (h *PaymentHandler) RefundPayment(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return
+	}
+
+	var req RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.service.RefundPayment(c.Param("tx_ref"), req.Amount, req.Reason)
+	if err != nil {
+		if err == models.ErrPaymentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if err == models.ErrPaymentNotRefundable || err == models.ErrInvalidRefundAmount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "refund": refund})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payment refunded successfully", "refund": refund})
+}
+
+// @Summary Get Payment Refunds
+// @Description Get a payment with its refund history (owner or Admin)
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param tx_ref path string true "Transaction Reference"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /payments/{tx_ref}/refunds [get]
+func (h *PaymentHandler) GetRefunds(c *gin.Context) {
+	payment, err := h.service.GetPayment(c.Param("tx_ref"))
+	if err != nil {
+		if err == models.ErrPaymentNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
+		}
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	if uid, ok := userID.(float64); role != "admin" && (!ok || uint(uid) != payment.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this payment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment": payment, "refunds": payment.Refunds})
+}
+
+// @Summary Get Flagged Payments
+// @Description Get flagged payments, such as payments for cancelled bookings or webhooks that reported another amount (Admin only)
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /payments/reconciliation/flagged [get]
+func (h *PaymentHandler) GetFlaggedPayments(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return
+	}
+
+	payments, err := h.service.GetFlaggedPayments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flagged payments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payments": payments})
+}
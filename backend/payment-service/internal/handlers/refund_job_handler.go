@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get Refund Jobs
+// @Description List the jobs refunding the bookings of cancelled events, newest first, with their progress (Admin only)
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /payments/refund-jobs [get]
+func (h *PaymentHandler) GetRefundJobs(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return
+	}
+
+	jobs, err := h.service.GetRefundJobs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refund jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// @Summary Get Refund Job
+// @Description Get a refund job with the refund of each booking and a report of the refunds that failed (Admin only)
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund Job ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /payments/refund-jobs/{id} [get]
+func (h *PaymentHandler) GetRefundJob(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund job ID"})
+		return
+	}
+
+	job, err := h.service.GetRefundJob(uint(id))
+	if err != nil {
+		refundJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "failed": failedRefunds(job)})
+}
+
+// @Summary Retry Refund Job
+// @Description Queue the failed refunds of a refund job again (Admin only)
+// @Tags payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Refund Job ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /payments/refund-jobs/{id}/retry [post]
+func (h *PaymentHandler) RetryRefundJob(c *gin.Context) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund job ID"})
+		return
+	}
+
+	job, err := h.service.RetryRefundJob(uint(id))
+	if err != nil {
+		refundJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Failed refunds queued again", "job": job})
+}
+
+func refundJobError(c *gin.Context, err error) {
+	if err == models.ErrRefundJobNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch refund job"})
+}
+
+// failedRefunds returns the items of a job whose refund failed.
+func failedRefunds(job *models.RefundJob) []models.RefundJobItem {
+	failed := []models.RefundJobItem{}
+	for _, item := range job.Items {
+		if item.Status == models.RefundJobItemFailed {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
+	"github.com/streadway/amqp"
+)
+
+type Refunder interface {
+	RefundBooking(bookingID uint, amount float64, reason string) error
+	StartEventRefund(eventID uint, reason string, bookings []events.BookingRefund) (*models.RefundJob, error)
+}
+
+// StartRefundConsumer processes refund requests published by the booking service.
+func StartRefundConsumer(refunder Refunder) {
+	err := Client.Consume(RefundRequestedQueue, func(d amqp.Delivery) error {
+		var message events.RefundRequested
+		if _, err := events.Unmarshal(d.Body, events.TypeRefundRequested, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding refund request: %v", err))
+		}
+
+		log.Printf("Received refund request for booking %d: %.2f", message.BookingID, message.Amount)
+		if err := refunder.RefundBooking(message.BookingID, message.Amount, message.Reason); err != nil {
+			err = fmt.Errorf("error refunding booking %d: %w", message.BookingID, err)
+			if errors.Is(err, models.ErrPaymentNotFound) || errors.Is(err, models.ErrPaymentNotRefundable) || errors.Is(err, models.ErrInvalidRefundAmount) {
+				return shared.Permanent(err)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to register refund consumer: %v", err)
+	}
+
+	err = Client.Consume(EventRefundRequestedQueue, func(d amqp.Delivery) error {
+		var message events.EventRefundRequested
+		if _, err := events.Unmarshal(d.Body, events.TypeEventRefundRequested, &message); err != nil {
+			return shared.Permanent(fmt.Errorf("error decoding event refund request: %v", err))
+		}
+
+		log.Printf("Received refund request for %d bookings of event %d", len(message.Bookings), message.EventID)
+		if _, err := refunder.StartEventRefund(message.EventID, message.Reason, message.Bookings); err != nil {
+			return fmt.Errorf("error starting refund job for event %d: %w", message.EventID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to register event refund consumer: %v", err)
+	}
+}
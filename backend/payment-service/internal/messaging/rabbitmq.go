@@ -1,65 +1,43 @@
 package messaging
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
 
-	"github.com/streadway/amqp"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	shared "github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
 )
 
-var Channel *amqp.Channel
+// Client is the service's long-lived RabbitMQ connection.
+var Client *shared.Client
 
-func ConnectRabbitMQ() {
-	connStr := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("RABBITMQ_USER"),
-		os.Getenv("RABBITMQ_PASS"),
-		os.Getenv("RABBITMQ_HOST"),
-		os.Getenv("RABBITMQ_PORT"),
-	)
+func ConnectRabbitMQ(dedupStore dedup.Store) {
+	Client = shared.NewClient(shared.URLFromEnv())
+	// Redelivered messages are acked without being processed twice
+	Client.Deduplicate(dedup.NewFilter(dedupStore))
 
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to RabbitMQ: ", err)
-	}
-
-	Channel, err = conn.Channel()
-	if err != nil {
-		log.Fatal("Failed to open a channel: ", err)
-	}
+	Client.DeclareTopology(shared.DeclareQueues(PaymentSuccessQueue, RefundRequestedQueue, EventRefundRequestedQueue))
+	// payment_refunded is a fanout exchange: every interested service binds its own queue.
+	// The queues are declared here too so refunds are kept until the subscribers start.
+	Client.DeclareTopology(shared.DeclareFanout(PaymentRefundedExchange, PaymentRefundedSubscriberQueues...))
 
-	_, err = Channel.QueueDeclare(
-		"payment_success", // name
-		true,              // durable
-		false,             // delete when unused
-		false,             // exclusive
-		false,             // no-wait
-		nil,               // arguments
-	)
-	if err != nil {
-		log.Fatal("Failed to declare a queue: ", err)
+	if err := Client.Connect(); err != nil {
+		log.Printf("RabbitMQ not available yet, retrying in the background: %v", err)
 	}
 }
 
-func PublishPaymentSuccess(bookingID uint, userID uint, amount float64) {
-	message := map[string]interface{}{
-		"booking_id": bookingID,
-		"user_id":    userID,
-		"amount":     amount,
-	}
-	body, _ := json.Marshal(message)
+const (
+	PaymentSuccessQueue  = "payment_success"
+	RefundRequestedQueue = "refund_requested"
+	// EventRefundRequestedQueue carries the bookings of a cancelled event to refund as one job.
+	EventRefundRequestedQueue = "event_refund_requested"
+	PaymentRefundedExchange   = "payment_refunded"
 
-	err := Channel.Publish(
-		"",                // exchange
-		"payment_success", // routing key
-		false,             // mandatory
-		false,             // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		})
-	if err != nil {
-		log.Println("Failed to publish message: ", err)
-	}
+	// Producer is the source attribute of the events this service publishes.
+	Producer = "payment-service"
+)
+
+// PaymentRefundedSubscriberQueues are the queues bound to PaymentRefundedExchange.
+var PaymentRefundedSubscriberQueues = []string{
+	"booking-service.payment_refunded",
+	"notification-service.payment_refunded",
 }
@@ -1,23 +1,83 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type PaymentStatus string
 
 const (
-	PaymentStatusPending PaymentStatus = "pending"
-	PaymentStatusSuccess PaymentStatus = "success"
-	PaymentStatusFailed  PaymentStatus = "failed"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusSuccess           PaymentStatus = "success"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
 type Payment struct {
 	gorm.Model
-	BookingID uint          `gorm:"not null" json:"booking_id"`
-	UserID    uint          `gorm:"not null" json:"user_id"`
-	Amount    float64       `gorm:"not null" json:"amount"`
-	Currency  string        `gorm:"default:'ETB'" json:"currency"`
-	TxRef     string        `gorm:"uniqueIndex;not null" json:"tx_ref"`
-	Status    PaymentStatus `gorm:"default:'pending'" json:"status"`
+	BookingID      uint          `gorm:"not null" json:"booking_id"`
+	UserID         uint          `gorm:"not null" json:"user_id"`
+	Amount         float64       `gorm:"not null" json:"amount"`
+	Currency       string        `gorm:"default:'ETB'" json:"currency"`
+	TxRef          string        `gorm:"uniqueIndex;not null" json:"tx_ref"`
+	Status         PaymentStatus `gorm:"default:'pending'" json:"status"`
+	RefundedAmount float64       `gorm:"default:0" json:"refunded_amount"`
+	FailureReason  string        `json:"failure_reason"`
+	Refunds        []Refund      `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
+
+	// Reconciliation
+	BookingCheckedAt *time.Time `json:"booking_checked_at"`
+	ReconcileFlag    string     `gorm:"index" json:"reconcile_flag"`
+	ReconcileNote    string     `json:"reconcile_note"`
+}
+
+// ReconcileFlagBookingCancelled marks a payment that succeeded for a booking
+// that had already been cancelled.
+const ReconcileFlagBookingCancelled = "paid_booking_cancelled"
+
+// ReconcileFlagWebhookMismatch marks a payment whose provider reported a
+// successful charge of a different amount or currency.
+const ReconcileFlagWebhookMismatch = "webhook_amount_mismatch"
+
+type RefundStatus string
+
+const (
+	RefundStatusPending RefundStatus = "pending"
+	RefundStatusSuccess RefundStatus = "success"
+	RefundStatusFailed  RefundStatus = "failed"
+)
+
+// Refund is a full or partial refund of a payment through the provider.
+type Refund struct {
+	gorm.Model
+	PaymentID     uint         `gorm:"not null;index" json:"payment_id"`
+	Amount        float64      `gorm:"not null" json:"amount"`
+	Reason        string       `json:"reason"`
+	Status        RefundStatus `gorm:"default:'pending'" json:"status"`
+	ProviderRef   string       `json:"provider_ref"`
+	FailureReason string       `json:"failure_reason"`
+}
+
+var (
+	ErrPaymentNotFound      = &Error{Message: "Payment not found"}
+	ErrPaymentNotRefundable = &Error{Message: "Only successful payments can be refunded"}
+	ErrInvalidRefundAmount  = &Error{Message: "Refund amount exceeds the refundable balance"}
+	ErrPaymentPending       = &Error{Message: "Payment has not been completed yet"}
+	ErrInvalidSignature     = &Error{Message: "Invalid webhook signature"}
+	ErrWebhookUnsupported   = &Error{Message: "The payment provider does not send webhooks"}
+	ErrWebhookMismatch      = &Error{Message: "Webhook amount or currency does not match the payment"}
+	ErrBookingNotFound      = &Error{Message: "Booking not found"}
+	ErrForbidden            = &Error{Message: "You are not allowed to pay for this booking"}
+	ErrBookingNotPayable    = &Error{Message: "Only pending bookings can be paid"}
+)
+
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
 }
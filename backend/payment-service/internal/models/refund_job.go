@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundJobStatus string
+
+const (
+	RefundJobStatusRunning   RefundJobStatus = "running"
+	RefundJobStatusCompleted RefundJobStatus = "completed"
+	// RefundJobStatusFailed marks a finished job with at least one refund
+	// that failed; its items say which.
+	RefundJobStatusFailed RefundJobStatus = "completed_with_failures"
+)
+
+// RefundJob refunds every confirmed booking of a cancelled event. Its
+// counters are recounted from the items after each batch.
+type RefundJob struct {
+	gorm.Model
+	EventID        uint            `gorm:"uniqueIndex;not null" json:"event_id"`
+	Reason         string          `json:"reason"`
+	Status         RefundJobStatus `gorm:"default:'running';index" json:"status"`
+	Total          int             `json:"total"`
+	Refunded       int             `json:"refunded"`
+	Skipped        int             `json:"skipped"`
+	Failed         int             `json:"failed"`
+	RefundedAmount float64         `gorm:"default:0" json:"refunded_amount"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+	Items          []RefundJobItem `gorm:"foreignKey:JobID" json:"items,omitempty"`
+}
+
+type RefundJobItemStatus string
+
+const (
+	RefundJobItemPending  RefundJobItemStatus = "pending"
+	RefundJobItemRefunded RefundJobItemStatus = "refunded"
+	// RefundJobItemSkipped is a booking whose payment was already refunded.
+	RefundJobItemSkipped RefundJobItemStatus = "skipped"
+	RefundJobItemFailed  RefundJobItemStatus = "failed"
+)
+
+// RefundJobItem is the refund of one booking within a RefundJob.
+type RefundJobItem struct {
+	gorm.Model
+	JobID          uint                `gorm:"not null;uniqueIndex:idx_refund_job_booking" json:"job_id"`
+	Job            *RefundJob          `gorm:"foreignKey:JobID" json:"-"`
+	BookingID      uint                `gorm:"not null;uniqueIndex:idx_refund_job_booking" json:"booking_id"`
+	UserID         uint                `json:"user_id"`
+	Amount         float64             `json:"amount"` // the booking's total
+	Status         RefundJobItemStatus `gorm:"default:'pending';index" json:"status"`
+	Attempts       int                 `gorm:"default:0" json:"attempts"`
+	RefundID       *uint               `json:"refund_id"`
+	RefundedAmount float64             `gorm:"default:0" json:"refunded_amount"`
+	FailureReason  string              `json:"failure_reason"`
+}
+
+var ErrRefundJobNotFound = &Error{Message: "Refund job not found"}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Results recorded against a webhook once it has been handled.
+const (
+	WebhookResultProcessed = "processed"
+	WebhookResultDuplicate = "duplicate"
+	WebhookResultIgnored   = "ignored"
+	WebhookResultRejected  = "rejected"
+	WebhookResultError     = "error"
+)
+
+// WebhookEvent is the raw body of a verified provider webhook, kept for
+// auditing and replaying transaction updates.
+type WebhookEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Event     string    `json:"event"`
+	TxRef     string    `gorm:"index;not null" json:"tx_ref"`
+	Status    string    `json:"status"`
+	Payload   string    `gorm:"type:text;not null" json:"payload"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error"`
+}
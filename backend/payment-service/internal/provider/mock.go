@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcomes the mock gateway can be told to produce.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFail    = "fail"
+	OutcomeTimeout = "timeout"
+)
+
+type mockTransaction struct {
+	req    InitializeRequest
+	status string
+}
+
+// MockProvider is an offline payment gateway. It serves its own checkout page
+// and settles a transaction only when its checkout is completed, then calls
+// the transaction's callback URL like a real gateway. Abandoned checkouts
+// stay pending, so the reconciler can be exercised offline.
+type MockProvider struct {
+	// BaseURL is the public URL of the payment service, used for checkout links.
+	BaseURL string
+	// Outcome settles checkouts completed without pressing Pay or Decline,
+	// e.g. by a script posting to the checkout URL. "fail" also declines
+	// refunds and "timeout" makes every call block for Timeout.
+	Outcome string
+	// Timeout is how long calls block before failing when Outcome is "timeout".
+	Timeout time.Duration
+
+	mu           sync.Mutex
+	transactions map[string]*mockTransaction
+}
+
+var _ PaymentProvider = (*MockProvider)(nil)
+
+// NewMockProvider configures the mock gateway from MOCK_PAYMENT_OUTCOME,
+// MOCK_PAYMENT_TIMEOUT and PAYMENT_PUBLIC_URL.
+func NewMockProvider() *MockProvider {
+	baseURL := os.Getenv("PAYMENT_PUBLIC_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	outcome := os.Getenv("MOCK_PAYMENT_OUTCOME")
+	if outcome == "" {
+		outcome = OutcomeSuccess
+	}
+
+	timeout, err := time.ParseDuration(os.Getenv("MOCK_PAYMENT_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &MockProvider{
+		BaseURL:      baseURL,
+		Outcome:      outcome,
+		Timeout:      timeout,
+		transactions: make(map[string]*mockTransaction),
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) simulateTimeout() error {
+	if p.Outcome != OutcomeTimeout {
+		return nil
+	}
+	time.Sleep(p.Timeout)
+	return ErrTimeout
+}
+
+func (p *MockProvider) Initialize(req *InitializeRequest) (string, error) {
+	if err := p.simulateTimeout(); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.transactions[req.TxRef] = &mockTransaction{req: *req, status: StatusPending}
+	p.mu.Unlock()
+
+	return fmt.Sprintf("%s/api/payments/mock/checkout/%s", p.BaseURL, url.PathEscape(req.TxRef)), nil
+}
+
+func (p *MockProvider) Verify(txRef string) (*VerifyResult, error) {
+	if err := p.simulateTimeout(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tx, ok := p.transactions[txRef]
+	if !ok {
+		return nil, fmt.Errorf("mock transaction %s not found", txRef)
+	}
+	return &VerifyResult{TxRef: txRef, Status: tx.status}, nil
+}
+
+func (p *MockProvider) Refund(txRef string, amount float64, reason string) (*RefundResult, error) {
+	if err := p.simulateTimeout(); err != nil {
+		return nil, err
+	}
+	if p.Outcome == OutcomeFail {
+		return nil, errors.New("mock refund declined")
+	}
+
+	// Transactions are kept in memory, so refunds of payments made before a
+	// restart are accepted as well.
+	return &RefundResult{Reference: fmt.Sprintf("mock-refund-%s-%d", txRef, time.Now().UnixNano())}, nil
+}
+
+var mockCheckoutPage = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Mock Checkout - TicketHub</title>
+	<style>
+		body { font-family: Arial, sans-serif; background-color: #f4f4f4; padding: 20px; }
+		.container { max-width: 480px; margin: 40px auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); text-align: center; }
+		.amount { font-size: 32px; font-weight: bold; margin: 20px 0; }
+		button { padding: 12px 24px; margin: 5px; border: none; border-radius: 4px; font-weight: bold; color: #ffffff; cursor: pointer; }
+		.pay { background-color: #28a745; }
+		.fail { background-color: #dc3545; }
+		.note { margin-top: 20px; font-size: 12px; color: #666; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<h2>Mock Payment Gateway</h2>
+		<p>Transaction {{.TxRef}}</p>
+		<div class="amount">{{printf "%.2f" .Amount}} {{.Currency}}</div>
+		<form method="POST">
+			<button class="pay" name="outcome" value="success">Pay</button>
+			<button class="fail" name="outcome" value="fail">Decline</button>
+		</form>
+		<p class="note">No real money is charged. This page is served by the payment service's offline provider.</p>
+	</div>
+</body>
+</html>`))
+
+// RegisterRoutes serves the mock checkout page.
+func (p *MockProvider) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/payments/mock/checkout/:tx_ref", p.showCheckout)
+	r.POST("/payments/mock/checkout/:tx_ref", p.completeCheckout)
+}
+
+func (p *MockProvider) showCheckout(c *gin.Context) {
+	p.mu.Lock()
+	tx, ok := p.transactions[c.Param("tx_ref")]
+	var req InitializeRequest
+	if ok {
+		req = tx.req
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		c.String(http.StatusNotFound, "Transaction not found")
+		return
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	mockCheckoutPage.Execute(c.Writer, req)
+}
+
+func (p *MockProvider) completeCheckout(c *gin.Context) {
+	txRef := c.Param("tx_ref")
+	outcome := c.PostForm("outcome")
+	if outcome == "" {
+		outcome = p.Outcome
+	}
+
+	p.mu.Lock()
+	tx, ok := p.transactions[txRef]
+	var req InitializeRequest
+	if ok {
+		// A transaction is settled once
+		if tx.status == StatusPending {
+			if outcome == OutcomeFail {
+				tx.status = StatusFailed
+			} else {
+				tx.status = StatusSuccess
+			}
+		}
+		req = tx.req
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		c.String(http.StatusNotFound, "Transaction not found")
+		return
+	}
+
+	if req.CallbackURL != "" {
+		go notifyCallback(req.CallbackURL, txRef)
+	}
+	c.Redirect(http.StatusSeeOther, req.ReturnURL)
+}
+
+// notifyCallback tells the payment service a checkout was completed, like
+// a real gateway's callback. The callback verifies the transaction, so it
+// carries nothing but the reference.
+func notifyCallback(callbackURL, txRef string) {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		fmt.Printf("Invalid mock callback URL %s: %v\n", callbackURL, err)
+		return
+	}
+	query := u.Query()
+	query.Set("trx_ref", txRef)
+	u.RawQuery = query.Encode()
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		fmt.Printf("Mock callback for %s failed: %v\n", txRef, err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestMock(outcome string) (*MockProvider, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	p := &MockProvider{BaseURL: "http://payments.test", Outcome: outcome, transactions: map[string]*mockTransaction{}}
+	r := gin.New()
+	p.RegisterRoutes(r.Group("/api"))
+	return p, r
+}
+
+func completeMockCheckout(r *gin.Engine, txRef, outcome string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	if outcome != "" {
+		form.Set("outcome", outcome)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/mock/checkout/"+txRef, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMockProviderSettlesOnlyOnCheckout(t *testing.T) {
+	tests := []struct {
+		name       string
+		outcome    string // configured
+		pressed    string // button on the checkout page
+		wantStatus string
+	}{
+		{"pay", OutcomeSuccess, OutcomeSuccess, StatusSuccess},
+		{"decline", OutcomeSuccess, OutcomeFail, StatusFailed},
+		{"pay with fail configured", OutcomeFail, OutcomeSuccess, StatusSuccess},
+		{"scripted with success configured", OutcomeSuccess, "", StatusSuccess},
+		{"scripted with fail configured", OutcomeFail, "", StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, r := newTestMock(tt.outcome)
+			if _, err := p.Initialize(&InitializeRequest{TxRef: "tx-1", Amount: 100, ReturnURL: "http://shop.test/done"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Abandoned checkouts stay pending however often they are verified
+			for i := 0; i < 2; i++ {
+				result, err := p.Verify("tx-1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Status != StatusPending {
+					t.Fatalf("status before checkout is %s, want %s", result.Status, StatusPending)
+				}
+			}
+
+			w := completeMockCheckout(r, "tx-1", tt.pressed)
+			if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "http://shop.test/done" {
+				t.Fatalf("checkout answered %d to %q, want a redirect to the return URL", w.Code, w.Header().Get("Location"))
+			}
+
+			result, err := p.Verify("tx-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("status after checkout is %s, want %s", result.Status, tt.wantStatus)
+			}
+
+			// A completed checkout cannot be settled the other way
+			completeMockCheckout(r, "tx-1", OutcomeFail)
+			if again, _ := p.Verify("tx-1"); again.Status != tt.wantStatus {
+				t.Errorf("status changed to %s after a second checkout", again.Status)
+			}
+		})
+	}
+}
+
+func TestMockProviderCallsCallback(t *testing.T) {
+	called := make(chan string, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- r.URL.Query().Get("trx_ref")
+	}))
+	defer callback.Close()
+
+	p, r := newTestMock(OutcomeSuccess)
+	p.Initialize(&InitializeRequest{TxRef: "tx-2", CallbackURL: callback.URL + "/api/payments/callback"})
+	completeMockCheckout(r, "tx-2", OutcomeSuccess)
+
+	select {
+	case txRef := <-called:
+		if txRef != "tx-2" {
+			t.Errorf("callback for %q, want tx-2", txRef)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+}
+
+func TestMockProviderUnknownTransaction(t *testing.T) {
+	p, r := newTestMock(OutcomeSuccess)
+	if _, err := p.Verify("missing"); err == nil {
+		t.Error("Verify found a transaction that was never initialized")
+	}
+	if w := completeMockCheckout(r, "missing", OutcomeSuccess); w.Code != http.StatusNotFound {
+		t.Errorf("checkout of an unknown transaction answered %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"errors"
+)
+
+// Transaction statuses reported by a provider's Verify.
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusPending = "pending"
+)
+
+var (
+	ErrTimeout          = errors.New("payment provider timed out")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+type InitializeRequest struct {
+	Amount      float64
+	Currency    string
+	Email       string
+	FirstName   string
+	LastName    string
+	TxRef       string
+	CallbackURL string
+	ReturnURL   string
+}
+
+type VerifyResult struct {
+	TxRef  string
+	Status string
+}
+
+type RefundResult struct {
+	Reference string
+}
+
+// PaymentProvider is a payment gateway the payment service can charge and
+// refund through.
+type PaymentProvider interface {
+	Name() string
+	// Initialize starts a transaction and returns the URL the customer pays at.
+	Initialize(req *InitializeRequest) (string, error)
+	Verify(txRef string) (*VerifyResult, error)
+	// Refund refunds amount of a transaction; an amount of 0 refunds it in full.
+	Refund(txRef string, amount float64, reason string) (*RefundResult, error)
+}
+
+// WebhookNotification is a transaction update pushed by a provider.
+type WebhookNotification struct {
+	Event     string
+	TxRef     string
+	Status    string
+	Reference string
+	// Amount and Currency the provider charged, checked against the payment
+	Amount   float64
+	Currency string
+}
+
+// WebhookVerifier is implemented by providers that push transaction updates.
+type WebhookVerifier interface {
+	// ParseWebhook checks the signature of a webhook body and decodes it. It
+	// returns ErrInvalidSignature when the body was not signed by the provider.
+	ParseWebhook(body []byte, signature string) (*WebhookNotification, error)
+}
@@ -1,32 +1,168 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/database"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentRepository interface {
 	CreatePayment(payment *models.Payment) error
 	UpdatePayment(payment *models.Payment) error
 	FindByTxRef(txRef string) (*models.Payment, error)
+	FindByID(id uint) (*models.Payment, error)
+	// FindByIDForUpdate locks the payment row until the surrounding transaction ends.
+	FindByIDForUpdate(id uint) (*models.Payment, error)
+	FindPaidByBookingID(bookingID uint) (*models.Payment, error)
+	FindRefundedByBookingID(bookingID uint) (*models.Payment, error)
+	// FindPendingBefore returns pending payments created before the given time, oldest first.
+	FindPendingBefore(before time.Time, limit int) ([]models.Payment, error)
+	// FindUncheckedPaid returns successful payments whose booking has not been
+	// checked yet, last updated before the given time.
+	FindUncheckedPaid(before time.Time, limit int) ([]models.Payment, error)
+	FindFlagged() ([]models.Payment, error)
+	// MarkBookingChecked records that the payment's booking was reconciled,
+	// with a flag when it needs attention.
+	MarkBookingChecked(id uint, flag, note string) error
+	// Flag flags a payment for an admin to look at.
+	Flag(id uint, flag, note string) error
+	CreateRefund(refund *models.Refund) error
+	UpdateRefund(refund *models.Refund) error
+	GetPendingRefundTotal(paymentID uint) (float64, error)
+	EnqueueOutboxMessage(message *outbox.Message) error
+	CreateWebhookEvent(event *models.WebhookEvent) error
+	UpdateWebhookEvent(event *models.WebhookEvent) error
+	// Transaction runs fn against a repository bound to a single database transaction.
+	Transaction(fn func(repo PaymentRepository) error) error
 }
 
-type paymentRepository struct{}
+type paymentRepository struct {
+	tx *gorm.DB
+}
 
 func NewPaymentRepository() PaymentRepository {
 	return &paymentRepository{}
 }
 
+func (r *paymentRepository) db() *gorm.DB {
+	if r.tx != nil {
+		return r.tx
+	}
+	return database.DB
+}
+
+func (r *paymentRepository) Transaction(fn func(repo PaymentRepository) error) error {
+	return r.db().Transaction(func(tx *gorm.DB) error {
+		return fn(&paymentRepository{tx: tx})
+	})
+}
+
+func (r *paymentRepository) EnqueueOutboxMessage(message *outbox.Message) error {
+	return r.db().Create(message).Error
+}
+
 func (r *paymentRepository) CreatePayment(payment *models.Payment) error {
-	return database.DB.Create(payment).Error
+	return r.db().Create(payment).Error
 }
 
 func (r *paymentRepository) UpdatePayment(payment *models.Payment) error {
-	return database.DB.Save(payment).Error
+	return r.db().Save(payment).Error
 }
 
 func (r *paymentRepository) FindByTxRef(txRef string) (*models.Payment, error) {
 	var payment models.Payment
-	err := database.DB.Where("tx_ref = ?", txRef).First(&payment).Error
+	err := r.db().Where("tx_ref = ?", txRef).First(&payment).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) FindByID(id uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db().Preload("Refunds").First(&payment, id).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) FindByIDForUpdate(id uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, id).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) FindPaidByBookingID(bookingID uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db().Where("booking_id = ? AND status IN ?", bookingID, []models.PaymentStatus{
+		models.PaymentStatusSuccess,
+		models.PaymentStatusPartiallyRefunded,
+	}).Order("id DESC").First(&payment).Error
+	return &payment, err
+}
+
+func (r *paymentRepository) FindRefundedByBookingID(bookingID uint) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db().Where("booking_id = ? AND status = ?", bookingID, models.PaymentStatusRefunded).
+		Order("id DESC").First(&payment).Error
 	return &payment, err
 }
+
+func (r *paymentRepository) FindPendingBefore(before time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db().Where("status = ? AND created_at < ?", models.PaymentStatusPending, before).
+		Order("created_at ASC").Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) FindUncheckedPaid(before time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db().Where("status = ? AND booking_checked_at IS NULL AND updated_at < ?", models.PaymentStatusSuccess, before).
+		Order("updated_at ASC").Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) FindFlagged() ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db().Preload("Refunds").Where("reconcile_flag <> ''").Order("updated_at DESC").Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) MarkBookingChecked(id uint, flag, note string) error {
+	return r.db().Model(&models.Payment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"booking_checked_at": time.Now(),
+		"reconcile_flag":     flag,
+		"reconcile_note":     note,
+	}).Error
+}
+
+func (r *paymentRepository) Flag(id uint, flag, note string) error {
+	return r.db().Model(&models.Payment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"reconcile_flag": flag,
+		"reconcile_note": note,
+	}).Error
+}
+
+func (r *paymentRepository) CreateRefund(refund *models.Refund) error {
+	return r.db().Create(refund).Error
+}
+
+func (r *paymentRepository) UpdateRefund(refund *models.Refund) error {
+	return r.db().Save(refund).Error
+}
+
+func (r *paymentRepository) GetPendingRefundTotal(paymentID uint) (float64, error) {
+	var total float64
+	err := r.db().Model(&models.Refund{}).
+		Where("payment_id = ? AND status = ?", paymentID, models.RefundStatusPending).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *paymentRepository) CreateWebhookEvent(event *models.WebhookEvent) error {
+	return r.db().Create(event).Error
+}
+
+func (r *paymentRepository) UpdateWebhookEvent(event *models.WebhookEvent) error {
+	return r.db().Save(event).Error
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/database"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefundJobRepository interface {
+	// AddRefundJob creates the refund job of an event, or adds the items it
+	// does not have yet to the existing one, and reopens the job if any
+	// were added.
+	AddRefundJob(job *models.RefundJob, items []models.RefundJobItem) (*models.RefundJob, error)
+	// FindPendingItems returns up to limit items still to be refunded, oldest
+	// first, with their job.
+	FindPendingItems(limit int) ([]models.RefundJobItem, error)
+	UpdateItem(item *models.RefundJobItem) error
+	// RefreshProgress recounts a job's items and completes the job once none
+	// is pending.
+	RefreshProgress(jobID uint) error
+	// RetryFailedItems puts a job's failed items back in the queue and
+	// returns how many there were.
+	RetryFailedItems(jobID uint) (int64, error)
+	FindJobs() ([]models.RefundJob, error)
+	// FindJobByID returns a job with its items.
+	FindJobByID(id uint) (*models.RefundJob, error)
+}
+
+type refundJobRepository struct{}
+
+func NewRefundJobRepository() RefundJobRepository {
+	return &refundJobRepository{}
+}
+
+func (r *refundJobRepository) AddRefundJob(job *models.RefundJob, items []models.RefundJobItem) (*models.RefundJob, error) {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(models.RefundJob{EventID: job.EventID}).Attrs(models.RefundJob{Reason: job.Reason, Status: models.RefundJobStatusRunning}).FirstOrCreate(job).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		for i := range items {
+			items[i].JobID = job.ID
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&items)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return tx.Model(job).Updates(map[string]interface{}{
+			"status":       models.RefundJobStatusRunning,
+			"completed_at": nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, r.RefreshProgress(job.ID)
+}
+
+func (r *refundJobRepository) FindPendingItems(limit int) ([]models.RefundJobItem, error) {
+	var items []models.RefundJobItem
+	err := database.DB.Preload("Job").Where("status = ?", models.RefundJobItemPending).Order("id").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+func (r *refundJobRepository) UpdateItem(item *models.RefundJobItem) error {
+	// The job is only loaded for reading; its counters come from RefreshProgress
+	return database.DB.Omit("Job").Save(item).Error
+}
+
+func (r *refundJobRepository) RefreshProgress(jobID uint) error {
+	var counts []struct {
+		Status models.RefundJobItemStatus
+		Count  int
+		Amount float64
+	}
+	err := database.DB.Model(&models.RefundJobItem{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(refunded_amount), 0) AS amount").
+		Where("job_id = ?", jobID).
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{"total": 0, "refunded": 0, "skipped": 0, "failed": 0, "refunded_amount": 0.0}
+	pending := 0
+	for _, count := range counts {
+		values["total"] = values["total"].(int) + count.Count
+		switch count.Status {
+		case models.RefundJobItemRefunded:
+			values["refunded"] = count.Count
+			values["refunded_amount"] = count.Amount
+		case models.RefundJobItemSkipped:
+			values["skipped"] = count.Count
+		case models.RefundJobItemFailed:
+			values["failed"] = count.Count
+		default:
+			pending += count.Count
+		}
+	}
+	query := database.DB.Model(&models.RefundJob{}).Where("id = ?", jobID)
+	if pending == 0 {
+		values["status"] = models.RefundJobStatusCompleted
+		if values["failed"].(int) > 0 {
+			values["status"] = models.RefundJobStatusFailed
+		}
+		values["completed_at"] = time.Now()
+		// A finished job keeps the time it first completed at
+		query = query.Where("status = ?", models.RefundJobStatusRunning)
+	}
+	return query.Updates(values).Error
+}
+
+func (r *refundJobRepository) RetryFailedItems(jobID uint) (int64, error) {
+	var retried int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefundJobItem{}).
+			Where("job_id = ? AND status = ?", jobID, models.RefundJobItemFailed).
+			Updates(map[string]interface{}{"status": models.RefundJobItemPending, "attempts": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		retried = result.RowsAffected
+		if retried == 0 {
+			return nil
+		}
+		return tx.Model(&models.RefundJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+			"status":       models.RefundJobStatusRunning,
+			"completed_at": nil,
+		}).Error
+	})
+	return retried, err
+}
+
+func (r *refundJobRepository) FindJobs() ([]models.RefundJob, error) {
+	var jobs []models.RefundJob
+	err := database.DB.Order("id DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *refundJobRepository) FindJobByID(id uint) (*models.RefundJob, error) {
+	var job models.RefundJob
+	err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&job, id).Error
+	return &job, err
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+)
+
+// bookingDetails is the subset of the Booking Service's booking payload the
+// payment service needs for reconciliation.
+type bookingDetails struct {
+	ID          uint       `json:"ID"`
+	UserID      uint       `json:"user_id"`
+	Status      string     `json:"status"`
+	TotalAmount float64    `json:"total_amount"`
+	Currency    string     `json:"currency"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+}
+
+func getBookingServiceURL() string {
+	bookingServiceURL := os.Getenv("BOOKING_SERVICE_URL")
+	if bookingServiceURL == "" {
+		bookingServiceURL = "http://localhost:3002"
+	}
+	return bookingServiceURL
+}
+
+func fetchBooking(bookingID uint) (*bookingDetails, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/bookings/%d", getBookingServiceURL(), bookingID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, models.ErrBookingNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("booking service returned status %d for booking %d", resp.StatusCode, bookingID)
+	}
+
+	var body struct {
+		Booking bookingDetails `json:"booking"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body.Booking, nil
+}
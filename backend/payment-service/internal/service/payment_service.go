@@ -3,39 +3,82 @@ package service
 import (
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/chapa"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/messaging"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
 	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+	"gorm.io/gorm"
 )
 
 type PaymentService interface {
-	InitializePayment(bookingID, userID uint, amount float64, email, firstName, lastName string) (string, error)
+	// InitializePayment charges the booking's total to its owner.
+	InitializePayment(bookingID, userID uint, email, firstName, lastName string) (string, error)
 	VerifyPayment(txRef string) (*models.Payment, error)
+	// HandleWebhook applies a signed transaction update pushed by the provider.
+	HandleWebhook(body []byte, signature string) error
+	GetPayment(txRef string) (*models.Payment, error)
+	// RefundPayment refunds amount of a successful payment; an amount of 0
+	// refunds the whole remaining balance.
+	RefundPayment(txRef string, amount float64, reason string) (*models.Refund, error)
+	RefundBooking(bookingID uint, amount float64, reason string) error
+	// ReconcilePayments settles stuck pending payments and flags payments
+	// whose booking was cancelled before they succeeded.
+	ReconcilePayments() error
+	GetFlaggedPayments() ([]models.Payment, error)
+	// StartEventRefund opens the job refunding the bookings of a cancelled event.
+	StartEventRefund(eventID uint, reason string, bookings []events.BookingRefund) (*models.RefundJob, error)
+	ProcessRefundJobs() error
+	GetRefundJobs() ([]models.RefundJob, error)
+	GetRefundJob(jobID uint) (*models.RefundJob, error)
+	RetryRefundJob(jobID uint) (*models.RefundJob, error)
 }
 
 type paymentService struct {
-	repo        repository.PaymentRepository
-	chapaClient *chapa.ChapaClient
+	repo     repository.PaymentRepository
+	jobs     repository.RefundJobRepository
+	provider provider.PaymentProvider
 }
 
-func NewPaymentService(repo repository.PaymentRepository) PaymentService {
+func NewPaymentService(repo repository.PaymentRepository, jobs repository.RefundJobRepository, paymentProvider provider.PaymentProvider) PaymentService {
 	return &paymentService{
-		repo:        repo,
-		chapaClient: chapa.NewChapaClient(),
+		repo:     repo,
+		jobs:     jobs,
+		provider: paymentProvider,
 	}
 }
 
-func (s *paymentService) InitializePayment(bookingID, userID uint, amount float64, email, firstName, lastName string) (string, error) {
+func (s *paymentService) InitializePayment(bookingID, userID uint, email, firstName, lastName string) (string, error) {
+	booking, err := fetchBooking(bookingID)
+	if err != nil {
+		return "", err
+	}
+	if booking.UserID != userID {
+		return "", models.ErrForbidden
+	}
+	if booking.Status != "pending" {
+		return "", models.ErrBookingNotPayable
+	}
+
+	// The amount always comes from the booking, which was priced by the booking service
+	currency := booking.Currency
+	if currency == "" {
+		currency = "ETB"
+	}
+
 	txRef := fmt.Sprintf("tx-%d-%d-%d", userID, bookingID, time.Now().Unix())
 
 	payment := &models.Payment{
 		BookingID: bookingID,
 		UserID:    userID,
-		Amount:    amount,
-		Currency:  "ETB",
+		Amount:    booking.TotalAmount,
+		Currency:  currency,
 		TxRef:     txRef,
 		Status:    models.PaymentStatusPending,
 	}
@@ -47,25 +90,25 @@ func (s *paymentService) InitializePayment(bookingID, userID uint, amount float6
 	}
 	fmt.Println("Payment created successfully in DB")
 
-	req := &chapa.InitializeRequest{
-		Amount:      fmt.Sprintf("%.2f", amount),
-		Currency:    "ETB",
+	req := &provider.InitializeRequest{
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
 		Email:       email,
 		FirstName:   firstName,
 		LastName:    lastName,
 		TxRef:       txRef,
-		CallbackURL: "http://localhost:3004/api/payments/callback",
+		CallbackURL: callbackURL(),
 		ReturnURL:   fmt.Sprintf("http://localhost:3000/payment/success?tx_ref=%s", txRef),
 	}
 
-	resp, err := s.chapaClient.InitializeTransaction(req)
+	checkoutURL, err := s.provider.Initialize(req)
 	if err != nil {
 		payment.Status = models.PaymentStatusFailed
 		s.repo.UpdatePayment(payment)
 		return "", err
 	}
 
-	return resp.Data.CheckoutURL, nil
+	return checkoutURL, nil
 }
 
 func (s *paymentService) VerifyPayment(txRef string) (*models.Payment, error) {
@@ -77,34 +120,340 @@ func (s *paymentService) VerifyPayment(txRef string) (*models.Payment, error) {
 	}
 	fmt.Printf("Found payment in DB: %+v\n", payment)
 
-	if payment.Status == models.PaymentStatusSuccess {
+	if payment.Status == models.PaymentStatusSuccess || payment.Status == models.PaymentStatusRefunded || payment.Status == models.PaymentStatusPartiallyRefunded {
 		fmt.Println("Payment already successful")
 		return payment, nil
 	}
 
-	resp, err := s.chapaClient.VerifyTransaction(txRef)
+	result, err := s.provider.Verify(txRef)
+	if err != nil {
+		fmt.Printf("%s verification error: %v\n", s.provider.Name(), err)
+		return nil, err
+	}
+	fmt.Printf("%s response: %+v\n", s.provider.Name(), result)
+
+	if result.Status == provider.StatusPending {
+		return nil, models.ErrPaymentPending
+	}
+
+	if result.Status != provider.StatusSuccess {
+		s.markPaymentFailed(payment.ID, "Declined by provider")
+		fmt.Printf("Payment verification failed at %s\n", s.provider.Name())
+		return nil, errors.New("payment verification failed")
+	}
+
+	payment, _, err = s.markPaymentSuccessful(payment.ID)
 	if err != nil {
-		fmt.Printf("Chapa verification error: %v\n", err)
+		fmt.Printf("Error updating payment status: %v\n", err)
 		return nil, err
 	}
-	fmt.Printf("Chapa response: %+v\n", resp)
+	return payment, nil
+}
 
-	if resp.Status == "success" || resp.Data.Status == "success" {
+// markPaymentSuccessful moves a pending (or previously failed) payment to
+// success and enqueues its payment_success event. It reports false when the
+// payment had already been settled, so repeated notifications are no-ops.
+func (s *paymentService) markPaymentSuccessful(paymentID uint) (*models.Payment, bool, error) {
+	var payment *models.Payment
+	updated := false
+	err := s.repo.Transaction(func(repo repository.PaymentRepository) error {
+		var err error
+		payment, err = repo.FindByIDForUpdate(paymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPending && payment.Status != models.PaymentStatusFailed {
+			return nil
+		}
+
+		payload, err := events.Marshal(events.TypePaymentSucceeded, messaging.Producer, events.BookingCorrelationID(payment.BookingID), events.PaymentSucceeded{
+			BookingID: payment.BookingID,
+			UserID:    payment.UserID,
+			Amount:    payment.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The status change and its event are committed together; the outbox relay publishes the event
 		payment.Status = models.PaymentStatusSuccess
-		if err := s.repo.UpdatePayment(payment); err != nil {
-			fmt.Printf("Error updating payment status: %v\n", err)
-			return nil, err
+		if err := repo.UpdatePayment(payment); err != nil {
+			return err
 		}
+		updated = true
+		return repo.EnqueueOutboxMessage(&outbox.Message{
+			RoutingKey: messaging.PaymentSuccessQueue,
+			Payload:    string(payload),
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if updated {
 		fmt.Println("Payment updated to success in DB")
+	}
+	return payment, updated, nil
+}
 
-		// Publish Event to RabbitMQ
-		messaging.PublishPaymentSuccess(payment.BookingID, payment.UserID, payment.Amount)
-	} else {
+// markPaymentFailed fails a payment that is still pending.
+func (s *paymentService) markPaymentFailed(paymentID uint, reason string) (bool, error) {
+	updated := false
+	err := s.repo.Transaction(func(repo repository.PaymentRepository) error {
+		payment, err := repo.FindByIDForUpdate(paymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusPending {
+			return nil
+		}
 		payment.Status = models.PaymentStatusFailed
-		s.repo.UpdatePayment(payment)
-		fmt.Println("Payment verification failed at Chapa")
-		return nil, errors.New("payment verification failed")
+		payment.FailureReason = reason
+		updated = true
+		return repo.UpdatePayment(payment)
+	})
+	return updated, err
+}
+
+func (s *paymentService) HandleWebhook(body []byte, signature string) error {
+	verifier, ok := s.provider.(provider.WebhookVerifier)
+	if !ok {
+		return models.ErrWebhookUnsupported
 	}
 
-	return payment, nil
+	notification, err := verifier.ParseWebhook(body, signature)
+	if err != nil {
+		if errors.Is(err, provider.ErrInvalidSignature) {
+			return models.ErrInvalidSignature
+		}
+		return err
+	}
+
+	event := &models.WebhookEvent{
+		Provider: s.provider.Name(),
+		Event:    notification.Event,
+		TxRef:    notification.TxRef,
+		Status:   notification.Status,
+		Payload:  string(body),
+	}
+	if err := s.repo.CreateWebhookEvent(event); err != nil {
+		return err
+	}
+
+	event.Result, err = s.applyWebhook(notification)
+	if err != nil {
+		if event.Result == "" {
+			event.Result = models.WebhookResultError
+		}
+		event.Error = err.Error()
+	}
+	if updateErr := s.repo.UpdateWebhookEvent(event); updateErr != nil {
+		fmt.Printf("Error recording result of webhook %d: %v\n", event.ID, updateErr)
+	}
+	fmt.Printf("%s webhook for %s: %s\n", s.provider.Name(), notification.TxRef, event.Result)
+	return err
+}
+
+func (s *paymentService) applyWebhook(notification *provider.WebhookNotification) (string, error) {
+	payment, err := s.repo.FindByTxRef(notification.TxRef)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", models.ErrPaymentNotFound
+		}
+		return "", err
+	}
+
+	var updated bool
+	switch notification.Status {
+	case provider.StatusSuccess:
+		// A valid signature does not make a charge of the wrong amount a payment
+		if !webhookMatchesPayment(notification, payment) {
+			note := fmt.Sprintf("Webhook reported %.2f %s for a payment of %.2f %s", notification.Amount, notification.Currency, payment.Amount, payment.Currency)
+			if err := s.repo.Flag(payment.ID, models.ReconcileFlagWebhookMismatch, note); err != nil {
+				return "", err
+			}
+			fmt.Printf("Rejected webhook for %s: %s\n", payment.TxRef, note)
+			return models.WebhookResultRejected, models.ErrWebhookMismatch
+		}
+		_, updated, err = s.markPaymentSuccessful(payment.ID)
+	case provider.StatusFailed:
+		updated, err = s.markPaymentFailed(payment.ID, "Declined by provider")
+	default:
+		return models.WebhookResultIgnored, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !updated {
+		return models.WebhookResultDuplicate, nil
+	}
+	return models.WebhookResultProcessed, nil
+}
+
+// webhookMatchesPayment reports whether a provider charged the payment's
+// amount in its currency, to the cent.
+func webhookMatchesPayment(notification *provider.WebhookNotification, payment *models.Payment) bool {
+	currency := payment.Currency
+	if currency == "" {
+		currency = "ETB"
+	}
+	return strings.EqualFold(notification.Currency, currency) &&
+		math.Abs(roundAmount(notification.Amount)-roundAmount(payment.Amount)) < 0.005
+}
+
+func (s *paymentService) GetPayment(txRef string) (*models.Payment, error) {
+	payment, err := s.repo.FindByTxRef(txRef)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	return s.repo.FindByID(payment.ID)
+}
+
+func (s *paymentService) RefundBooking(bookingID uint, amount float64, reason string) error {
+	payment, err := s.repo.FindPaidByBookingID(bookingID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// A full refund (amount 0) of a payment the reconciler already refunded is done
+			if amount == 0 {
+				if refunded, err := s.repo.FindRefundedByBookingID(bookingID); err == nil {
+					fmt.Printf("Payment %s for booking %d is already refunded\n", refunded.TxRef, bookingID)
+					return nil
+				}
+			}
+			return models.ErrPaymentNotFound
+		}
+		return err
+	}
+
+	_, err = s.refund(payment.ID, amount, reason)
+	return err
+}
+
+func (s *paymentService) RefundPayment(txRef string, amount float64, reason string) (*models.Refund, error) {
+	payment, err := s.repo.FindByTxRef(txRef)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPaymentNotFound
+		}
+		return nil, err
+	}
+
+	return s.refund(payment.ID, amount, reason)
+}
+
+func (s *paymentService) refund(paymentID uint, amount float64, reason string) (*models.Refund, error) {
+	if amount < 0 {
+		return nil, models.ErrInvalidRefundAmount
+	}
+
+	// Reserve the refund while holding the payment row so concurrent refunds
+	// cannot exceed the amount paid
+	var payment *models.Payment
+	refund := &models.Refund{Reason: reason, Status: models.RefundStatusPending}
+	err := s.repo.Transaction(func(repo repository.PaymentRepository) error {
+		var err error
+		payment, err = repo.FindByIDForUpdate(paymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != models.PaymentStatusSuccess && payment.Status != models.PaymentStatusPartiallyRefunded {
+			return models.ErrPaymentNotRefundable
+		}
+
+		pending, err := repo.GetPendingRefundTotal(payment.ID)
+		if err != nil {
+			return err
+		}
+		refundable := roundAmount(payment.Amount - payment.RefundedAmount - pending)
+		if amount == 0 {
+			amount = refundable
+		}
+		if amount <= 0 || amount > refundable {
+			return models.ErrInvalidRefundAmount
+		}
+
+		refund.PaymentID = payment.ID
+		refund.Amount = amount
+		return repo.CreateRefund(refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A refund of the whole payment is sent without an amount
+	providerAmount := amount
+	if amount >= payment.Amount {
+		providerAmount = 0
+	}
+	result, err := s.provider.Refund(payment.TxRef, providerAmount, reason)
+	if err != nil {
+		refund.Status = models.RefundStatusFailed
+		refund.FailureReason = err.Error()
+		if updateErr := s.repo.UpdateRefund(refund); updateErr != nil {
+			fmt.Printf("Error recording failed refund %d: %v\n", refund.ID, updateErr)
+		}
+		return refund, err
+	}
+
+	err = s.repo.Transaction(func(repo repository.PaymentRepository) error {
+		payment, err := repo.FindByIDForUpdate(paymentID)
+		if err != nil {
+			return err
+		}
+
+		payment.RefundedAmount = roundAmount(payment.RefundedAmount + amount)
+		if payment.RefundedAmount >= payment.Amount {
+			payment.Status = models.PaymentStatusRefunded
+		} else {
+			payment.Status = models.PaymentStatusPartiallyRefunded
+		}
+		if err := repo.UpdatePayment(payment); err != nil {
+			return err
+		}
+
+		refund.Status = models.RefundStatusSuccess
+		refund.ProviderRef = result.Reference
+		if err := repo.UpdateRefund(refund); err != nil {
+			return err
+		}
+
+		payload, err := events.Marshal(events.TypePaymentRefunded, messaging.Producer, events.BookingCorrelationID(payment.BookingID), events.PaymentRefunded{
+			PaymentID:     payment.ID,
+			RefundID:      refund.ID,
+			BookingID:     payment.BookingID,
+			UserID:        payment.UserID,
+			Amount:        refund.Amount,
+			TotalRefunded: payment.RefundedAmount,
+			Status:        string(payment.Status),
+			Reason:        reason,
+		})
+		if err != nil {
+			return err
+		}
+		return repo.EnqueueOutboxMessage(&outbox.Message{
+			Exchange: messaging.PaymentRefundedExchange,
+			Payload:  string(payload),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Refunded %.2f of payment %s\n", amount, payment.TxRef)
+	return refund, nil
+}
+
+// callbackURL is where the provider reports transaction updates. It must be
+// reachable by the provider, so deployments set PAYMENT_CALLBACK_URL.
+func callbackURL() string {
+	if url := os.Getenv("PAYMENT_CALLBACK_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:3004/api/payments/callback"
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
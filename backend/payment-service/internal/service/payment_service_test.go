@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
+)
+
+func TestWebhookMatchesPayment(t *testing.T) {
+	tests := []struct {
+		name            string
+		amount          float64
+		currency        string
+		paymentAmount   float64
+		paymentCurrency string
+		want            bool
+	}{
+		{"same amount and currency", 250, "ETB", 250, "ETB", true},
+		{"currency case", 250, "etb", 250, "ETB", true},
+		{"payment without currency is ETB", 250, "ETB", 250, "", true},
+		{"rounding noise", 250.004, "ETB", 250, "ETB", true},
+		{"one cent less", 249.99, "ETB", 250, "ETB", false},
+		{"more", 300, "ETB", 250, "ETB", false},
+		{"other currency", 250, "USD", 250, "ETB", false},
+		{"amount missing", 0, "ETB", 250, "ETB", false},
+		{"currency missing", 250, "", 250, "ETB", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := &provider.WebhookNotification{Amount: tt.amount, Currency: tt.currency}
+			payment := &models.Payment{Amount: tt.paymentAmount, Currency: tt.paymentCurrency}
+			if got := webhookMatchesPayment(notification, payment); got != tt.want {
+				t.Errorf("webhookMatchesPayment = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
+)
+
+// Actions for payments that succeeded after their booking was cancelled.
+const (
+	ReconcileActionRefund = "refund"
+	ReconcileActionFlag   = "flag"
+)
+
+const reconcileBatchSize = 100
+
+// paymentVerifyAfter is how long a payment may stay pending before the
+// reconciler asks the provider about it.
+func paymentVerifyAfter() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("PAYMENT_VERIFY_AFTER")); err == nil && d > 0 {
+		return d
+	}
+	return 2 * time.Minute
+}
+
+// paymentExpiry is how long a payment may stay pending before it is failed.
+func paymentExpiry() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("PAYMENT_EXPIRY")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// paidCancelledAction is what happens to a payment that succeeded for a
+// cancelled booking: "refund" (default) refunds it automatically, "flag" only
+// flags it so an admin can re-allocate seats or refund by hand.
+func paidCancelledAction() string {
+	if os.Getenv("RECONCILE_PAID_CANCELLED_ACTION") == ReconcileActionFlag {
+		return ReconcileActionFlag
+	}
+	return ReconcileActionRefund
+}
+
+func (s *paymentService) ReconcilePayments() error {
+	if err := s.reconcilePending(); err != nil {
+		return err
+	}
+	return s.reconcilePaid()
+}
+
+func (s *paymentService) GetFlaggedPayments() ([]models.Payment, error) {
+	return s.repo.FindFlagged()
+}
+
+// reconcilePending re-verifies payments the customer never returned from and
+// fails the ones that have been pending for longer than the expiry.
+func (s *paymentService) reconcilePending() error {
+	payments, err := s.repo.FindPendingBefore(time.Now().Add(-paymentVerifyAfter()), reconcileBatchSize)
+	if err != nil {
+		return err
+	}
+
+	expiredBefore := time.Now().Add(-paymentExpiry())
+	for _, payment := range payments {
+		expired := payment.CreatedAt.Before(expiredBefore)
+
+		result, err := s.provider.Verify(payment.TxRef)
+		if err != nil {
+			fmt.Printf("Reconciler could not verify payment %s: %v\n", payment.TxRef, err)
+			if expired {
+				s.expirePayment(&payment)
+			}
+			continue
+		}
+
+		switch result.Status {
+		case provider.StatusSuccess:
+			if _, _, err := s.markPaymentSuccessful(payment.ID); err != nil {
+				fmt.Printf("Reconciler failed to settle payment %s: %v\n", payment.TxRef, err)
+			} else {
+				fmt.Printf("Reconciler settled late payment %s\n", payment.TxRef)
+			}
+		case provider.StatusFailed:
+			if _, err := s.markPaymentFailed(payment.ID, "Declined by provider"); err != nil {
+				fmt.Printf("Reconciler failed to fail payment %s: %v\n", payment.TxRef, err)
+			}
+		default:
+			if expired {
+				s.expirePayment(&payment)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *paymentService) expirePayment(payment *models.Payment) {
+	if _, err := s.markPaymentFailed(payment.ID, "Payment expired"); err != nil {
+		fmt.Printf("Reconciler failed to expire payment %s: %v\n", payment.TxRef, err)
+		return
+	}
+	fmt.Printf("Reconciler expired payment %s\n", payment.TxRef)
+}
+
+// reconcilePaid checks that every successful payment ended up confirming its
+// booking. A booking that was cancelled (or failed) without ever being
+// confirmed means the payment arrived too late.
+func (s *paymentService) reconcilePaid() error {
+	// Give the booking service time to consume the payment_success event
+	payments, err := s.repo.FindUncheckedPaid(time.Now().Add(-paymentVerifyAfter()), reconcileBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		booking, err := fetchBooking(payment.BookingID)
+		if err != nil {
+			fmt.Printf("Reconciler could not fetch booking %d: %v\n", payment.BookingID, err)
+			continue
+		}
+
+		if booking.Status == "pending" {
+			continue
+		}
+		if booking.Status == "confirmed" || booking.ConfirmedAt != nil {
+			// Later cancellations of confirmed bookings are refunded by the booking service
+			if err := s.repo.MarkBookingChecked(payment.ID, "", ""); err != nil {
+				fmt.Printf("Reconciler failed to update payment %s: %v\n", payment.TxRef, err)
+			}
+			continue
+		}
+
+		s.resolvePaidCancelled(&payment, booking)
+	}
+	return nil
+}
+
+func (s *paymentService) resolvePaidCancelled(payment *models.Payment, booking *bookingDetails) {
+	note := fmt.Sprintf("Booking %d was %s before the payment completed", booking.ID, booking.Status)
+	fmt.Printf("Reconciler: payment %s succeeded but %s\n", payment.TxRef, note)
+
+	if paidCancelledAction() == ReconcileActionRefund {
+		if _, err := s.refund(payment.ID, 0, note); err != nil {
+			note = fmt.Sprintf("%s; automatic refund failed: %v", note, err)
+		} else {
+			note = fmt.Sprintf("%s; refunded automatically", note)
+		}
+	} else {
+		note = fmt.Sprintf("%s; awaiting seat re-allocation or manual refund", note)
+	}
+
+	if err := s.repo.MarkBookingChecked(payment.ID, models.ReconcileFlagBookingCancelled, note); err != nil {
+		fmt.Printf("Reconciler failed to flag payment %s: %v\n", payment.TxRef, err)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"gorm.io/gorm"
+)
+
+const (
+	refundJobBatchSize = 50
+	// maxRefundAttempts is how often a booking's refund is tried before it
+	// is reported as failed.
+	maxRefundAttempts = 3
+)
+
+// StartEventRefund opens the refund job of a cancelled event. The refund
+// job worker refunds its bookings in the background.
+func (s *paymentService) StartEventRefund(eventID uint, reason string, bookings []events.BookingRefund) (*models.RefundJob, error) {
+	items := make([]models.RefundJobItem, 0, len(bookings))
+	for _, booking := range bookings {
+		items = append(items, models.RefundJobItem{
+			BookingID: booking.BookingID,
+			UserID:    booking.UserID,
+			Amount:    booking.Amount,
+			Status:    models.RefundJobItemPending,
+		})
+	}
+
+	job, err := s.jobs.AddRefundJob(&models.RefundJob{EventID: eventID, Reason: reason}, items)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Refund job %d for event %d has %d bookings\n", job.ID, eventID, len(items))
+	return job, nil
+}
+
+// ProcessRefundJobs refunds the next batch of pending bookings of every
+// running refund job in full.
+func (s *paymentService) ProcessRefundJobs() error {
+	items, err := s.jobs.FindPendingItems(refundJobBatchSize)
+	if err != nil {
+		return err
+	}
+
+	touched := make(map[uint]bool)
+	for i := range items {
+		item := &items[i]
+		if err := s.refundJobItem(item); err != nil {
+			return err
+		}
+		touched[item.JobID] = true
+	}
+
+	for jobID := range touched {
+		if err := s.jobs.RefreshProgress(jobID); err != nil {
+			fmt.Printf("Failed to update progress of refund job %d: %v\n", jobID, err)
+		}
+	}
+	return nil
+}
+
+// refundJobItem refunds one booking of a refund job. Provider errors are
+// retried by later runs until maxRefundAttempts; refunds that cannot
+// succeed fail at once. Only database errors are returned.
+func (s *paymentService) refundJobItem(item *models.RefundJobItem) error {
+	reason := "Event cancelled"
+	if item.Job != nil && item.Job.Reason != "" {
+		reason = item.Job.Reason
+	}
+	item.Attempts++
+
+	payment, err := s.repo.FindPaidByBookingID(item.BookingID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := s.repo.FindRefundedByBookingID(item.BookingID); err == nil {
+			item.Status = models.RefundJobItemSkipped
+			item.FailureReason = ""
+		} else {
+			item.Status = models.RefundJobItemFailed
+			item.FailureReason = models.ErrPaymentNotFound.Message
+		}
+		return s.jobs.UpdateItem(item)
+	}
+	if err != nil {
+		return err
+	}
+
+	refund, err := s.refund(payment.ID, 0, reason)
+	switch {
+	case err == nil:
+		item.Status = models.RefundJobItemRefunded
+		item.RefundID = &refund.ID
+		item.RefundedAmount = refund.Amount
+		item.FailureReason = ""
+	case errors.Is(err, models.ErrPaymentNotRefundable) || errors.Is(err, models.ErrInvalidRefundAmount) || item.Attempts >= maxRefundAttempts:
+		item.Status = models.RefundJobItemFailed
+		item.FailureReason = err.Error()
+	default:
+		item.FailureReason = err.Error()
+	}
+	if err != nil {
+		fmt.Printf("Refund of booking %d in refund job %d failed (attempt %d): %v\n", item.BookingID, item.JobID, item.Attempts, err)
+	}
+	return s.jobs.UpdateItem(item)
+}
+
+func (s *paymentService) GetRefundJobs() ([]models.RefundJob, error) {
+	return s.jobs.FindJobs()
+}
+
+func (s *paymentService) GetRefundJob(jobID uint) (*models.RefundJob, error) {
+	job, err := s.jobs.FindJobByID(jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrRefundJobNotFound
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// RetryRefundJob queues the failed refunds of a job again.
+func (s *paymentService) RetryRefundJob(jobID uint) (*models.RefundJob, error) {
+	if _, err := s.GetRefundJob(jobID); err != nil {
+		return nil, err
+	}
+	retried, err := s.jobs.RetryFailedItems(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.jobs.RefreshProgress(jobID); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Retrying %d failed refunds of refund job %d\n", retried, jobID)
+	return s.GetRefundJob(jobID)
+}
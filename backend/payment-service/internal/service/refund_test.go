@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/models"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/provider"
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/repository"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/outbox"
+)
+
+// refundRepository keeps one payment, its pending refund total and the
+// refunds created against it.
+type refundRepository struct {
+	repository.PaymentRepository
+	payment models.Payment
+	pending float64
+	refunds []*models.Refund
+}
+
+func (r *refundRepository) Transaction(fn func(repo repository.PaymentRepository) error) error {
+	return fn(r)
+}
+
+func (r *refundRepository) FindByIDForUpdate(id uint) (*models.Payment, error) {
+	payment := r.payment
+	return &payment, nil
+}
+
+func (r *refundRepository) GetPendingRefundTotal(paymentID uint) (float64, error) {
+	return r.pending, nil
+}
+
+func (r *refundRepository) CreateRefund(refund *models.Refund) error {
+	r.refunds = append(r.refunds, refund)
+	return nil
+}
+
+func (r *refundRepository) UpdateRefund(refund *models.Refund) error {
+	return nil
+}
+
+func (r *refundRepository) UpdatePayment(payment *models.Payment) error {
+	r.payment = *payment
+	return nil
+}
+
+func (r *refundRepository) EnqueueOutboxMessage(message *outbox.Message) error {
+	return nil
+}
+
+// refundProvider records the amounts refunded through it.
+type refundProvider struct {
+	provider.PaymentProvider
+	amounts []float64
+}
+
+func (p *refundProvider) Refund(txRef string, amount float64, reason string) (*provider.RefundResult, error) {
+	p.amounts = append(p.amounts, amount)
+	return &provider.RefundResult{Reference: "rf-1"}, nil
+}
+
+func TestRefundBounds(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         models.PaymentStatus
+		refunded       float64
+		pending        float64
+		amount         float64
+		wantErr        error
+		wantRefund     float64
+		wantProvider   float64 // 0 is a full refund
+		wantStatus     models.PaymentStatus
+		wantTotalAfter float64
+	}{
+		{"full refund by default", models.PaymentStatusSuccess, 0, 0, 0, nil, 200, 0, models.PaymentStatusRefunded, 200},
+		{"partial refund", models.PaymentStatusSuccess, 0, 0, 50, nil, 50, 50, models.PaymentStatusPartiallyRefunded, 50},
+		{"rest of a partial refund", models.PaymentStatusPartiallyRefunded, 50, 0, 0, nil, 150, 150, models.PaymentStatusRefunded, 200},
+		{"exactly the rest", models.PaymentStatusPartiallyRefunded, 50, 0, 150, nil, 150, 150, models.PaymentStatusRefunded, 200},
+		{"more than paid", models.PaymentStatusSuccess, 0, 0, 200.01, models.ErrInvalidRefundAmount, 0, 0, "", 0},
+		{"more than left", models.PaymentStatusPartiallyRefunded, 150, 0, 60, models.ErrInvalidRefundAmount, 0, 0, "", 0},
+		{"pending refunds count", models.PaymentStatusSuccess, 0, 180, 30, models.ErrInvalidRefundAmount, 0, 0, "", 0},
+		{"nothing left", models.PaymentStatusPartiallyRefunded, 120, 80, 0, models.ErrInvalidRefundAmount, 0, 0, "", 0},
+		{"negative amount", models.PaymentStatusSuccess, 0, 0, -5, models.ErrInvalidRefundAmount, 0, 0, "", 0},
+		{"pending payment", models.PaymentStatusPending, 0, 0, 0, models.ErrPaymentNotRefundable, 0, 0, "", 0},
+		{"refunded payment", models.PaymentStatusRefunded, 200, 0, 0, models.ErrPaymentNotRefundable, 0, 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &refundRepository{
+				payment: models.Payment{TxRef: "tx-1", Amount: 200, RefundedAmount: tt.refunded, Status: tt.status},
+				pending: tt.pending,
+			}
+			repo.payment.ID = 1
+			p := &refundProvider{}
+			s := NewPaymentService(repo, nil, p).(*paymentService)
+
+			refund, err := s.refund(1, tt.amount, "requested by customer")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.refunds) != 0 || len(p.amounts) != 0 {
+					t.Errorf("refund recorded or sent after %v", err)
+				}
+				return
+			}
+
+			if refund.Amount != tt.wantRefund || refund.Status != models.RefundStatusSuccess {
+				t.Errorf("refund of %v is %s, want %v succeeded", refund.Amount, refund.Status, tt.wantRefund)
+			}
+			if len(p.amounts) != 1 || p.amounts[0] != tt.wantProvider {
+				t.Errorf("provider asked to refund %v, want [%v]", p.amounts, tt.wantProvider)
+			}
+			if repo.payment.Status != tt.wantStatus || repo.payment.RefundedAmount != tt.wantTotalAfter {
+				t.Errorf("payment is %s with %v refunded, want %s with %v", repo.payment.Status, repo.payment.RefundedAmount, tt.wantStatus, tt.wantTotalAfter)
+			}
+		})
+	}
+}
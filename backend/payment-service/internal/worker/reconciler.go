@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/service"
+)
+
+// StartReconciler periodically settles stuck pending payments and flags
+// payments made for cancelled bookings.
+func StartReconciler(paymentService service.PaymentService) {
+	interval, err := time.ParseDuration(os.Getenv("RECONCILE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 1 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := paymentService.ReconcilePayments(); err != nil {
+				fmt.Printf("Error in payment reconciler: %v\n", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/payment-service/internal/service"
+)
+
+// StartRefundJobWorker periodically refunds the bookings of cancelled events.
+func StartRefundJobWorker(paymentService service.PaymentService) {
+	interval, err := time.ParseDuration(os.Getenv("REFUND_JOB_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if err := paymentService.ProcessRefundJobs(); err != nil {
+				fmt.Printf("Error in refund job worker: %v\n", err)
+			}
+		}
+	}()
+}
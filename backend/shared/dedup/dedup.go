@@ -0,0 +1,137 @@
+// Package dedup makes RabbitMQ consumers idempotent. RabbitMQ delivers at
+// least once, so a consumer may see the same message again after a
+// reconnect, a retry or an outbox relay that published twice. A Filter
+// records the ID of every message a consumer has processed in a Store and
+// acks repeats without running the handler again.
+//
+// A message is claimed before it is processed. The claim is a short lease:
+// if the handler fails the claim is released so the retry runs, and if the
+// process dies mid-way the lease expires and a redelivery is processed.
+// While another delivery holds the lease the message is retried later.
+// Once the handler succeeds the ID is kept for the dedup window.
+package dedup
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/shared/events"
+	"github.com/Antiaastu/distributed-event-ticketing/shared/messaging"
+	"github.com/streadway/amqp"
+)
+
+// State is what a Store knows about a message ID.
+type State int
+
+const (
+	// StateNew means the caller now holds the claim and should process the message.
+	StateNew State = iota
+	// StateInFlight means another delivery holds an unexpired claim.
+	StateInFlight
+	// StateDone means the message was processed within the dedup window.
+	StateDone
+)
+
+const (
+	defaultWindow = 24 * time.Hour
+	defaultLease  = time.Minute
+)
+
+// ErrInFlight is returned for a message another delivery is still processing,
+// so the delivery is retried after the usual delay.
+var ErrInFlight = errors.New("message is already being processed")
+
+// Store records processed message IDs. Keys are unique per consumer.
+type Store interface {
+	// Claim takes a lease on key unless it is already claimed or done.
+	Claim(key string, lease time.Duration) (State, error)
+	// Complete marks key as processed for window.
+	Complete(key string, window time.Duration) error
+	// Release drops the claim on key so the message can be processed again.
+	Release(key string) error
+}
+
+// Filter is a messaging.Deduplicator backed by a Store.
+type Filter struct {
+	store  Store
+	window time.Duration
+	lease  time.Duration
+}
+
+// NewFilter returns a Filter that keeps processed IDs for DEDUP_WINDOW
+// (a Go duration, 24h by default).
+func NewFilter(store Store) *Filter {
+	return &Filter{store: store, window: WindowFromEnv(), lease: defaultLease}
+}
+
+// WindowFromEnv reads DEDUP_WINDOW.
+func WindowFromEnv() time.Duration {
+	if v := os.Getenv("DEDUP_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid DEDUP_WINDOW %q, using %s", v, defaultWindow)
+	}
+	return defaultWindow
+}
+
+// StoreKindFromEnv reads DEDUP_STORE, falling back to def.
+func StoreKindFromEnv(def string) string {
+	if v := os.Getenv("DEDUP_STORE"); v != "" {
+		return v
+	}
+	return def
+}
+
+// Wrap implements messaging.Deduplicator.
+func (f *Filter) Wrap(queue string, handler messaging.Handler) messaging.Handler {
+	return func(d amqp.Delivery) error {
+		id := MessageID(d)
+		if id == "" {
+			// Nothing to deduplicate on
+			return handler(d)
+		}
+
+		key := queue + ":" + id
+		state, err := f.store.Claim(key, f.lease)
+		if err != nil {
+			return fmt.Errorf("failed to claim message %s: %v", id, err)
+		}
+		switch state {
+		case StateDone:
+			log.Printf("Skipping duplicate message %s on %s", id, queue)
+			return nil
+		case StateInFlight:
+			return fmt.Errorf("message %s on %s: %w", id, queue, ErrInFlight)
+		}
+
+		if err := handler(d); err != nil {
+			if releaseErr := f.store.Release(key); releaseErr != nil {
+				log.Printf("Failed to release claim on message %s: %v", id, releaseErr)
+			}
+			return err
+		}
+
+		if err := f.store.Complete(key, f.window); err != nil {
+			// The message was processed; a redelivery after the lease expires would run it again
+			log.Printf("Failed to record message %s as processed: %v", id, err)
+		}
+		return nil
+	}
+}
+
+// MessageID identifies a delivery: the AMQP message-id when the producer set
+// one, otherwise the envelope ID. Bare legacy payloads have no ID.
+func MessageID(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	envelope, err := events.Decode(d.Body, "")
+	if err != nil {
+		return ""
+	}
+	return envelope.ID
+}
@@ -0,0 +1,71 @@
+package dedup
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+func TestFilter(t *testing.T) {
+	failed := errors.New("handler failed")
+	tests := []struct {
+		name      string
+		messageID string
+		results   []error // one per delivery
+		wantErrs  []error
+		wantCalls int
+	}{
+		{"duplicate is skipped", "m1", []error{nil, nil}, []error{nil, nil}, 1},
+		{"failure is retried", "m1", []error{failed, nil, nil}, []error{failed, nil, nil}, 2},
+		{"no ID is never deduplicated", "", []error{nil, nil}, []error{nil, nil}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := func(d amqp.Delivery) error {
+				err := tt.results[calls]
+				calls++
+				return err
+			}
+			filter := &Filter{store: NewMemoryStore(), window: time.Hour, lease: time.Minute}
+			h := filter.Wrap("queue", handler)
+
+			for i, want := range tt.wantErrs {
+				if err := h(amqp.Delivery{MessageId: tt.messageID, Body: []byte("legacy")}); !errors.Is(err, want) {
+					t.Errorf("delivery %d: error %v, want %v", i+1, err, want)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFilterRetriesMessageInFlight(t *testing.T) {
+	store := NewMemoryStore()
+	store.Claim("queue:m1", time.Minute)
+
+	calls := 0
+	h := (&Filter{store: store, window: time.Hour, lease: time.Minute}).Wrap("queue", func(d amqp.Delivery) error {
+		calls++
+		return nil
+	})
+	if err := h(amqp.Delivery{MessageId: "m1"}); !errors.Is(err, ErrInFlight) {
+		t.Errorf("error %v, want ErrInFlight", err)
+	}
+	if calls != 0 {
+		t.Errorf("handler ran %d times while the message was in flight", calls)
+	}
+
+	// The same message on another queue is processed by that consumer
+	h = (&Filter{store: store, window: time.Hour, lease: time.Minute}).Wrap("other", func(d amqp.Delivery) error {
+		calls++
+		return nil
+	})
+	if err := h(amqp.Delivery{MessageId: "m1"}); err != nil || calls != 1 {
+		t.Errorf("other queue: error %v after %d calls, want it processed", err, calls)
+	}
+}
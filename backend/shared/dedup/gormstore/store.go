@@ -0,0 +1,95 @@
+// Package gormstore keeps processed message IDs in the service's own
+// database, in the processed_messages table.
+package gormstore
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const pruneInterval = time.Hour
+
+// ProcessedMessage is a claimed or processed message ID.
+type ProcessedMessage struct {
+	MessageKey string    `gorm:"primaryKey;size:255"`
+	Done       bool      `gorm:"not null;default:false"`
+	ExpiresAt  time.Time `gorm:"index;not null"`
+	CreatedAt  time.Time
+}
+
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore migrates the processed_messages table and starts pruning expired
+// rows in the background.
+func NewStore(db *gorm.DB) (*Store, error) {
+	if err := db.AutoMigrate(&ProcessedMessage{}); err != nil {
+		return nil, err
+	}
+
+	s := &Store{db: db}
+	go func() {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Prune(); err != nil {
+				log.Printf("Failed to prune processed messages: %v", err)
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (s *Store) Claim(key string, lease time.Duration) (dedup.State, error) {
+	now := time.Now()
+
+	// An expired claim or record can be taken over
+	if err := s.db.Where("message_key = ? AND expires_at <= ?", key, now).Delete(&ProcessedMessage{}).Error; err != nil {
+		return 0, err
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedMessage{
+		MessageKey: key,
+		ExpiresAt:  now.Add(lease),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return dedup.StateNew, nil
+	}
+
+	var existing ProcessedMessage
+	if err := s.db.Where("message_key = ?", key).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between the insert and the read; try again later
+			return dedup.StateInFlight, nil
+		}
+		return 0, err
+	}
+	if existing.Done {
+		return dedup.StateDone, nil
+	}
+	return dedup.StateInFlight, nil
+}
+
+func (s *Store) Complete(key string, window time.Duration) error {
+	return s.db.Model(&ProcessedMessage{}).
+		Where("message_key = ?", key).
+		Updates(map[string]interface{}{"done": true, "expires_at": time.Now().Add(window)}).Error
+}
+
+func (s *Store) Release(key string) error {
+	return s.db.Where("message_key = ? AND done = ?", key, false).Delete(&ProcessedMessage{}).Error
+}
+
+// Prune deletes rows whose claim or dedup window has expired.
+func (s *Store) Prune() error {
+	return s.db.Where("expires_at <= ?", time.Now()).Delete(&ProcessedMessage{}).Error
+}
@@ -0,0 +1,69 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	done    bool
+	expires time.Time
+}
+
+// MemoryStore keeps message IDs in process memory. IDs are lost on restart,
+// so it only suits a single instance that can tolerate that.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryStore) Claim(key string, lease time.Duration) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		if entry.done {
+			return StateDone, nil
+		}
+		return StateInFlight, nil
+	}
+	s.entries[key] = memoryEntry{expires: now.Add(lease)}
+	return StateNew, nil
+}
+
+func (s *MemoryStore) Complete(key string, window time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{done: true, expires: time.Now().Add(window)}
+	return nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// prune drops expired entries at most once a minute.
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < time.Minute {
+		return
+	}
+	s.pruned = now
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(s *MemoryStore)
+		want  State
+	}{
+		{"new key", func(s *MemoryStore) {}, StateNew},
+		{"claimed", func(s *MemoryStore) { s.Claim("k", time.Minute) }, StateInFlight},
+		{"lease expired", func(s *MemoryStore) { s.Claim("k", time.Nanosecond); time.Sleep(time.Millisecond) }, StateNew},
+		{"completed", func(s *MemoryStore) { s.Claim("k", time.Minute); s.Complete("k", time.Hour) }, StateDone},
+		{"window passed", func(s *MemoryStore) {
+			s.Claim("k", time.Minute)
+			s.Complete("k", time.Nanosecond)
+			time.Sleep(time.Millisecond)
+		}, StateNew},
+		{"released", func(s *MemoryStore) { s.Claim("k", time.Minute); s.Release("k") }, StateNew},
+		{"other key", func(s *MemoryStore) { s.Claim("other", time.Minute); s.Complete("other", time.Hour) }, StateNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			tt.steps(s)
+			state, err := s.Claim("k", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state != tt.want {
+				t.Errorf("Claim = %v, want %v", state, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package redisstore keeps processed message IDs in Redis, expiring them
+// with key TTLs.
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/Antiaastu/distributed-event-ticketing/shared/dedup"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "dedup:"
+
+	valueInFlight = "processing"
+	valueDone     = "done"
+)
+
+type Store struct {
+	client *redis.Client
+}
+
+func NewStore(client *redis.Client) *Store {
+	return &Store{client: client}
+}
+
+func (s *Store) Claim(key string, lease time.Duration) (dedup.State, error) {
+	ctx := context.Background()
+
+	claimed, err := s.client.SetNX(ctx, keyPrefix+key, valueInFlight, lease).Result()
+	if err != nil {
+		return 0, err
+	}
+	if claimed {
+		return dedup.StateNew, nil
+	}
+
+	value, err := s.client.Get(ctx, keyPrefix+key).Result()
+	if err == redis.Nil {
+		// Expired or released between the two calls; try again later
+		return dedup.StateInFlight, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if value == valueDone {
+		return dedup.StateDone, nil
+	}
+	return dedup.StateInFlight, nil
+}
+
+func (s *Store) Complete(key string, window time.Duration) error {
+	return s.client.Set(context.Background(), keyPrefix+key, valueDone, window).Err()
+}
+
+func (s *Store) Release(key string) error {
+	return s.client.Del(context.Background(), keyPrefix+key).Err()
+}
@@ -0,0 +1,159 @@
+// Package events defines the envelope and payload schemas of every message
+// exchanged between the services.
+//
+// Messages are CloudEvents 1.0 structured-mode JSON. Besides the required
+// attributes (specversion, id, source, type) each envelope carries time,
+// datacontenttype and two extensions: correlationid, which ties together the
+// messages of one booking flow, and dataversion, the "major.minor" version of
+// the payload schema. Adding optional fields bumps the minor version;
+// renaming, removing or retyping a field bumps the major version and needs a
+// new consumer before producers switch. The JSON schemas of the payloads are
+// in the schemas directory.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SpecVersion     = "1.0"
+	DataContentType = "application/json"
+)
+
+// Envelope is a CloudEvents-compatible message wrapping a typed payload.
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataVersion     string          `json:"dataversion"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// New wraps data in an envelope of the given type, stamped with the current
+// schema version of that type. source names the producing service.
+func New(eventType, source, correlationID string, data interface{}) (*Envelope, error) {
+	version, ok := currentVersions[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              hex.EncodeToString(id),
+		Type:            eventType,
+		Source:          source,
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		DataVersion:     version,
+		CorrelationID:   correlationID,
+		Data:            payload,
+	}, nil
+}
+
+// Marshal builds an envelope and returns its JSON encoding.
+func Marshal(eventType, source, correlationID string, data interface{}) ([]byte, error) {
+	envelope, err := New(eventType, source, correlationID, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// Decode parses a message body. Bodies published before the envelope was
+// introduced are bare payloads; they are wrapped as version 1.0 of
+// legacyType so consumers can handle both during a rollout.
+func Decode(body []byte, legacyType string) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+
+	if envelope.SpecVersion == "" {
+		return &Envelope{
+			Type:            legacyType,
+			DataContentType: DataContentType,
+			DataVersion:     "1.0",
+			Data:            json.RawMessage(body),
+		}, nil
+	}
+
+	if envelope.Type == "" || envelope.DataVersion == "" {
+		return nil, fmt.Errorf("envelope %s is missing type or dataversion", envelope.ID)
+	}
+	return &envelope, nil
+}
+
+// DecodeData checks that the envelope has the expected type and a schema
+// version this consumer understands, then decodes the payload into v.
+func (e *Envelope) DecodeData(eventType string, v interface{}) error {
+	if e.Type != eventType {
+		return fmt.Errorf("expected event type %q, got %q", eventType, e.Type)
+	}
+	if !Compatible(eventType, e.DataVersion) {
+		return fmt.Errorf("unsupported %s version %s (consumer understands %s)", eventType, e.DataVersion, currentVersions[eventType])
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// Unmarshal decodes a message body of the given type into v.
+func Unmarshal(body []byte, eventType string, v interface{}) (*Envelope, error) {
+	envelope, err := Decode(body, eventType)
+	if err != nil {
+		return nil, err
+	}
+	return envelope, envelope.DecodeData(eventType, v)
+}
+
+// Compatible reports whether a payload of the given version can be read with
+// the schema this build knows: the major versions must match. Newer minor
+// versions only add optional fields, which are ignored.
+func Compatible(eventType, version string) bool {
+	current, ok := currentVersions[eventType]
+	if !ok {
+		return false
+	}
+
+	major, err := majorVersion(version)
+	if err != nil {
+		return false
+	}
+	currentMajor, _ := majorVersion(current)
+	return major == currentMajor
+}
+
+func majorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	return strconv.Atoi(major)
+}
+
+// BookingCorrelationID is the correlation ID shared by every message about
+// a booking.
+func BookingCorrelationID(bookingID uint) string {
+	return fmt.Sprintf("booking-%d", bookingID)
+}
+
+// EventCorrelationID is the correlation ID shared by every message about
+// an event's lifecycle.
+func EventCorrelationID(eventID uint) string {
+	return fmt.Sprintf("event-%d", eventID)
+}
@@ -0,0 +1,338 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// samples holds a populated payload of every event type. A type added to
+// currentVersions without a sample fails TestSamplesCoverEveryType.
+var samples = map[string]interface{}{
+	TypeBookingConfirmed: &BookingConfirmed{
+		BookingID: 1, UserID: 2, EventID: 3, Amount: 120.5, SeatCount: 2,
+		Seats: `["vip-1-1","vip-1-2"]`, TicketClasses: map[string]int{"vip": 2}, HoldToken: "0123456789abcdef",
+	},
+	TypeBookingCancelled: &BookingCancelled{
+		BookingID: 1, UserID: 2, EventID: 3, SeatCount: 2, RefundAmount: 60, Reason: "Changed plans", EventCancelled: true,
+	},
+	TypeRefundRequested:  &RefundRequested{BookingID: 1, UserID: 2, Amount: 60, Reason: "Changed plans"},
+	TypePaymentSucceeded: &PaymentSucceeded{BookingID: 1, UserID: 2, Amount: 120.5},
+	TypePaymentRefunded: &PaymentRefunded{
+		PaymentID: 4, RefundID: 5, BookingID: 1, UserID: 2, Amount: 60, TotalRefunded: 60, Status: "partially_refunded", Reason: "Changed plans",
+	},
+	TypeTicketIssued: &TicketIssued{BookingID: 1, UserID: 2, IssuedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	TypeAuditLogRecorded: &AuditLogRecorded{
+		UserID: 2, Action: "CREATE_BOOKING", Details: "Created booking 1", IPAddress: "127.0.0.1", CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	},
+	TypeEmailVerificationRequested: &EmailVerificationRequested{Email: "user@example.com", Code: "123456"},
+	TypePasswordResetRequested:     &PasswordResetRequested{Email: "user@example.com", Code: "654321"},
+	TypeEventStatusChanged: &EventStatusChanged{
+		EventID: 3, OrganizerID: 6, Title: "Concert", Date: time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC),
+		FromStatus: "on_sale", ToStatus: "sales_closed", Reason: "Sales ended",
+	},
+	TypeEventCancelled: &EventCancelled{
+		EventID: 3, OrganizerID: 6, Title: "Concert", Date: time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC),
+		Reason: "Storm", CancelledAt: time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+	},
+	TypeEventRefundRequested: &EventRefundRequested{
+		EventID: 3, Reason: "Event cancelled: Storm", Bookings: []BookingRefund{{BookingID: 1, UserID: 2, Amount: 120.5}},
+	},
+}
+
+func TestSamplesCoverEveryType(t *testing.T) {
+	for eventType := range currentVersions {
+		if _, ok := samples[eventType]; !ok {
+			t.Errorf("no sample payload for %s", eventType)
+		}
+	}
+}
+
+func TestMarshalDecodeRoundTrip(t *testing.T) {
+	for eventType, sample := range samples {
+		t.Run(eventType, func(t *testing.T) {
+			body, err := Marshal(eventType, "test-service", "booking-1", sample)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			envelope, err := Decode(body, eventType)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if envelope.SpecVersion != SpecVersion || envelope.ID == "" || envelope.Source != "test-service" ||
+				envelope.DataContentType != DataContentType || envelope.CorrelationID != "booking-1" || envelope.Time.IsZero() {
+				t.Errorf("unexpected envelope attributes: %+v", envelope)
+			}
+			if envelope.Type != eventType || envelope.DataVersion != CurrentVersion(eventType) {
+				t.Errorf("envelope is %s %s, want %s %s", envelope.Type, envelope.DataVersion, eventType, CurrentVersion(eventType))
+			}
+
+			decoded := reflect.New(reflect.TypeOf(sample).Elem()).Interface()
+			if err := envelope.DecodeData(eventType, decoded); err != nil {
+				t.Fatalf("DecodeData: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, sample) {
+				t.Errorf("round trip changed the payload:\n got %+v\nwant %+v", decoded, sample)
+			}
+		})
+	}
+}
+
+func TestMarshalRejectsUnknownType(t *testing.T) {
+	if _, err := Marshal("tickethub.unknown", "test-service", "", struct{}{}); err == nil {
+		t.Error("Marshal accepted an unknown event type")
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.0", true},
+		{"1.2", true},
+		{"1.9", true}, // a newer minor version only adds optional fields
+		{"2.0", false},
+		{"0.9", false},
+		{"", false},
+		{"x.1", false},
+	}
+	for _, tt := range tests {
+		if got := Compatible(TypeBookingConfirmed, tt.version); got != tt.want {
+			t.Errorf("Compatible(%s, %q) = %v, want %v", TypeBookingConfirmed, tt.version, got, tt.want)
+		}
+	}
+	if Compatible("tickethub.unknown", "1.0") {
+		t.Error("Compatible accepted an unknown event type")
+	}
+}
+
+func TestDecodeDataChecksVersionAndType(t *testing.T) {
+	body, err := Marshal(TypeBookingConfirmed, "test-service", "", samples[TypeBookingConfirmed])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A newer minor version with a field this build does not know
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatal(err)
+	}
+	raw["dataversion"] = "1.7"
+	raw["data"].(map[string]interface{})["loyalty_points"] = 10
+	newer, _ := json.Marshal(raw)
+	var confirmed BookingConfirmed
+	if _, err := Unmarshal(newer, TypeBookingConfirmed, &confirmed); err != nil {
+		t.Errorf("newer minor version rejected: %v", err)
+	}
+	if confirmed.BookingID != 1 {
+		t.Errorf("payload of the newer minor version not decoded: %+v", confirmed)
+	}
+
+	raw["dataversion"] = "2.0"
+	newerMajor, _ := json.Marshal(raw)
+	if _, err := Unmarshal(newerMajor, TypeBookingConfirmed, &confirmed); err == nil {
+		t.Error("different major version accepted")
+	}
+
+	if _, err := Unmarshal(body, TypeBookingCancelled, &BookingCancelled{}); err == nil {
+		t.Error("payload of another event type accepted")
+	}
+}
+
+func TestDecodeLegacyBarePayload(t *testing.T) {
+	// Published before the envelope existed
+	body := []byte(`{"booking_id": 7, "user_id": 8, "event_id": 9, "amount": 50, "seat_count": 1, "seats": "[]"}`)
+
+	envelope, err := Decode(body, TypeBookingConfirmed)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if envelope.Type != TypeBookingConfirmed || envelope.DataVersion != "1.0" {
+		t.Errorf("legacy payload wrapped as %s %s, want %s 1.0", envelope.Type, envelope.DataVersion, TypeBookingConfirmed)
+	}
+
+	var confirmed BookingConfirmed
+	if err := envelope.DecodeData(TypeBookingConfirmed, &confirmed); err != nil {
+		t.Fatalf("DecodeData: %v", err)
+	}
+	want := BookingConfirmed{BookingID: 7, UserID: 8, EventID: 9, Amount: 50, SeatCount: 1, Seats: "[]"}
+	if !reflect.DeepEqual(confirmed, want) {
+		t.Errorf("legacy payload decoded as %+v, want %+v", confirmed, want)
+	}
+}
+
+func TestDecodeRejectsIncompleteEnvelope(t *testing.T) {
+	body := []byte(`{"specversion": "1.0", "id": "abc", "source": "test-service", "data": {}}`)
+	if _, err := Decode(body, TypeBookingConfirmed); err == nil {
+		t.Error("envelope without type and dataversion accepted")
+	}
+}
+
+// jsonSchema is the subset of JSON Schema the payload schemas use.
+type jsonSchema struct {
+	Title      string                 `json:"title"`
+	Type       string                 `json:"type"`
+	Format     string                 `json:"format"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+}
+
+// TestSchemasMatchPayloads fails when a payload struct and its JSON schema
+// drift apart: every field must be in the schema with a matching type, the
+// schema must not have fields the struct lacks, and its title must carry
+// the current version.
+func TestSchemasMatchPayloads(t *testing.T) {
+	for eventType, sample := range samples {
+		t.Run(eventType, func(t *testing.T) {
+			version := CurrentVersion(eventType)
+			major, _ := majorVersion(version)
+			data, err := os.ReadFile(filepath.Join("schemas", fmt.Sprintf("%s.v%d.json", eventType, major)))
+			if err != nil {
+				t.Fatalf("no schema: %v", err)
+			}
+			var schema jsonSchema
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("invalid schema: %v", err)
+			}
+
+			if want := eventType + " " + version; schema.Title != want {
+				t.Errorf("schema title is %q, want %q", schema.Title, want)
+			}
+			compareSchema(t, eventType, &schema, reflect.TypeOf(sample).Elem())
+		})
+	}
+}
+
+// TestEverySchemaHasAType catches schema files left behind for types that
+// no longer exist.
+func TestEverySchemaHasAType(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("schemas", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		if name == "envelope.json" {
+			continue
+		}
+		eventType := name[:strings.LastIndex(strings.TrimSuffix(name, ".json"), ".v")]
+		if _, ok := currentVersions[eventType]; !ok {
+			t.Errorf("schema %s has no event type", name)
+		}
+	}
+}
+
+func compareSchema(t *testing.T, path string, schema *jsonSchema, structType reflect.Type) {
+	t.Helper()
+	if schema.Type != "object" {
+		t.Errorf("%s: schema type is %q, want object", path, schema.Type)
+		return
+	}
+
+	fields := jsonFields(structType)
+	for name, fieldType := range fields {
+		property, ok := schema.Properties[name]
+		if !ok {
+			t.Errorf("%s: field %q is missing from the schema", path, name)
+			continue
+		}
+		compareType(t, path+"."+name, property, fieldType)
+	}
+
+	var extra []string
+	for name := range schema.Properties {
+		if _, ok := fields[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		t.Errorf("%s: schema property %q has no field in the struct", path, name)
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("%s: required property %q is not defined", path, name)
+		}
+	}
+}
+
+func compareType(t *testing.T, path string, schema *jsonSchema, fieldType reflect.Type) {
+	t.Helper()
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		if schema.Type != "string" || schema.Format != "date-time" {
+			t.Errorf("%s: time is %q/%q in the schema, want string/date-time", path, schema.Type, schema.Format)
+		}
+		return
+	}
+
+	var want string
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		want = "integer"
+	case reflect.Float32, reflect.Float64:
+		want = "number"
+	case reflect.String:
+		want = "string"
+	case reflect.Bool:
+		want = "boolean"
+	case reflect.Map:
+		want = "object"
+	case reflect.Slice:
+		if schema.Type != "array" {
+			t.Errorf("%s: schema type is %q, want array", path, schema.Type)
+			return
+		}
+		if schema.Items == nil {
+			t.Errorf("%s: array has no items schema", path)
+			return
+		}
+		elem := fieldType.Elem()
+		if elem.Kind() == reflect.Struct && elem != reflect.TypeOf(time.Time{}) {
+			compareSchema(t, path+"[]", schema.Items, elem)
+		} else {
+			compareType(t, path+"[]", schema.Items, elem)
+		}
+		return
+	case reflect.Struct:
+		compareSchema(t, path, schema, fieldType)
+		return
+	default:
+		t.Errorf("%s: unsupported field kind %s", path, fieldType.Kind())
+		return
+	}
+	if schema.Type != want {
+		t.Errorf("%s: schema type is %q, want %q", path, schema.Type, want)
+	}
+}
+
+// jsonFields returns the JSON names of a struct's exported fields with
+// their types.
+func jsonFields(structType reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
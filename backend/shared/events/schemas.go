@@ -0,0 +1,168 @@
+package events
+
+import (
+	"time"
+)
+
+// Event types, used as the CloudEvents type attribute.
+const (
+	TypeBookingConfirmed           = "tickethub.booking.confirmed"
+	TypeBookingCancelled           = "tickethub.booking.cancelled"
+	TypeRefundRequested            = "tickethub.payment.refund_requested"
+	TypePaymentSucceeded           = "tickethub.payment.succeeded"
+	TypePaymentRefunded            = "tickethub.payment.refunded"
+	TypeTicketIssued               = "tickethub.ticket.issued"
+	TypeAuditLogRecorded           = "tickethub.audit.recorded"
+	TypeEmailVerificationRequested = "tickethub.auth.email_verification_requested"
+	TypePasswordResetRequested     = "tickethub.auth.password_reset_requested"
+	TypeEventStatusChanged         = "tickethub.event.status_changed"
+	TypeEventCancelled             = "tickethub.event.cancelled"
+	TypeEventRefundRequested       = "tickethub.payment.event_refund_requested"
+)
+
+// currentVersions is the schema version producers stamp on each type and the
+// version consumers are built against. Keep it in sync with schemas/.
+var currentVersions = map[string]string{
+	TypeBookingConfirmed:           "1.2",
+	TypeBookingCancelled:           "1.1",
+	TypeRefundRequested:            "1.0",
+	TypePaymentSucceeded:           "1.0",
+	TypePaymentRefunded:            "1.0",
+	TypeTicketIssued:               "1.0",
+	TypeAuditLogRecorded:           "1.0",
+	TypeEmailVerificationRequested: "1.0",
+	TypePasswordResetRequested:     "1.0",
+	TypeEventStatusChanged:         "1.1",
+	TypeEventCancelled:             "1.0",
+	TypeEventRefundRequested:       "1.0",
+}
+
+// CurrentVersion returns the schema version of an event type.
+func CurrentVersion(eventType string) string {
+	return currentVersions[eventType]
+}
+
+// BookingConfirmed is published by the booking service once a booking is paid.
+type BookingConfirmed struct {
+	BookingID uint    `json:"booking_id"`
+	UserID    uint    `json:"user_id"`
+	EventID   uint    `json:"event_id"`
+	Amount    float64 `json:"amount"`
+	SeatCount int     `json:"seat_count"`
+	Seats     string  `json:"seats"` // JSON array of seat IDs
+	// TicketClasses is the number of tickets per tier code. Added in 1.1.
+	TicketClasses map[string]int `json:"ticket_classes,omitempty"`
+	// HoldToken is the token of the seat hold the booking was created
+	// under; only seats locked under it are sold. Added in 1.2.
+	HoldToken string `json:"hold_token,omitempty"`
+}
+
+// BookingCancelled is published by the booking service when a booking is cancelled.
+type BookingCancelled struct {
+	BookingID    uint    `json:"booking_id"`
+	UserID       uint    `json:"user_id"`
+	EventID      uint    `json:"event_id"`
+	SeatCount    int     `json:"seat_count"`
+	RefundAmount float64 `json:"refund_amount"`
+	Reason       string  `json:"reason"`
+	// EventCancelled is set when the booking was cancelled because its
+	// event was. Added in 1.1.
+	EventCancelled bool `json:"event_cancelled,omitempty"`
+}
+
+// RefundRequested asks the payment service to refund a booking's payment.
+type RefundRequested struct {
+	BookingID uint    `json:"booking_id"`
+	UserID    uint    `json:"user_id"`
+	Amount    float64 `json:"amount"`
+	Reason    string  `json:"reason"`
+}
+
+// PaymentSucceeded is published by the payment service when a payment is verified.
+type PaymentSucceeded struct {
+	BookingID uint    `json:"booking_id"`
+	UserID    uint    `json:"user_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// PaymentRefunded is published by the payment service after each refund.
+type PaymentRefunded struct {
+	PaymentID     uint    `json:"payment_id"`
+	RefundID      uint    `json:"refund_id"`
+	BookingID     uint    `json:"booking_id"`
+	UserID        uint    `json:"user_id"`
+	Amount        float64 `json:"amount"`
+	TotalRefunded float64 `json:"total_refunded"`
+	Status        string  `json:"status"`
+	Reason        string  `json:"reason"`
+}
+
+// TicketIssued is published by the notification service once a booking's
+// ticket has been generated and sent.
+type TicketIssued struct {
+	BookingID uint      `json:"booking_id"`
+	UserID    uint      `json:"user_id"`
+	IssuedAt  time.Time `json:"issued_at"`
+}
+
+// AuditLogRecorded is an audit trail entry stored by the auth service.
+type AuditLogRecorded struct {
+	UserID    uint      `json:"user_id"`
+	Action    string    `json:"action"`
+	Details   string    `json:"details"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailVerificationRequested asks the notification service to send a
+// verification code.
+type EmailVerificationRequested struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// PasswordResetRequested asks the notification service to send a password
+// reset code.
+type PasswordResetRequested struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// EventStatusChanged is published by the event service whenever an event
+// moves through its lifecycle (draft, published, on_sale, sold_out,
+// sales_closed since 1.1, cancelled, completed).
+type EventStatusChanged struct {
+	EventID     uint      `json:"event_id"`
+	OrganizerID uint      `json:"organizer_id"`
+	Title       string    `json:"title"`
+	Date        time.Time `json:"date"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	Reason      string    `json:"reason"`
+}
+
+// EventCancelled is published by the event service when an event is
+// cancelled, so its bookings can be cancelled and refunded.
+type EventCancelled struct {
+	EventID     uint      `json:"event_id"`
+	OrganizerID uint      `json:"organizer_id"`
+	Title       string    `json:"title"`
+	Date        time.Time `json:"date"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
+
+// EventRefundRequested asks the payment service to refund every booking of
+// a cancelled event in full, as a single refund job.
+type EventRefundRequested struct {
+	EventID  uint            `json:"event_id"`
+	Reason   string          `json:"reason"`
+	Bookings []BookingRefund `json:"bookings"`
+}
+
+// BookingRefund is a booking to refund as part of an EventRefundRequested.
+type BookingRefund struct {
+	BookingID uint    `json:"booking_id"`
+	UserID    uint    `json:"user_id"`
+	Amount    float64 `json:"amount"` // the booking's total, for the job report
+}
@@ -0,0 +1,151 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Header = "Idempotency-Key"
+
+// responseRecorder keeps a copy of the response body so it can be stored
+// against the idempotency key.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func ttlFromEnv() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
+
+// Middleware replays the stored response when a request is retried with
+// the same Idempotency-Key. Keys are scoped to the route and the caller,
+// and reusing a key with a different body is rejected with 409 Conflict.
+// Requests without the header are passed through unchanged.
+func Middleware(repo Repository) gin.HandlerFunc {
+	ttl := ttlFromEnv()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		userID, _ := c.Get("user_id")
+		scope := fmt.Sprintf("%s %s user:%v", c.Request.Method, c.FullPath(), userID)
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		record := &Record{
+			Key:         key,
+			Scope:       scope,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		reserved, err := repo.Reserve(record)
+		if err == nil && !reserved {
+			var existing *Record
+			existing, err = repo.Find(scope, key)
+			if err == nil && time.Now().After(existing.ExpiresAt) {
+				// The previous use is outside the window; start over with this request
+				if err = repo.Delete(existing.ID); err == nil {
+					reserved, err = repo.Reserve(record)
+				}
+			}
+			if err == nil && !reserved {
+				replay(c, existing, requestHash)
+				return
+			}
+		}
+		if err != nil {
+			log.Printf("Idempotency check failed for key %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		// A panicking handler must not leave the key in progress until it
+		// expires; release it and let the recovery middleware answer
+		defer func() {
+			if r := recover(); r != nil {
+				release(repo, record)
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+
+		c.Next()
+
+		// Server errors are not stored so the client can retry with the same key
+		if recorder.Status() >= http.StatusInternalServerError {
+			release(repo, record)
+			return
+		}
+		if err := repo.Complete(record.ID, recorder.Status(), recorder.body.String()); err != nil {
+			log.Printf("Failed to store response for idempotency key %s: %v", key, err)
+		}
+	}
+}
+
+// release drops a reserved key so the request can be retried with it.
+func release(repo Repository, record *Record) {
+	if err := repo.Delete(record.ID); err != nil {
+		log.Printf("Failed to release idempotency key %s: %v", record.Key, err)
+	}
+}
+
+func replay(c *gin.Context, existing *Record, requestHash string) {
+	if existing.RequestHash != requestHash {
+		c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key has already been used with a different request"})
+		c.Abort()
+		return
+	}
+	if !existing.Completed {
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+		c.Abort()
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(existing.StatusCode, "application/json; charset=utf-8", []byte(existing.ResponseBody))
+	c.Abort()
+}
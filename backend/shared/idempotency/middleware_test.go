@@ -0,0 +1,214 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryRepository is a Repository kept in a map.
+type memoryRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	records map[uint]*Record
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{records: map[uint]*Record{}}
+}
+
+func (r *memoryRepository) Reserve(record *Record) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.records {
+		if existing.Scope == record.Scope && existing.Key == record.Key {
+			return false, nil
+		}
+	}
+	r.nextID++
+	record.ID = r.nextID
+	stored := *record
+	r.records[record.ID] = &stored
+	return true, nil
+}
+
+func (r *memoryRepository) Find(scope, key string) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.records {
+		if existing.Scope == scope && existing.Key == key {
+			found := *existing
+			return &found, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memoryRepository) Complete(id uint, statusCode int, responseBody string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.records[id]
+	record.Completed, record.StatusCode, record.ResponseBody = true, statusCode, responseBody
+	return nil
+}
+
+func (r *memoryRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.records, id)
+	return nil
+}
+
+func (r *memoryRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.records)
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newRouter serves POST /orders behind the middleware. The handler counts
+// its calls and answers with the given status, or panics on "panic".
+func newRouter(repo Repository, calls *int) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.POST("/orders", Middleware(repo), func(c *gin.Context) {
+		*calls++
+		switch c.Query("outcome") {
+		case "panic":
+			panic("handler failed")
+		case "error":
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+		default:
+			c.JSON(http.StatusCreated, gin.H{"order": *calls})
+		}
+	})
+	return r
+}
+
+func send(r *gin.Engine, key, outcome, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders?outcome="+outcome, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	type step struct {
+		key, outcome, body string
+		wantStatus         int
+		wantBody           string
+		wantReplayed       bool
+	}
+	tests := []struct {
+		name      string
+		steps     []step
+		wantCalls int
+		wantKeys  int
+	}{
+		{
+			name: "replays the first response",
+			steps: []step{
+				{key: "k1", body: `{"a":1}`, wantStatus: http.StatusCreated, wantBody: `{"order":1}`},
+				{key: "k1", body: `{"a":1}`, wantStatus: http.StatusCreated, wantBody: `{"order":1}`, wantReplayed: true},
+			},
+			wantCalls: 1,
+			wantKeys:  1,
+		},
+		{
+			name: "rejects a different body with the same key",
+			steps: []step{
+				{key: "k1", body: `{"a":1}`, wantStatus: http.StatusCreated},
+				{key: "k1", body: `{"a":2}`, wantStatus: http.StatusConflict},
+			},
+			wantCalls: 1,
+			wantKeys:  1,
+		},
+		{
+			name: "passes requests without a key through",
+			steps: []step{
+				{body: `{"a":1}`, wantStatus: http.StatusCreated},
+				{body: `{"a":1}`, wantStatus: http.StatusCreated},
+			},
+			wantCalls: 2,
+			wantKeys:  0,
+		},
+		{
+			name: "releases the key after a server error",
+			steps: []step{
+				{key: "k1", outcome: "error", body: `{"a":1}`, wantStatus: http.StatusInternalServerError},
+				{key: "k1", body: `{"a":1}`, wantStatus: http.StatusCreated, wantBody: `{"order":2}`},
+			},
+			wantCalls: 2,
+			wantKeys:  1,
+		},
+		{
+			name: "releases the key after a panic",
+			steps: []step{
+				{key: "k1", outcome: "panic", body: `{"a":1}`, wantStatus: http.StatusInternalServerError},
+				{key: "k1", body: `{"a":1}`, wantStatus: http.StatusCreated, wantBody: `{"order":2}`},
+			},
+			wantCalls: 2,
+			wantKeys:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryRepository()
+			calls := 0
+			r := newRouter(repo, &calls)
+
+			for i, s := range tt.steps {
+				w := send(r, s.key, s.outcome, s.body)
+				if w.Code != s.wantStatus {
+					t.Fatalf("request %d: status %d, want %d", i+1, w.Code, s.wantStatus)
+				}
+				if s.wantBody != "" && w.Body.String() != s.wantBody {
+					t.Errorf("request %d: body %s, want %s", i+1, w.Body.String(), s.wantBody)
+				}
+				if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != s.wantReplayed {
+					t.Errorf("request %d: replayed %v, want %v", i+1, replayed, s.wantReplayed)
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+			if n := repo.count(); n != tt.wantKeys {
+				t.Errorf("%d keys stored, want %d", n, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejectsKeyInProgress(t *testing.T) {
+	repo := newMemoryRepository()
+	// The first request is still running
+	sum := sha256.Sum256([]byte(`{"a":1}`))
+	repo.Reserve(&Record{Key: "k1", Scope: "POST /orders user:<nil>", RequestHash: hex.EncodeToString(sum[:]), ExpiresAt: time.Now().Add(time.Hour)})
+
+	calls := 0
+	w := send(newRouter(repo, &calls), "k1", "", `{"a":1}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "still being processed") {
+		t.Errorf("got %d %s, want 409 still being processed", w.Code, w.Body.String())
+	}
+	if calls != 0 {
+		t.Errorf("handler ran %d times, want 0", calls)
+	}
+}
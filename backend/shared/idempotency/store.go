@@ -0,0 +1,74 @@
+// Package idempotency honours the Idempotency-Key header. The first
+// response produced for a key is stored and replayed for retries of the
+// same request within the TTL, so a double-clicked or retried request is
+// only executed once.
+package idempotency
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Record stores the first response produced for an Idempotency-Key so
+// that retries of the same request can be answered without re-executing it.
+type Record struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Key          string    `gorm:"not null;uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	Scope        string    `gorm:"not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"`
+	RequestHash  string    `gorm:"not null" json:"request_hash"`
+	Completed    bool      `gorm:"default:false" json:"completed"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody string    `json:"response_body"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+type Repository interface {
+	// Reserve inserts the key and reports false if it already exists for the scope.
+	Reserve(record *Record) (bool, error)
+	Find(scope, key string) (*Record, error)
+	Complete(id uint, statusCode int, responseBody string) error
+	Delete(id uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository keeps keys in the service's own database, in the
+// idempotency_keys table.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Reserve(record *Record) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) Find(scope, key string) (*Record, error) {
+	var record Record
+	err := r.db.Where("scope = ? AND key = ?", scope, key).First(&record).Error
+	return &record, err
+}
+
+func (r *repository) Complete(id uint, statusCode int, responseBody string) error {
+	return r.db.Model(&Record{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"completed":     true,
+		"status_code":   statusCode,
+		"response_body": responseBody,
+	}).Error
+}
+
+func (r *repository) Delete(id uint) error {
+	return r.db.Delete(&Record{}, id).Error
+}
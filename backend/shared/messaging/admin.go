@@ -0,0 +1,106 @@
+package messaging
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterHandler serves the admin API for a client's dead-letter queues.
+// Routes must be mounted behind the service's auth middleware; every handler
+// requires the admin role.
+type DeadLetterHandler struct {
+	client *Client
+}
+
+func NewDeadLetterHandler(client *Client) *DeadLetterHandler {
+	return &DeadLetterHandler{client: client}
+}
+
+// RegisterRoutes mounts the dead-letter endpoints under r:
+//
+//	GET  /dead-letters                      counts per consumed queue
+//	GET  /dead-letters?queue=<q>&limit=<n>  messages of one queue
+//	GET  /dead-letters/:queue/:id           a single message
+//	POST /dead-letters/:queue/:id/replay    move a message back onto its queue
+func (h *DeadLetterHandler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/dead-letters", h.ListDeadLetters)
+	r.GET("/dead-letters/:queue/:id", h.GetDeadLetter)
+	r.POST("/dead-letters/:queue/:id/replay", h.ReplayDeadLetter)
+}
+
+func requireAdmin(c *gin.Context) bool {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Requires Admin role"})
+		return false
+	}
+	return true
+}
+
+func deadLetterError(c *gin.Context, err error) {
+	switch err {
+	case ErrUnknownQueue, ErrDeadLetterNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrNotConnected:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	queue := c.Query("queue")
+	if queue == "" {
+		stats, err := h.client.DeadLetterStats()
+		if err != nil {
+			deadLetterError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"queues": stats})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	letters, err := h.client.DeadLetters(queue, limit)
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"queue": queue, "messages": letters})
+}
+
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	letter, err := h.client.DeadLetter(c.Param("queue"), c.Param("id"))
+	if err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": letter})
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	if err := h.client.ReplayDeadLetter(c.Param("queue"), c.Param("id")); err != nil {
+		deadLetterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message replayed"})
+}
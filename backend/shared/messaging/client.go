@@ -0,0 +1,408 @@
+// Package messaging is the RabbitMQ client shared by all services. A Client
+// keeps one long-lived connection, reconnects with backoff when the broker
+// goes away, re-declares the registered topology and restarts consumers on
+// every reconnect, and publishes with publisher confirms. Consumers ack
+// manually; failed deliveries are retried through a delay queue and end up
+// in a per-queue dead-letter queue (see retry.go).
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	ErrNotConnected = errors.New("rabbitmq is not connected")
+	ErrClosed       = errors.New("rabbitmq client is closed")
+)
+
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Topology declares exchanges, queues and bindings on a fresh connection.
+type Topology func(ch *amqp.Channel) error
+
+// Handler processes a single delivery. Returning an error retries the
+// delivery later; wrap the error with Permanent to dead-letter it at once.
+type Handler func(d amqp.Delivery) error
+
+// Deduplicator wraps the handler of a queue so that a message delivered
+// more than once is only processed once.
+type Deduplicator interface {
+	Wrap(queue string, handler Handler) Handler
+}
+
+type consumer struct {
+	queue   string
+	handler Handler
+}
+
+type Client struct {
+	url            string
+	confirmTimeout time.Duration
+	retryDelay     time.Duration
+	maxRetries     int
+	prefetch       int
+
+	mu        sync.Mutex
+	conn      *amqp.Connection
+	publisher *amqp.Channel
+	confirms  chan amqp.Confirmation
+	returns   chan amqp.Return
+	nextTag   uint64
+	topology  []Topology
+	consumers []consumer
+	dedup     Deduplicator
+	closed    bool
+
+	// publishMu keeps a single publish in flight so each confirm can be
+	// matched to its message.
+	publishMu sync.Mutex
+}
+
+// URLFromEnv builds the broker URL from RABBITMQ_USER, RABBITMQ_PASS,
+// RABBITMQ_HOST and RABBITMQ_PORT.
+func URLFromEnv() string {
+	return fmt.Sprintf("amqp://%s:%s@%s:%s/",
+		os.Getenv("RABBITMQ_USER"),
+		os.Getenv("RABBITMQ_PASS"),
+		os.Getenv("RABBITMQ_HOST"),
+		os.Getenv("RABBITMQ_PORT"),
+	)
+}
+
+// NewClient creates a client for url. Nothing is dialled until Connect.
+func NewClient(url string) *Client {
+	confirmTimeout, err := time.ParseDuration(os.Getenv("RABBITMQ_CONFIRM_TIMEOUT"))
+	if err != nil || confirmTimeout <= 0 {
+		confirmTimeout = 5 * time.Second
+	}
+
+	retryDelay, err := time.ParseDuration(os.Getenv("RABBITMQ_RETRY_DELAY"))
+	if err != nil || retryDelay <= 0 {
+		retryDelay = 10 * time.Second
+	}
+
+	maxRetries, err := strconv.Atoi(os.Getenv("RABBITMQ_MAX_RETRIES"))
+	if err != nil || maxRetries < 0 {
+		maxRetries = 3
+	}
+
+	return &Client{
+		url:            url,
+		confirmTimeout: confirmTimeout,
+		retryDelay:     retryDelay,
+		maxRetries:     maxRetries,
+		prefetch:       10,
+	}
+}
+
+// DeclareTopology registers fn to run on every (re)connect. It runs
+// immediately when the client is already connected.
+func (c *Client) DeclareTopology(fn Topology) error {
+	c.mu.Lock()
+	c.topology = append(c.topology, fn)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return runTopology(conn, []Topology{fn})
+}
+
+// Deduplicate makes every consumer registered afterwards skip messages it
+// has already processed.
+func (c *Client) Deduplicate(d Deduplicator) {
+	c.mu.Lock()
+	c.dedup = d
+	c.mu.Unlock()
+}
+
+// Consume registers handler for queue. The consumer is started now when the
+// client is connected, and again after every reconnect.
+func (c *Client) Consume(queue string, handler Handler) error {
+	c.mu.Lock()
+	if c.dedup != nil {
+		handler = c.dedup.Wrap(queue, handler)
+	}
+	cons := consumer{queue: queue, handler: handler}
+	c.consumers = append(c.consumers, cons)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return c.startConsumer(conn, cons)
+}
+
+// Connect dials the broker and keeps the connection alive in the background.
+// When the first attempt fails the error is returned, but the client keeps
+// retrying, so callers may log it and carry on.
+func (c *Client) Connect() error {
+	err := c.connect()
+	go c.maintain()
+	return err
+}
+
+// Close shuts the connection down and stops reconnecting.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	conn := c.conn
+	c.conn = nil
+	c.publisher = nil
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (c *Client) connect() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	topology := append([]Topology(nil), c.topology...)
+	consumers := append([]consumer(nil), c.consumers...)
+	c.mu.Unlock()
+
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+	}
+
+	if err := runTopology(conn, topology); err != nil {
+		conn.Close()
+		return err
+	}
+
+	publisher, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+	if err := publisher.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %v", err)
+	}
+	confirms := publisher.NotifyPublish(make(chan amqp.Confirmation, 1))
+	returns := publisher.NotifyReturn(make(chan amqp.Return, 1))
+
+	c.mu.Lock()
+	c.conn = conn
+	c.publisher = publisher
+	c.confirms = confirms
+	c.returns = returns
+	c.nextTag = 1
+	c.mu.Unlock()
+
+	// Consumers start once the publisher is ready, as failed deliveries are
+	// republished to the retry and dead-letter queues
+	for _, cons := range consumers {
+		if err := c.startConsumer(conn, cons); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	log.Println("Connected to RabbitMQ")
+	return nil
+}
+
+// maintain waits for the connection to drop and reconnects with exponential
+// backoff until the client is closed.
+func (c *Client) maintain() {
+	for {
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+
+		if conn != nil {
+			reason := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				return
+			}
+			c.conn = nil
+			c.publisher = nil
+			c.mu.Unlock()
+			log.Printf("RabbitMQ connection lost: %v", reason)
+		}
+
+		delay := minReconnectDelay
+		for {
+			time.Sleep(delay)
+			err := c.connect()
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrClosed) {
+				return
+			}
+			log.Printf("RabbitMQ reconnect failed, retrying in %s: %v", delay, err)
+			if delay *= 2; delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+	}
+}
+
+func runTopology(conn *amqp.Connection, topology []Topology) error {
+	if len(topology) == 0 {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+	defer ch.Close()
+
+	for _, declare := range topology {
+		if err := declare(ch); err != nil {
+			return fmt.Errorf("failed to declare topology: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) startConsumer(conn *amqp.Connection, cons consumer) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+
+	if err := declareRetryTopology(ch, cons.queue); err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to declare retry queues for %s: %v", cons.queue, err)
+	}
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to set prefetch for %s: %v", cons.queue, err)
+	}
+
+	msgs, err := ch.Consume(
+		cons.queue, // queue
+		"",         // consumer
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+	if err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to register a consumer for %s: %v", cons.queue, err)
+	}
+
+	// The delivery channel closes with the connection; the consumer is
+	// started again after the reconnect and unacked messages are redelivered
+	go func() {
+		for d := range msgs {
+			c.handle(cons, d)
+		}
+	}()
+	return nil
+}
+
+// Publish sends a persistent JSON message and waits for the broker to confirm
+// it. Messages that cannot be routed to any queue are reported as errors.
+func (c *Client) Publish(exchange, routingKey string, body []byte) error {
+	return c.PublishMessage(exchange, routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+}
+
+// PublishOptional is Publish for messages nobody may be subscribed to yet:
+// a message that cannot be routed to any queue is dropped by the broker
+// instead of being reported as an error.
+func (c *Client) PublishOptional(exchange, routingKey string, body []byte) error {
+	return c.publish(exchange, routingKey, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+}
+
+// PublishMessage is Publish for callers that need to set headers or other
+// message properties.
+func (c *Client) PublishMessage(exchange, routingKey string, msg amqp.Publishing) error {
+	return c.publish(exchange, routingKey, true, msg)
+}
+
+// publish sends msg and waits for its confirm. Mandatory messages that are
+// returned as unroutable are reported as errors.
+func (c *Client) publish(exchange, routingKey string, mandatory bool, msg amqp.Publishing) error {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	c.mu.Lock()
+	publisher, confirms, returns := c.publisher, c.confirms, c.returns
+	tag := c.nextTag
+	if publisher != nil {
+		c.nextTag++
+	}
+	c.mu.Unlock()
+
+	if publisher == nil {
+		return ErrNotConnected
+	}
+
+	// Drop returns left over from publishes that timed out
+	for drained := false; !drained; {
+		select {
+		case <-returns:
+		default:
+			drained = true
+		}
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	if err := publisher.Publish(exchange, routingKey, mandatory, false, msg); err != nil {
+		return fmt.Errorf("failed to publish a message: %v", err)
+	}
+
+	timeout := time.After(c.confirmTimeout)
+	for {
+		select {
+		case confirm, ok := <-confirms:
+			if !ok {
+				return ErrNotConnected
+			}
+			if confirm.DeliveryTag < tag {
+				continue
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("broker rejected message to %q with routing key %q", exchange, routingKey)
+			}
+			select {
+			case ret := <-returns:
+				return fmt.Errorf("message to %q with routing key %q was returned: %s", exchange, routingKey, ret.ReplyText)
+			default:
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for broker to confirm message to %q with routing key %q", exchange, routingKey)
+		}
+	}
+}
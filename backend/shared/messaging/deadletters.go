@@ -0,0 +1,188 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	ErrUnknownQueue       = errors.New("queue is not consumed by this service")
+	ErrDeadLetterNotFound = errors.New("dead-lettered message not found")
+)
+
+// DeadLetter is a message that ran out of retries.
+type DeadLetter struct {
+	ID          string      `json:"id"`
+	Queue       string      `json:"queue"`
+	Error       string      `json:"error"`
+	RetryCount  int         `json:"retry_count"`
+	FailedAt    string      `json:"failed_at"`
+	PublishedAt time.Time   `json:"published_at"`
+	ContentType string      `json:"content_type"`
+	Headers     amqp.Table  `json:"headers"`
+	Body        interface{} `json:"body"`
+}
+
+// DeadLetterQueueStats is the number of dead-lettered messages for a queue.
+type DeadLetterQueueStats struct {
+	Queue    string `json:"queue"`
+	Messages int    `json:"messages"`
+}
+
+func newDeadLetter(queue string, d amqp.Delivery) DeadLetter {
+	var body interface{} = string(d.Body)
+	if json.Valid(d.Body) {
+		body = json.RawMessage(d.Body)
+	}
+
+	return DeadLetter{
+		ID:          headerString(d.Headers, HeaderDeadLetterID),
+		Queue:       queue,
+		Error:       headerString(d.Headers, HeaderLastError),
+		RetryCount:  RetryCount(d.Headers),
+		FailedAt:    headerString(d.Headers, HeaderFailedAt),
+		PublishedAt: d.Timestamp,
+		ContentType: d.ContentType,
+		Headers:     d.Headers,
+		Body:        body,
+	}
+}
+
+// ConsumedQueues returns the queues the client has consumers for.
+func (c *Client) ConsumedQueues() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queues := make([]string, 0, len(c.consumers))
+	for _, cons := range c.consumers {
+		queues = append(queues, cons.queue)
+	}
+	return queues
+}
+
+func (c *Client) consumes(queue string) bool {
+	for _, q := range c.ConsumedQueues() {
+		if q == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// channel opens a short-lived channel for admin operations.
+func (c *Client) channel() (*amqp.Channel, error) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn.Channel()
+}
+
+// DeadLetterStats counts the dead-lettered messages of every consumed queue.
+func (c *Client) DeadLetterStats() ([]DeadLetterQueueStats, error) {
+	ch, err := c.channel()
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	var stats []DeadLetterQueueStats
+	for _, queue := range c.ConsumedQueues() {
+		q, err := ch.QueueInspect(DeadLetterQueue(queue))
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, DeadLetterQueueStats{Queue: queue, Messages: q.Messages})
+	}
+	return stats, nil
+}
+
+// scanDeadLetters fetches dead-lettered messages of queue without acking them
+// and calls visit for each until it returns false. Messages that visit does
+// not ack go back to the queue when the channel is closed.
+func (c *Client) scanDeadLetters(queue string, visit func(ch *amqp.Channel, d amqp.Delivery) (bool, error)) error {
+	if !c.consumes(queue) {
+		return ErrUnknownQueue
+	}
+
+	ch, err := c.channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for {
+		d, ok, err := ch.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := visit(ch, d)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// DeadLetters lists up to limit dead-lettered messages of queue, oldest first.
+func (c *Client) DeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	letters := []DeadLetter{}
+	err := c.scanDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) (bool, error) {
+		letters = append(letters, newDeadLetter(queue, d))
+		return len(letters) < limit, nil
+	})
+	return letters, err
+}
+
+// DeadLetter returns the dead-lettered message of queue with the given ID.
+func (c *Client) DeadLetter(queue, id string) (*DeadLetter, error) {
+	var found *DeadLetter
+	err := c.scanDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) (bool, error) {
+		if headerString(d.Headers, HeaderDeadLetterID) != id {
+			return true, nil
+		}
+		letter := newDeadLetter(queue, d)
+		found = &letter
+		return false, nil
+	})
+	if err == nil && found == nil {
+		err = ErrDeadLetterNotFound
+	}
+	return found, err
+}
+
+// ReplayDeadLetter moves the dead-lettered message with the given ID back
+// onto queue with a fresh retry budget.
+func (c *Client) ReplayDeadLetter(queue, id string) error {
+	replayed := false
+	err := c.scanDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) (bool, error) {
+		if headerString(d.Headers, HeaderDeadLetterID) != id {
+			return true, nil
+		}
+
+		msg := copyDelivery(d)
+		for _, header := range []string{HeaderRetryCount, HeaderLastError, HeaderDeadLetterID, HeaderFailedAt, HeaderOriginalQueue} {
+			delete(msg.Headers, header)
+		}
+		if err := c.PublishMessage("", queue, msg); err != nil {
+			return false, fmt.Errorf("failed to replay message: %v", err)
+		}
+
+		replayed = true
+		return false, d.Ack(false)
+	})
+	if err == nil && !replayed {
+		err = ErrDeadLetterNotFound
+	}
+	return err
+}
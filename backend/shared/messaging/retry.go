@@ -0,0 +1,193 @@
+package messaging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers added to retried and dead-lettered messages.
+const (
+	HeaderRetryCount    = "x-retry-count"
+	HeaderLastError     = "x-last-error"
+	HeaderDeadLetterID  = "x-dead-letter-id"
+	HeaderFailedAt      = "x-failed-at"
+	HeaderOriginalQueue = "x-original-queue"
+)
+
+// RetryQueue holds failed deliveries of queue until the retry delay passes,
+// then dead-letters them back onto queue.
+func RetryQueue(queue string) string {
+	return queue + ".retry"
+}
+
+// DeadLetterExchange receives deliveries of queue that ran out of retries.
+func DeadLetterExchange(queue string) string {
+	return queue + ".dlx"
+}
+
+// DeadLetterQueue is bound to DeadLetterExchange and keeps the messages
+// until an admin replays them.
+func DeadLetterQueue(queue string) string {
+	return queue + ".dlq"
+}
+
+// PermanentError marks a failure that retrying cannot fix, such as a
+// malformed message.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so the delivery is dead-lettered without retries.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func declareRetryTopology(ch *amqp.Channel, queue string) error {
+	_, err := ch.QueueDeclare(
+		RetryQueue(queue), // name
+		true,              // durable
+		false,             // delete when unused
+		false,             // exclusive
+		false,             // no-wait
+		amqp.Table{
+			// Expired messages go back to the work queue through the default exchange
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = ch.ExchangeDeclare(
+		DeadLetterExchange(queue), // name
+		"direct",                  // type
+		true,                      // durable
+		false,                     // auto-deleted
+		false,                     // internal
+		false,                     // no-wait
+		nil,                       // arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := DeclareQueues(DeadLetterQueue(queue))(ch); err != nil {
+		return err
+	}
+	return ch.QueueBind(DeadLetterQueue(queue), queue, DeadLetterExchange(queue), false, nil)
+}
+
+// handle runs the consumer's handler and acks the delivery once it has been
+// processed, scheduled for a retry or dead-lettered. If the failed delivery
+// cannot be moved it is requeued instead.
+func (c *Client) handle(cons consumer, d amqp.Delivery) {
+	err := cons.handler(d)
+	if err == nil {
+		d.Ack(false)
+		return
+	}
+
+	retries := RetryCount(d.Headers)
+	if deadLetters(err, retries, c.maxRetries) {
+		log.Printf("Dead-lettering message from %s after %d retries: %v", cons.queue, retries, err)
+		err = c.deadLetter(cons.queue, d, err)
+	} else {
+		log.Printf("Retrying message from %s in %s (attempt %d of %d): %v", cons.queue, c.retryDelay, retries+1, c.maxRetries, err)
+		err = c.retry(cons.queue, d, retries+1, err)
+	}
+
+	if err != nil {
+		log.Printf("Failed to move message from %s, requeueing it: %v", cons.queue, err)
+		time.Sleep(time.Second)
+		d.Nack(false, true)
+		return
+	}
+	d.Ack(false)
+}
+
+// deadLetters reports whether a failed delivery goes to the dead-letter
+// queue rather than being retried: permanent failures go at once, others
+// once they have been retried maxRetries times.
+func deadLetters(err error, retries, maxRetries int) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent) || retries >= maxRetries
+}
+
+func (c *Client) retry(queue string, d amqp.Delivery, attempt int, cause error) error {
+	msg := copyDelivery(d)
+	msg.Headers[HeaderRetryCount] = int32(attempt)
+	msg.Headers[HeaderLastError] = cause.Error()
+	msg.Expiration = strconv.FormatInt(c.retryDelay.Milliseconds(), 10)
+
+	return c.PublishMessage("", RetryQueue(queue), msg)
+}
+
+func (c *Client) deadLetter(queue string, d amqp.Delivery, cause error) error {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	msg := copyDelivery(d)
+	msg.Headers[HeaderDeadLetterID] = hex.EncodeToString(id)
+	msg.Headers[HeaderLastError] = cause.Error()
+	msg.Headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+	msg.Headers[HeaderOriginalQueue] = queue
+
+	return c.PublishMessage(DeadLetterExchange(queue), queue, msg)
+}
+
+// RetryCount returns how many times a delivery has been retried.
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func copyDelivery(d amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: d.CorrelationId,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		AppId:         d.AppId,
+		Body:          d.Body,
+	}
+}
+
+func headerString(headers amqp.Table, key string) string {
+	if v, ok := headers[key]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
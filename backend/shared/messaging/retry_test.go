@@ -0,0 +1,81 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestDeadLetters(t *testing.T) {
+	failed := errors.New("database unavailable")
+	tests := []struct {
+		name    string
+		err     error
+		retries int
+		want    bool
+	}{
+		{"first failure", failed, 0, false},
+		{"last retry left", failed, 2, false},
+		{"out of retries", failed, 3, true},
+		{"permanent", Permanent(failed), 0, true},
+		{"wrapped permanent", fmt.Errorf("handling booking 7: %w", Permanent(failed)), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deadLetters(tt.err, tt.retries, 3); got != tt.want {
+				t.Errorf("deadLetters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		headers amqp.Table
+		want    int
+	}{
+		{nil, 0},
+		{amqp.Table{}, 0},
+		{amqp.Table{HeaderRetryCount: int32(2)}, 2},
+		{amqp.Table{HeaderRetryCount: int64(3)}, 3},
+		{amqp.Table{HeaderRetryCount: 1}, 1},
+		{amqp.Table{HeaderRetryCount: "2"}, 0},
+	}
+	for _, tt := range tests {
+		if got := RetryCount(tt.headers); got != tt.want {
+			t.Errorf("RetryCount(%v) = %d, want %d", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestQueueNames(t *testing.T) {
+	queue := "booking-service.event_cancelled"
+	if got := RetryQueue(queue); got != queue+".retry" {
+		t.Errorf("RetryQueue = %q", got)
+	}
+	if got := DeadLetterExchange(queue); got != queue+".dlx" {
+		t.Errorf("DeadLetterExchange = %q", got)
+	}
+	if got := DeadLetterQueue(queue); got != queue+".dlq" {
+		t.Errorf("DeadLetterQueue = %q", got)
+	}
+}
+
+func TestCopyDeliveryKeepsOriginalHeaders(t *testing.T) {
+	d := amqp.Delivery{
+		Headers:   amqp.Table{"ce-id": "42"},
+		MessageId: "42",
+		Body:      []byte(`{}`),
+	}
+	msg := copyDelivery(d)
+	msg.Headers[HeaderRetryCount] = int32(1)
+
+	if _, ok := d.Headers[HeaderRetryCount]; ok {
+		t.Error("retry header was added to the original delivery")
+	}
+	if msg.Headers["ce-id"] != "42" || msg.MessageId != "42" || msg.DeliveryMode != amqp.Persistent {
+		t.Errorf("copy lost properties: %+v", msg)
+	}
+}
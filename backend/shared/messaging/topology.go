@@ -0,0 +1,54 @@
+package messaging
+
+import (
+	"github.com/streadway/amqp"
+)
+
+// DeclareQueues declares durable queues on the default exchange.
+func DeclareQueues(names ...string) Topology {
+	return func(ch *amqp.Channel) error {
+		for _, name := range names {
+			_, err := ch.QueueDeclare(
+				name,  // name
+				true,  // durable
+				false, // delete when unused
+				false, // exclusive
+				false, // no-wait
+				nil,   // arguments
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DeclareFanout declares a durable fanout exchange and binds the given
+// durable queues to it, one per subscribing service.
+func DeclareFanout(exchange string, queues ...string) Topology {
+	return func(ch *amqp.Channel) error {
+		err := ch.ExchangeDeclare(
+			exchange, // name
+			"fanout", // type
+			true,     // durable
+			false,    // auto-deleted
+			false,    // internal
+			false,    // no-wait
+			nil,      // arguments
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := DeclareQueues(queues...)(ch); err != nil {
+			return err
+		}
+		for _, queue := range queues {
+			if err := ch.QueueBind(queue, "", exchange, false, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+// Package outbox is the transactional outbox of the services. A message is
+// written in the same database transaction as the state change it
+// announces, and a relay publishes pending messages to RabbitMQ and marks
+// them sent, so no state change is lost while the broker is away.
+package outbox
+
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+)
+
+// Message is a message written in the same transaction as the state
+// change it announces. The relay publishes pending rows to RabbitMQ.
+type Message struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Exchange   string     `json:"exchange"`
+	RoutingKey string     `gorm:"not null" json:"routing_key"`
+	Payload    string     `gorm:"type:text;not null" json:"payload"`
+	Status     Status     `gorm:"default:'pending';index" json:"status"`
+	Attempts   int        `gorm:"default:0" json:"attempts"`
+	LastError  string     `json:"last_error"`
+	SentAt     *time.Time `json:"sent_at"`
+
+	// Optional messages may have no subscriber: they are published without
+	// the mandatory flag, so the broker drops them instead of returning them
+	// when no queue is bound to the exchange
+	Optional bool `gorm:"default:false" json:"optional"`
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+)
+
+// Publisher sends a message to RabbitMQ; *messaging.Client is one.
+type Publisher interface {
+	Publish(exchange, routingKey string, body []byte) error
+	// PublishOptional sends a message the broker may drop when no queue is
+	// bound to the exchange.
+	PublishOptional(exchange, routingKey string, body []byte) error
+}
+
+// Send returns the publish function the relay uses: optional messages are
+// published without the mandatory flag.
+func Send(publisher Publisher) func(message *Message) error {
+	return func(message *Message) error {
+		if message.Optional {
+			return publisher.PublishOptional(message.Exchange, message.RoutingKey, []byte(message.Payload))
+		}
+		return publisher.Publish(message.Exchange, message.RoutingKey, []byte(message.Payload))
+	}
+}
+
+// Deliver hands pending messages to publish in order and records the
+// outcome on each: published messages are marked sent, a failed one keeps
+// the error. After a failure the later messages to the same exchange are
+// held back so they keep their order; messages to other exchanges are
+// still published. It returns the messages it tried.
+func Deliver(messages []Message, publish func(message *Message) error) []*Message {
+	var tried []*Message
+	blocked := make(map[string]bool)
+	for i := range messages {
+		message := &messages[i]
+		if blocked[message.Exchange] {
+			continue
+		}
+		tried = append(tried, message)
+		message.Attempts++
+		if err := publish(message); err != nil {
+			message.LastError = err.Error()
+			blocked[message.Exchange] = true
+			continue
+		}
+		now := time.Now()
+		message.Status = StatusSent
+		message.SentAt = &now
+		message.LastError = ""
+	}
+	return tried
+}
+
+// StartRelay periodically publishes pending outbox messages to RabbitMQ.
+func StartRelay(store *Store, publisher Publisher) {
+	ticker := time.NewTicker(5 * time.Second)
+	go func() {
+		for range ticker.C {
+			sent, err := store.RelayPending(100, Send(publisher))
+			if err != nil {
+				fmt.Printf("Error in outbox relay: %v\n", err)
+			}
+			if sent > 0 {
+				fmt.Printf("Outbox relay published %d message(s)\n", sent)
+			}
+		}
+	}()
+}
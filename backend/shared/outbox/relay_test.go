@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+)
+
+// broker accepts mandatory messages only on exchanges with a bound queue,
+// as RabbitMQ returns them otherwise.
+type broker struct {
+	bound     map[string]bool
+	published []string
+}
+
+func (b *broker) Publish(exchange, routingKey string, body []byte) error {
+	if !b.bound[exchange] {
+		return errors.New("message was returned: NO_ROUTE")
+	}
+	b.published = append(b.published, exchange)
+	return nil
+}
+
+func (b *broker) PublishOptional(exchange, routingKey string, body []byte) error {
+	if b.bound[exchange] {
+		b.published = append(b.published, exchange)
+	}
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   []Message
+		wantStatus []Status
+		wantTried  int
+	}{
+		{
+			name:       "bound exchanges",
+			messages:   []Message{{Exchange: "a"}, {Exchange: "b"}},
+			wantStatus: []Status{StatusSent, StatusSent},
+			wantTried:  2,
+		},
+		{
+			name:       "optional message without subscriber",
+			messages:   []Message{{Exchange: "none", Optional: true}, {Exchange: "a"}},
+			wantStatus: []Status{StatusSent, StatusSent},
+			wantTried:  2,
+		},
+		{
+			name:       "failure does not hold back other exchanges",
+			messages:   []Message{{Exchange: "none"}, {Exchange: "a"}, {Exchange: "b"}},
+			wantStatus: []Status{StatusPending, StatusSent, StatusSent},
+			wantTried:  3,
+		},
+		{
+			name:       "failure holds back later messages to its exchange",
+			messages:   []Message{{Exchange: "a"}, {Exchange: "none"}, {Exchange: "b"}, {Exchange: "none", Optional: true}},
+			wantStatus: []Status{StatusSent, StatusPending, StatusSent, StatusPending},
+			wantTried:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.messages {
+				tt.messages[i].Status = StatusPending
+			}
+			b := &broker{bound: map[string]bool{"a": true, "b": true}}
+
+			tried := Deliver(tt.messages, Send(b))
+			if len(tried) != tt.wantTried {
+				t.Errorf("tried %d messages, want %d", len(tried), tt.wantTried)
+			}
+			for i, message := range tt.messages {
+				if message.Status != tt.wantStatus[i] {
+					t.Errorf("message %d to %q is %s, want %s", i, message.Exchange, message.Status, tt.wantStatus[i])
+				}
+				if message.Status == StatusSent && (message.SentAt == nil || message.LastError != "") {
+					t.Errorf("message %d sent without a time or with error %q", i, message.LastError)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverRecordsFailure(t *testing.T) {
+	messages := []Message{{Exchange: "none", Status: StatusPending, Attempts: 2}}
+	Deliver(messages, Send(&broker{}))
+	if messages[0].Attempts != 3 || messages[0].LastError == "" {
+		t.Errorf("got attempts %d and error %q, want 3 and the publish error", messages[0].Attempts, messages[0].LastError)
+	}
+}
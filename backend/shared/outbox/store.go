@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Store keeps the outbox in the service's own database, in the
+// outbox_messages table.
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// RelayPending hands up to limit pending messages to publish, oldest first,
+// and records the outcome of each as Deliver does. Rows are locked while
+// they are being published so concurrent relays skip them.
+func (s *Store) RelayPending(limit int, publish func(message *Message) error) (int, error) {
+	sent := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var messages []Message
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", StatusPending).
+			Order("id").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil {
+			return err
+		}
+
+		for _, message := range Deliver(messages, publish) {
+			err := tx.Model(message).Updates(map[string]interface{}{
+				"attempts":   message.Attempts,
+				"status":     message.Status,
+				"sent_at":    message.SentAt,
+				"last_error": message.LastError,
+			}).Error
+			if err != nil {
+				return err
+			}
+			if message.Status == StatusSent {
+				sent++
+			}
+		}
+		return nil
+	})
+	return sent, err
+}
@@ -8,6 +8,7 @@ import { EventDetails } from './checkout/EventDetails';
 import { PaymentForm } from './checkout/PaymentForm';
 import { Seat } from '../../types';
 import { Sidebar } from '../layout/Sidebar';
+import { getAdmissionToken } from '../events/WaitingRoomPanel';
 
 interface CheckoutSummaryNewProps {
   selectedSeats?: Seat[];
@@ -38,6 +39,8 @@ export function CheckoutSummaryNew({
   onLogout 
 }: CheckoutSummaryNewProps) {
   const [isProcessing, setIsProcessing] = useState(false);
+  // Reused across retries of the same checkout so the backend can de-duplicate them
+  const [checkoutKey, setCheckoutKey] = useState(() => crypto.randomUUID());
 
   let subtotal = 0;
   let items: { id: string; label: string; sublabel: string; price: number }[] = [];
@@ -92,13 +95,16 @@ export function CheckoutSummaryNew({
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
-          'Authorization': `Bearer ${token}`
+          'Authorization': `Bearer ${token}`,
+          'Idempotency-Key': `${checkoutKey}-booking`
         },
         body: JSON.stringify({
           event_id: eventId,
           seat_count: seatCount,
+          ticket_class: ticketDetails?.ticketClass,
           amount: total,
-          seats: selectedSeats
+          seats: selectedSeats,
+          admission_token: getAdmissionToken(eventId)
         }),
       });
 
@@ -106,6 +112,7 @@ export function CheckoutSummaryNew({
         const errorData = await bookingResponse.json();
         // console.error('Booking creation failed:', errorData);
         toast.error(errorData.error || 'Booking failed. Please try again.');
+        setCheckoutKey(crypto.randomUUID());
         setIsProcessing(false);
         return;
       }
@@ -125,11 +132,11 @@ export function CheckoutSummaryNew({
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`,
+          'Idempotency-Key': `${checkoutKey}-payment`
         },
         body: JSON.stringify({
           booking_id: bookingId,
-          user_id: userId,
-          amount: total,
           email: userEmail,
           first_name: firstName,
           last_name: lastName
@@ -143,6 +150,7 @@ export function CheckoutSummaryNew({
         const errorData = await response.json();
         console.error('Payment initialization failed:', errorData);
         toast.error(errorData.error || 'Payment initialization failed.');
+        setCheckoutKey(crypto.randomUUID());
         setIsProcessing(false);
       }
     } catch (error) {
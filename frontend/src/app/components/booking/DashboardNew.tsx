@@ -25,63 +25,39 @@ export function DashboardNew({ userEmail, event, ticketClass, onCheckout, onLogo
 
   useEffect(() => {
     const loadSeats = async () => {
-      const initialSeats: Seat[] = [];
-      
-      // Determine seats and price based on class
-      let totalSeats = event.seatsNormal;
-      let price = event.priceNormal;
-      
-      if (ticketClass === 'vip') {
-        totalSeats = event.seatsVIP;
-        price = event.priceVIP;
-      } else if (ticketClass === 'vvip') {
-        totalSeats = event.seatsVVIP;
-        price = event.priceVVIP;
-      }
-
-      // Calculate rows and cols (approximate)
-      const seatsPerRow = 10;
-      const rows = Math.ceil(totalSeats / seatsPerRow);
-      
-      for (let row = 1; row <= rows; row++) {
-        for (let number = 1; number <= seatsPerRow; number++) {
-          if (initialSeats.length >= totalSeats) break;
-
-          initialSeats.push({
-            id: `${ticketClass}-${row}-${number}`,
-            row,
-            number,
-            status: 'available',
-            price
-          });
-        }
-      }
+      const sectionSeats: Seat[] = [];
+      let rowOffset = 0;
 
+      // The seat map lists every seat of the venue with its live status;
+      // only the sections of the chosen ticket class are shown here.
       try {
-        const token = localStorage.getItem('access_token');
-        const response = await fetch(`http://localhost:8080/api/bookings/event/${event.id}/seats`, {
-          headers: {
-            'Authorization': `Bearer ${token}`
-          }
-        });
+        const response = await fetch(`http://localhost:8080/api/events/${event.id}/seatmap`);
 
         if (response.ok) {
-          const data = await response.json();
-          const bookedSeats = data.seats || [];
-          // Create a map of seat ID to status
-          const seatStatusMap = new Map(bookedSeats.map((s: any) => [s.id, s.status]));
-
-          initialSeats.forEach(seat => {
-            if (seatStatusMap.has(seat.id)) {
-              seat.status = seatStatusMap.get(seat.id) as any;
-            }
-          });
+          const seatMap = await response.json();
+          (seatMap.sections || [])
+            .filter((section: any) => section.ticket_class === ticketClass)
+            .forEach((section: any) => {
+              // Stack the rows of several sections of the same class
+              section.rows.forEach((row: any) => {
+                row.seats.forEach((seat: any) => {
+                  sectionSeats.push({
+                    id: seat.id,
+                    row: rowOffset + row.position,
+                    number: seat.number,
+                    status: seat.status,
+                    price: section.price
+                  });
+                });
+              });
+              rowOffset += section.rows.length;
+            });
         }
       } catch (error) {
-        console.error('Failed to fetch booked seats:', error);
+        console.error('Failed to fetch seat map:', error);
       }
-      
-      setSeats(initialSeats);
+
+      setSeats(sectionSeats);
     };
 
     loadSeats();
@@ -1,7 +1,9 @@
 'use client';
 
+import { useState } from 'react';
 import { X, Check } from 'lucide-react';
-import { Event } from '../../types';
+import { Event, TicketTier } from '../../types';
+import { WaitingRoomPanel, getAdmissionToken } from './WaitingRoomPanel';
 
 interface TicketClassSelectionModalProps {
   event: Event;
@@ -10,61 +12,91 @@ interface TicketClassSelectionModalProps {
 }
 
 export function TicketClassSelectionModal({ event, onClose, onSelectClass }: TicketClassSelectionModalProps) {
-  const classes = [
-    { 
-      id: 'normal', 
-      name: 'Normal', 
-      price: event.priceNormal, 
-      available: event.availableNormal ?? 0 
-    },
-    { 
-      id: 'vip', 
-      name: 'VIP', 
-      price: event.priceVIP, 
-      available: event.availableVIP ?? 0 
-    },
-    { 
-      id: 'vvip', 
-      name: 'VVIP', 
-      price: event.priceVVIP, 
-      available: event.availableVVIP ?? 0 
-    },
-  ];
+  const [admitted, setAdmitted] = useState(() => !event.waitingRoom || !!getAdmissionToken(event.id));
+  const now = new Date();
+  const windowOpen = (start?: string | null, end?: string | null) =>
+    (!start || new Date(start) <= now) && (!end || new Date(end) > now);
+
+  // The event's own sale window applies to every tier
+  const eventOnSale = (!event.status || event.status === 'on_sale') && windowOpen(event.salesStart, event.salesEnd);
+  const eventNote = event.salesStart && new Date(event.salesStart) > now
+    ? `On sale ${new Date(event.salesStart).toLocaleString()}`
+    : 'Sales closed';
+  const onSale = (tier: TicketTier) => eventOnSale && windowOpen(tier.salesStart, tier.salesEnd);
+  const tierNote = (tier: TicketTier) => {
+    if (!eventOnSale) return eventNote;
+    if (tier.salesStart && new Date(tier.salesStart) > now) return `On sale ${new Date(tier.salesStart).toLocaleString()}`;
+    return 'Not on sale';
+  };
+
+  const limitNote = (tier: TicketTier) => [
+    tier.maxPerOrder > 0 ? `Max ${tier.maxPerOrder} per order` : '',
+    tier.maxPerUser ? `Max ${tier.maxPerUser} per person` : '',
+  ].filter(Boolean).join(' · ');
+
+  // Buyers of events with a waiting room queue first, from the time the
+  // event is published
+  const queueing = !admitted && (event.status === 'published' || eventOnSale);
+
+  // Events from before ticket tiers only have the three legacy classes
+  const classes = event.tiers && event.tiers.length > 0
+    ? event.tiers.map((tier) => ({
+        id: tier.code,
+        name: tier.name,
+        price: tier.price,
+        available: onSale(tier) ? tier.available : 0,
+        note: !onSale(tier) ? tierNote(tier) : limitNote(tier),
+      }))
+    : [
+        { id: 'normal', name: 'Normal', price: event.priceNormal, available: eventOnSale ? event.availableNormal ?? 0 : 0, note: eventOnSale ? '' : eventNote },
+        { id: 'vip', name: 'VIP', price: event.priceVIP, available: eventOnSale ? event.availableVIP ?? 0 : 0, note: eventOnSale ? '' : eventNote },
+        { id: 'vvip', name: 'VVIP', price: event.priceVVIP, available: eventOnSale ? event.availableVVIP ?? 0 : 0, note: eventOnSale ? '' : eventNote },
+      ];
 
   return (
     <div className="fixed inset-0 bg-black/50 backdrop-blur-sm flex items-center justify-center z-50 p-4">
       <div className="bg-[var(--card)] border border-[var(--border)] rounded-2xl w-full max-w-md shadow-2xl animate-in fade-in zoom-in duration-200">
         <div className="flex items-center justify-between p-6 border-b border-[var(--border)]">
-          <h2 className="text-xl font-semibold">Select Ticket Class</h2>
+          <h2 className="text-xl font-semibold">{queueing ? 'Waiting Room' : 'Select Ticket Class'}</h2>
           <button onClick={onClose} className="p-2 hover:bg-[var(--muted)] rounded-full transition-colors">
             <X className="w-5 h-5" />
           </button>
         </div>
 
-        <div className="p-6 space-y-4">
-          {classes.map((cls) => (
-            <button
-              key={cls.id}
-              disabled={cls.available <= 0}
-              onClick={() => onSelectClass(cls.id, cls.price)}
-              className={`w-full flex items-center justify-between p-4 rounded-xl border transition-all ${
-                cls.available > 0
-                  ? 'border-[var(--border)] hover:border-[var(--primary)] hover:bg-[var(--muted)]'
-                  : 'border-[var(--border)] opacity-50 cursor-not-allowed'
-              }`}
-            >
-              <div className="text-left">
-                <div className="font-medium">{cls.name}</div>
-                <div className="text-sm text-[var(--muted-foreground)]">
-                  {cls.available > 0 ? `${cls.available} seats left` : 'Sold Out'}
+        {queueing ? (
+          <WaitingRoomPanel eventId={event.id} onAdmitted={() => setAdmitted(true)} />
+        ) : (
+          <div className="p-6 space-y-4">
+            {event.maxTicketsPerUser ? (
+              <p className="text-sm text-[var(--muted-foreground)]">
+                Limit of {event.maxTicketsPerUser} tickets per person for this event
+              </p>
+            ) : null}
+            {classes.map((cls) => (
+              <button
+                key={cls.id}
+                disabled={cls.available <= 0}
+                onClick={() => onSelectClass(cls.id, cls.price)}
+                className={`w-full flex items-center justify-between p-4 rounded-xl border transition-all ${
+                  cls.available > 0
+                    ? 'border-[var(--border)] hover:border-[var(--primary)] hover:bg-[var(--muted)]'
+                    : 'border-[var(--border)] opacity-50 cursor-not-allowed'
+                }`}
+              >
+                <div className="text-left">
+                  <div className="font-medium">{cls.name}</div>
+                  <div className="text-sm text-[var(--muted-foreground)]">
+                    {cls.available > 0 ? `${cls.available} seats left` : cls.note || 'Sold Out'}
+                    {cls.available > 0 && cls.note ? ` · ${cls.note}` : ''}
+                  </div>
                 </div>
-              </div>
-              <div className="font-semibold text-lg">
-                ${cls.price}
-              </div>
-            </button>
-          ))}
-        </div>
+                <div className="font-semibold text-lg">
+                  ${cls.price}
+                </div>
+              </button>
+            ))}
+          </div>
+        )}
       </div>
     </div>
   );
@@ -0,0 +1,127 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import { Clock, Users } from 'lucide-react';
+import { toast } from 'sonner';
+
+const EVENT_API_URL = 'http://localhost:8080/api/events';
+
+interface QueueTicket {
+  queue_token: string;
+  status: 'waiting' | 'admitted';
+  position?: number;
+  estimated_wait_seconds?: number;
+  admitted_until?: string;
+}
+
+const admissionKey = (eventId: string | number) => `admission:${eventId}`;
+
+// The admission token the waiting room gave for an event, while its
+// purchase window is open. Bookings of events with a waiting room need it.
+export function getAdmissionToken(eventId: string | number): string | undefined {
+  const stored = sessionStorage.getItem(admissionKey(eventId));
+  if (!stored) return undefined;
+  const { token, until } = JSON.parse(stored);
+  if (new Date(until) <= new Date()) {
+    sessionStorage.removeItem(admissionKey(eventId));
+    return undefined;
+  }
+  return token;
+}
+
+function formatWait(seconds?: number) {
+  if (!seconds) return 'less than a minute';
+  const minutes = Math.ceil(seconds / 60);
+  return minutes === 1 ? 'about a minute' : `about ${minutes} minutes`;
+}
+
+interface WaitingRoomPanelProps {
+  eventId: string;
+  onAdmitted: () => void;
+}
+
+export function WaitingRoomPanel({ eventId, onAdmitted }: WaitingRoomPanelProps) {
+  const [ticket, setTicket] = useState<QueueTicket | null>(null);
+
+  const admit = (admitted: QueueTicket) => {
+    sessionStorage.setItem(admissionKey(eventId), JSON.stringify({ token: admitted.queue_token, until: admitted.admitted_until }));
+    toast.success('It is your turn! Pick your tickets before your purchase window closes.');
+    onAdmitted();
+  };
+
+  // Joining again returns the place the user already has
+  const join = async () => {
+    const token = localStorage.getItem('access_token');
+    try {
+      const response = await fetch(`${EVENT_API_URL}/${eventId}/queue`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` },
+      });
+      const data = await response.json();
+      if (!response.ok) {
+        toast.error(data.error || 'Failed to join the waiting room');
+        return;
+      }
+      if (data.status === 'admitted') {
+        admit(data);
+      } else {
+        setTicket(data);
+      }
+    } catch (error) {
+      console.error('Failed to join the waiting room:', error);
+      toast.error('Failed to join the waiting room');
+    }
+  };
+
+  useEffect(() => {
+    join();
+  }, [eventId]);
+
+  // Follow the position until the user is admitted
+  const queueToken = ticket?.queue_token;
+  useEffect(() => {
+    if (!queueToken) return;
+    const source = new EventSource(`${EVENT_API_URL}/${eventId}/queue/${queueToken}/stream`);
+    source.addEventListener('queue', (e) => {
+      const update: QueueTicket = JSON.parse((e as MessageEvent).data);
+      if (update.status === 'admitted') {
+        source.close();
+        admit(update);
+      } else {
+        setTicket(update);
+      }
+    });
+    source.addEventListener('expired', () => {
+      source.close();
+      setTicket(null);
+      toast.error('Your place in the queue expired, joining again');
+      join();
+    });
+    return () => source.close();
+  }, [eventId, queueToken]);
+
+  return (
+    <div className="p-6 space-y-4 text-center">
+      <div className="mx-auto w-12 h-12 rounded-full bg-[var(--muted)] flex items-center justify-center">
+        <Users className="w-6 h-6 text-[var(--primary)]" />
+      </div>
+      <div>
+        <div className="font-semibold text-lg">You are in the waiting room</div>
+        <div className="text-sm text-[var(--muted-foreground)]">
+          This on-sale is busy. Keep this window open; buyers are let in in the order they arrived.
+        </div>
+      </div>
+      {ticket ? (
+        <div className="rounded-xl border border-[var(--border)] p-4 space-y-1">
+          <div className="text-3xl font-bold">#{ticket.position}</div>
+          <div className="text-sm text-[var(--muted-foreground)] flex items-center justify-center gap-1">
+            <Clock className="w-4 h-4" />
+            Estimated wait {formatWait(ticket.estimated_wait_seconds)}
+          </div>
+        </div>
+      ) : (
+        <div className="text-sm text-[var(--muted-foreground)]">Joining the queue...</div>
+      )}
+    </div>
+  );
+}
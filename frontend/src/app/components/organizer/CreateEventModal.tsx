@@ -15,6 +15,11 @@ export function CreateEventModal({ onClose, onCreate }: CreateEventModalProps) {
     description: '',
     date: '',
     location: '',
+    salesStart: '',
+    salesEnd: '',
+    waitingRoom: false,
+    admissionRate: 0,
+    maxTicketsPerUser: 0,
     priceNormal: 50,
     priceVIP: 100,
     priceVVIP: 200,
@@ -32,6 +37,12 @@ export function CreateEventModal({ onClose, onCreate }: CreateEventModalProps) {
         description: formData.description,
         date: new Date(formData.date).toISOString(),
         location: formData.location,
+        // Empty fields leave that end of the sale window open
+        sales_start: formData.salesStart ? new Date(formData.salesStart).toISOString() : null,
+        sales_end: formData.salesEnd ? new Date(formData.salesEnd).toISOString() : null,
+        waiting_room: formData.waitingRoom,
+        admission_rate: Number(formData.admissionRate),
+        max_tickets_per_user: Number(formData.maxTicketsPerUser),
         
         price_normal: Number(formData.priceNormal),
         price_vip: Number(formData.priceVIP),
@@ -105,6 +116,66 @@ export function CreateEventModal({ onClose, onCreate }: CreateEventModalProps) {
             </div>
           </div>
 
+          <div className="grid grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium mb-1">Sales Start</label>
+              <input
+                type="datetime-local"
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+                value={formData.salesStart}
+                onChange={e => setFormData({ ...formData, salesStart: e.target.value })}
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium mb-1">Sales End</label>
+              <input
+                type="datetime-local"
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+                value={formData.salesEnd}
+                onChange={e => setFormData({ ...formData, salesEnd: e.target.value })}
+              />
+            </div>
+          </div>
+
+          <div className="grid grid-cols-2 gap-4 items-end">
+            <label className="flex items-center gap-2 text-sm font-medium pb-2">
+              <input
+                type="checkbox"
+                checked={formData.waitingRoom}
+                onChange={e => setFormData({ ...formData, waitingRoom: e.target.checked })}
+              />
+              Waiting Room
+            </label>
+            <div>
+              <label className="block text-sm font-medium mb-1">Admitted per Minute</label>
+              <input
+                type="number"
+                min="0"
+                disabled={!formData.waitingRoom}
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none disabled:opacity-50"
+                value={formData.admissionRate}
+                onChange={e => setFormData({ ...formData, admissionRate: Number(e.target.value) })}
+              />
+            </div>
+          </div>
+          <p className="text-xs text-[var(--muted-foreground)] -mt-2">
+            For busy on-sales: buyers queue and are let in at this rate (0 for the default)
+          </p>
+
+          <div>
+            <label className="block text-sm font-medium mb-1">Max Tickets per User</label>
+            <input
+              type="number"
+              min="0"
+              className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+              value={formData.maxTicketsPerUser}
+              onChange={e => setFormData({ ...formData, maxTicketsPerUser: Number(e.target.value) })}
+            />
+            <p className="text-xs text-[var(--muted-foreground)] mt-1">
+              Across each buyer's pending and confirmed bookings (0 for no limit)
+            </p>
+          </div>
+
           <div className="grid grid-cols-3 gap-4">
             <div>
               <label className="block text-sm font-medium mb-1">Normal Seats</label>
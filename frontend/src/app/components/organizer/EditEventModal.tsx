@@ -3,6 +3,13 @@
 import { useState, useEffect } from 'react';
 import { X } from 'lucide-react';
 
+// toLocalInput formats an ISO timestamp for a datetime-local input.
+const toLocalInput = (iso?: string | null) => {
+  if (!iso) return '';
+  const date = new Date(iso);
+  return new Date(date.getTime() - date.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+};
+
 interface EditEventModalProps {
   event: any;
   onClose: () => void;
@@ -15,7 +22,12 @@ export function EditEventModal({ event, onClose, onUpdate }: EditEventModalProps
     title: event.title,
     description: event.description,
     location: event.location,
-    total_seats: event.total_seats
+    total_seats: event.total_seats,
+    sales_start: toLocalInput(event.sales_start),
+    sales_end: toLocalInput(event.sales_end),
+    waiting_room: !!event.waiting_room,
+    admission_rate: event.admission_rate || 0,
+    max_tickets_per_user: event.max_tickets_per_user || 0
   });
 
   const bookedSeats = event.total_seats - event.available_seats;
@@ -26,7 +38,12 @@ export function EditEventModal({ event, onClose, onUpdate }: EditEventModalProps
     try {
       await onUpdate(event.ID, {
         ...formData,
-        total_seats: Number(formData.total_seats)
+        total_seats: Number(formData.total_seats),
+        // An empty field leaves that end of the sale window open
+        sales_start: formData.sales_start ? new Date(formData.sales_start).toISOString() : null,
+        sales_end: formData.sales_end ? new Date(formData.sales_end).toISOString() : null,
+        admission_rate: Number(formData.admission_rate),
+        max_tickets_per_user: Number(formData.max_tickets_per_user)
       });
       onClose();
     } catch (error) {
@@ -95,6 +112,69 @@ export function EditEventModal({ event, onClose, onUpdate }: EditEventModalProps
             />
           </div>
 
+          <div className="grid grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium mb-1">Sales Start</label>
+              <input
+                type="datetime-local"
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+                value={formData.sales_start}
+                onChange={e => setFormData({ ...formData, sales_start: e.target.value })}
+              />
+            </div>
+            <div>
+              <label className="block text-sm font-medium mb-1">Sales End</label>
+              <input
+                type="datetime-local"
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+                value={formData.sales_end}
+                onChange={e => setFormData({ ...formData, sales_end: e.target.value })}
+              />
+            </div>
+          </div>
+          <p className="text-xs text-[var(--muted-foreground)] -mt-2">
+            Leave empty to sell from publishing until the event starts
+          </p>
+
+          <div className="grid grid-cols-2 gap-4 items-end">
+            <label className="flex items-center gap-2 text-sm font-medium pb-2">
+              <input
+                type="checkbox"
+                checked={formData.waiting_room}
+                onChange={e => setFormData({ ...formData, waiting_room: e.target.checked })}
+              />
+              Waiting Room
+            </label>
+            <div>
+              <label className="block text-sm font-medium mb-1">Admitted per Minute</label>
+              <input
+                type="number"
+                min="0"
+                disabled={!formData.waiting_room}
+                className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none disabled:opacity-50"
+                value={formData.admission_rate}
+                onChange={e => setFormData({ ...formData, admission_rate: Number(e.target.value) })}
+              />
+            </div>
+          </div>
+          <p className="text-xs text-[var(--muted-foreground)] -mt-2">
+            For busy on-sales: buyers queue and are let in at this rate (0 for the default)
+          </p>
+
+          <div>
+            <label className="block text-sm font-medium mb-1">Max Tickets per User</label>
+            <input
+              type="number"
+              min="0"
+              className="w-full px-4 py-2 rounded-lg bg-[var(--background)] border border-[var(--border)] focus:ring-2 focus:ring-[var(--primary)] outline-none"
+              value={formData.max_tickets_per_user}
+              onChange={e => setFormData({ ...formData, max_tickets_per_user: Number(e.target.value) })}
+            />
+            <p className="text-xs text-[var(--muted-foreground)] mt-1">
+              Across each buyer's pending and confirmed bookings (0 for no limit)
+            </p>
+          </div>
+
           <div className="flex justify-end gap-3 mt-6">
             <button
               type="button"
@@ -5,7 +5,7 @@ import { Sidebar } from '../layout/Sidebar';
 import { SettingsPage } from '../layout/SettingsPage';
 import { Plus } from 'lucide-react';
 import { StatsOverview } from './dashboard/StatsOverview';
-import { EventsList } from './dashboard/EventsList';
+import { EventsList, EventStatus, LifecycleAction } from './dashboard/EventsList';
 import { EventAnalytics } from './dashboard/EventAnalytics';
 import { CreateEventModal } from './CreateEventModal';
 import { EditEventModal } from './EditEventModal';
@@ -20,6 +20,12 @@ interface Event {
   total_seats: number;
   available_seats: number;
   organizer_id: number;
+  status?: EventStatus;
+  sales_start?: string | null;
+  sales_end?: string | null;
+  waiting_room?: boolean;
+  admission_rate?: number;
+  max_tickets_per_user?: number;
   available_normal?: number;
   available_vip?: number;
   available_vvip?: number;
@@ -154,6 +160,38 @@ export function OrganizerDashboard({ userEmail, onLogout }: OrganizerDashboardPr
     }
   };
 
+  const handleLifecycle = async (event: Event, action: LifecycleAction) => {
+    let body: string | undefined;
+    if (action === 'cancel') {
+      const reason = window.prompt(`Cancel "${event.title}"? Ticket sales stop for good and ticket holders are refunded in full. Reason (optional):`);
+      if (reason === null) return;
+      body = JSON.stringify({ reason });
+    }
+
+    try {
+      const token = localStorage.getItem('access_token');
+      const res = await fetch(`${EVENT_API_URL}/${event.ID}/${action}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body
+      });
+
+      const data = await res.json();
+      if (res.ok) {
+        toast.success(data.message);
+        fetchEvents();
+      } else {
+        toast.error(data.error || `Failed to ${action} event`);
+      }
+    } catch (error) {
+      console.error(`Error trying to ${action} event:`, error);
+      toast.error(`Error trying to ${action} event`);
+    }
+  };
+
   const handleViewStats = async (event: Event) => {
     setSelectedEvent(event);
     setLoadingSales(true);
@@ -223,6 +261,7 @@ export function OrganizerDashboard({ userEmail, onLogout }: OrganizerDashboardPr
                 setShowEditModal(true);
               }}
               onViewStats={handleViewStats}
+              onLifecycle={handleLifecycle}
             />
 
             {selectedEvent && (
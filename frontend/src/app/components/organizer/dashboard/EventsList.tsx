@@ -1,5 +1,29 @@
 import { Calendar, MapPin, Edit } from 'lucide-react';
 
+export type EventStatus = 'draft' | 'published' | 'on_sale' | 'sold_out' | 'sales_closed' | 'cancelled' | 'completed';
+
+export type LifecycleAction = 'publish' | 'unpublish' | 'cancel';
+
+const STATUS_LABELS: Record<EventStatus, string> = {
+  draft: 'Draft',
+  published: 'Published',
+  on_sale: 'On Sale',
+  sold_out: 'Sold Out',
+  sales_closed: 'Sales Closed',
+  cancelled: 'Cancelled',
+  completed: 'Completed',
+};
+
+const STATUS_STYLES: Record<EventStatus, string> = {
+  draft: 'bg-gray-100 text-gray-600 dark:bg-gray-800 dark:text-gray-300',
+  published: 'bg-blue-100 text-blue-600 dark:bg-blue-900/30 dark:text-blue-400',
+  on_sale: 'bg-green-100 text-green-600 dark:bg-green-900/30 dark:text-green-400',
+  sold_out: 'bg-red-100 text-red-600 dark:bg-red-900/30 dark:text-red-400',
+  sales_closed: 'bg-yellow-100 text-yellow-700 dark:bg-yellow-900/30 dark:text-yellow-400',
+  cancelled: 'bg-red-100 text-red-600 dark:bg-red-900/30 dark:text-red-400',
+  completed: 'bg-gray-100 text-gray-600 dark:bg-gray-800 dark:text-gray-300',
+};
+
 interface Event {
   ID: number;
   title: string;
@@ -9,6 +33,12 @@ interface Event {
   total_seats: number;
   available_seats: number;
   organizer_id: number;
+  status?: EventStatus;
+  sales_start?: string | null;
+  sales_end?: string | null;
+  waiting_room?: boolean;
+  admission_rate?: number;
+  max_tickets_per_user?: number;
   available_normal?: number;
   available_vip?: number;
   available_vvip?: number;
@@ -19,9 +49,10 @@ interface EventsListProps {
   loading: boolean;
   onEdit: (event: Event) => void;
   onViewStats: (event: Event) => void;
+  onLifecycle: (event: Event, action: LifecycleAction) => void;
 }
 
-export function EventsList({ events, loading, onEdit, onViewStats }: EventsListProps) {
+export function EventsList({ events, loading, onEdit, onViewStats, onLifecycle }: EventsListProps) {
   return (
     <div>
       <h2 className="text-xl font-semibold mb-4">Your Events</h2>
@@ -33,7 +64,10 @@ export function EventsList({ events, loading, onEdit, onViewStats }: EventsListP
         </div>
       ) : (
         <div className="grid grid-cols-1 lg:grid-cols-2 gap-6">
-          {events.map(event => (
+          {events.map(event => {
+            const status: EventStatus = event.status ?? 'on_sale';
+            const closed = status === 'cancelled' || status === 'completed';
+            return (
             <div key={event.ID} className="bg-[var(--card)] border border-[var(--border)] rounded-2xl p-6 hover:shadow-lg transition-all">
               <div className="flex justify-between items-start mb-4">
                 <div>
@@ -48,20 +82,18 @@ export function EventsList({ events, loading, onEdit, onViewStats }: EventsListP
                   </div>
                 </div>
                 <div className="flex flex-col items-end gap-2">
-                  <span className={`px-3 py-1 rounded-full text-xs ${
-                    event.available_seats > 0 
-                      ? 'bg-green-100 text-green-600 dark:bg-green-900/30 dark:text-green-400'
-                      : 'bg-red-100 text-red-600 dark:bg-red-900/30 dark:text-red-400'
-                  }`}>
-                    {event.available_seats > 0 ? 'Active' : 'Sold Out'}
+                  <span className={`px-3 py-1 rounded-full text-xs ${STATUS_STYLES[status]}`}>
+                    {STATUS_LABELS[status]}
                   </span>
-                  <button
-                    onClick={() => onEdit(event)}
-                    className="p-2 hover:bg-[var(--muted)] rounded-lg transition-colors text-[var(--muted-foreground)] hover:text-[var(--foreground)]"
-                    title="Edit Event"
-                  >
-                    <Edit className="w-4 h-4" />
-                  </button>
+                  {!closed && (
+                    <button
+                      onClick={() => onEdit(event)}
+                      className="p-2 hover:bg-[var(--muted)] rounded-lg transition-colors text-[var(--muted-foreground)] hover:text-[var(--foreground)]"
+                      title="Edit Event"
+                    >
+                      <Edit className="w-4 h-4" />
+                    </button>
+                  )}
                 </div>
               </div>
 
@@ -81,6 +113,32 @@ export function EventsList({ events, loading, onEdit, onViewStats }: EventsListP
                 </div>
               </div>
 
+              {!closed && (
+                <div className="flex gap-2 mb-3">
+                  {status === 'draft' ? (
+                    <button
+                      onClick={() => onLifecycle(event, 'publish')}
+                      className="flex-1 py-2 bg-[var(--primary)] text-white rounded-lg hover:opacity-90 transition-opacity text-sm font-medium"
+                    >
+                      Publish
+                    </button>
+                  ) : (
+                    <button
+                      onClick={() => onLifecycle(event, 'unpublish')}
+                      className="flex-1 py-2 bg-[var(--muted)] hover:bg-[var(--muted)]/80 rounded-lg transition-colors text-sm font-medium"
+                    >
+                      Unpublish
+                    </button>
+                  )}
+                  <button
+                    onClick={() => onLifecycle(event, 'cancel')}
+                    className="flex-1 py-2 bg-red-100 text-red-600 hover:bg-red-200 dark:bg-red-900/30 dark:text-red-400 rounded-lg transition-colors text-sm font-medium"
+                  >
+                    Cancel Event
+                  </button>
+                </div>
+              )}
+
               <button
                 onClick={() => onViewStats(event)}
                 className="w-full py-2 bg-[var(--muted)] hover:bg-[var(--muted)]/80 rounded-lg transition-colors text-sm font-medium"
@@ -88,7 +146,8 @@ export function EventsList({ events, loading, onEdit, onViewStats }: EventsListP
                 View Analytics & Transactions
               </button>
             </div>
-          ))}
+            );
+          })}
         </div>
       )}
     </div>
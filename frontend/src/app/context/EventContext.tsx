@@ -123,6 +123,23 @@ export function EventProvider({ children }: { children: React.ReactNode }) {
           availableNormal: e.available_normal,
           availableVIP: e.available_vip,
           availableVVIP: e.available_vvip,
+          status: e.status,
+          salesStart: e.sales_start,
+          salesEnd: e.sales_end,
+          waitingRoom: e.waiting_room,
+          maxTicketsPerUser: e.max_tickets_per_user,
+          tiers: (e.tiers || []).map((t: any) => ({
+            code: t.code,
+            name: t.name,
+            price: t.price,
+            capacity: t.capacity,
+            available: t.available,
+            salesStart: t.sales_start,
+            salesEnd: t.sales_end,
+            minPerOrder: t.min_per_order,
+            maxPerOrder: t.max_per_order,
+            maxPerUser: t.max_per_user,
+          })),
         }));
         setEvents(mappedEvents);
       }
@@ -1,3 +1,16 @@
+export interface TicketTier {
+  code: string;
+  name: string;
+  price: number;
+  capacity: number;
+  available: number;
+  salesStart?: string | null;
+  salesEnd?: string | null;
+  minPerOrder: number;
+  maxPerOrder: number;
+  maxPerUser?: number;
+}
+
 export interface Event {
   id: string;
   name: string;
@@ -24,6 +37,20 @@ export interface Event {
   availableNormal?: number;
   availableVIP?: number;
   availableVVIP?: number;
+
+  // Ticket tiers; the legacy class fields mirror the normal, vip and vvip tiers
+  tiers?: TicketTier[];
+
+  // Lifecycle status and the sale window of the whole event
+  status?: string;
+  salesStart?: string | null;
+  salesEnd?: string | null;
+
+  // Buyers queue in a waiting room before they can book
+  waitingRoom?: boolean;
+
+  // Tickets one user may hold for the event across their bookings
+  maxTicketsPerUser?: number;
 }
 
 export type SeatStatus = 'available' | 'selected' | 'sold' | 'locked';
@@ -50,7 +77,7 @@ export interface Booking {
   }>;
   totalAmount: number;
   bookingDate: string;
-  status: 'confirmed' | 'cancelled';
+  status: 'pending' | 'confirmed' | 'failed' | 'cancelled' | 'refunded' | 'expired';
 }
 
 export type UserRole = 'admin' | 'organizer' | 'user';